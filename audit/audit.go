@@ -0,0 +1,332 @@
+// Package audit checks that shard holders still store their shards, without downloading
+// them: a proof of retrievability with precomputed challenges.
+//
+// When an object is stored, the owner prepares a number of challenges per shard. Each
+// challenge samples byte ranges of the shard payload at positions derived from a secret
+// audit key, and its expected response is an HMAC-SHA256 tag, under the same key, of the
+// sampled bytes:
+//
+//	positions = HMAC(key, "ranges" | object ID | index | challenge)
+//	tag       = HMAC(key, "tag" | object ID | index | challenge | sampled bytes)
+//
+// Only the tags are kept in the audit file, so neither the positions nor the expected
+// responses can be learned from it. To run a challenge the challenger reveals its ranges,
+// reads them from the holder and compares their tag. Every challenge is used once: the
+// positions of a used challenge are known to the holder.
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"rs-encoder/util"
+	"sort"
+)
+
+// KeySize is the size in bytes of an audit key
+const KeySize = 32
+
+// Default challenge parameters: 16 ranges of 64 bytes find a holder that lost 10% of a
+// shard with a probability above 80% per challenge
+const (
+	DefaultChallenges = 32
+	DefaultSamples    = 16
+	DefaultRangeSize  = 64
+)
+
+// ErrExhausted is returned when a shard has no unused challenge left
+var ErrExhausted = errors.New("audit: no unused challenges left, prepare a new audit")
+
+// Tag is the expected response to a challenge
+type Tag [sha256.Size]byte
+
+// MarshalText encodes the tag in hexadecimal
+func (t Tag) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(t[:])), nil
+}
+
+// UnmarshalText decodes a tag in hexadecimal
+func (t *Tag) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != len(t) {
+		return fmt.Errorf("invalid audit tag %q", text)
+	}
+	_, err := hex.Decode(t[:], text)
+	return err
+}
+
+// Params are the challenge parameters of an audit
+type Params struct {
+	Challenges int   `json:"challenges"` // Challenges per shard
+	Samples    int   `json:"samples"`    // Byte ranges sampled per challenge
+	RangeSize  int64 `json:"range_size"` // Size in bytes of a range
+}
+
+// DefaultParams returns the default challenge parameters
+func DefaultParams() Params {
+	return Params{Challenges: DefaultChallenges, Samples: DefaultSamples, RangeSize: DefaultRangeSize}
+}
+
+// Audit holds the precomputed challenges of the shards of an object
+type Audit struct {
+	Params
+	ObjectID util.ObjectID `json:"object_id"`
+	Shards   []ShardAudit  `json:"shards"` // One entry per shard index
+}
+
+// ShardAudit holds the challenges of one shard
+type ShardAudit struct {
+	Index  int   `json:"index"`
+	Length int64 `json:"length"` // Size of the payload as stored
+	Next   int   `json:"next"`   // First unused challenge
+	Tags   []Tag `json:"tags"`   // Expected response of every challenge
+}
+
+// Remaining returns the number of unused challenges of the shard
+func (s *ShardAudit) Remaining() int {
+	return len(s.Tags) - s.Next
+}
+
+// Prepare precomputes the challenges of the shards of object id held by store, which must
+// hold all shards indices 0 to shards-1, typically the shard directory just written by
+// the encoder
+func Prepare(key []byte, store Store, id util.ObjectID, shards int, params Params) (*Audit, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid audit key: %d bytes, expected %d", len(key), KeySize)
+	}
+	if params.Challenges <= 0 || params.Samples <= 0 || params.RangeSize <= 0 {
+		return nil, fmt.Errorf("invalid audit parameters: %d challenges of %d ranges of %d bytes", params.Challenges, params.Samples, params.RangeSize)
+	}
+	sizes, err := store.Shards(id)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &Audit{Params: params, ObjectID: id, Shards: make([]ShardAudit, shards)}
+	for index := range audit.Shards {
+		length, ok := sizes[index]
+		if !ok {
+			return nil, fmt.Errorf("shard %d of object %s is missing", index, id)
+		}
+		payload, err := store.ReadRange(id, index, 0, length)
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %v", index, err)
+		}
+
+		shard := ShardAudit{Index: index, Length: length, Tags: make([]Tag, params.Challenges)}
+		for challenge := range shard.Tags {
+			ranges := audit.ranges(key, &shard, challenge)
+			sampled := make([][]byte, len(ranges))
+			for i, r := range ranges {
+				sampled[i] = payload[r.Offset : r.Offset+r.Length]
+			}
+			shard.Tags[challenge] = audit.tag(key, index, challenge, sampled)
+		}
+		audit.Shards[index] = shard
+	}
+	return audit, nil
+}
+
+// Range is a byte range of a shard payload
+type Range struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// ranges returns the byte ranges a challenge samples from a shard. The ranges are spread
+// over the payload by a PRF keyed with the audit key and may overlap; shards shorter than
+// a range are sampled whole.
+func (a *Audit) ranges(key []byte, shard *ShardAudit, challenge int) []Range {
+	length := min(a.RangeSize, shard.Length)
+	seed := hmac.New(sha256.New, key)
+	seed.Write([]byte("rs-encoder audit ranges\x00"))
+	seed.Write(a.ObjectID[:])
+	seed.Write(binary.BigEndian.AppendUint16(nil, uint16(shard.Index)))
+	seed.Write(binary.BigEndian.AppendUint32(nil, uint32(challenge)))
+	prf := hmac.New(sha256.New, seed.Sum(nil))
+
+	ranges := make([]Range, a.Samples)
+	for i := range ranges {
+		prf.Reset()
+		prf.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+		r := binary.BigEndian.Uint64(prf.Sum(nil))
+		ranges[i] = Range{Offset: int64(r % uint64(shard.Length-length+1)), Length: length}
+	}
+	return ranges
+}
+
+// tag returns the expected response of a challenge over the sampled bytes
+func (a *Audit) tag(key []byte, index, challenge int, sampled [][]byte) Tag {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("rs-encoder audit tag\x00"))
+	mac.Write(a.ObjectID[:])
+	mac.Write(binary.BigEndian.AppendUint16(nil, uint16(index)))
+	mac.Write(binary.BigEndian.AppendUint32(nil, uint32(challenge)))
+	for _, data := range sampled {
+		mac.Write(data)
+	}
+
+	var tag Tag
+	mac.Sum(tag[:0])
+	return tag
+}
+
+// Holder is a named shard holder to audit
+type Holder struct {
+	Name    string
+	Store   Store
+	Indices []int // Shards the holder is expected to hold, nil to challenge the shards it claims
+}
+
+// Failure is a shard a holder failed to prove it holds
+type Failure struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// HolderReport is the outcome of the audit of one holder
+type HolderReport struct {
+	Holder string    `json:"holder"`
+	Passed []int     `json:"passed"` // Shards whose challenge was answered
+	Failed []Failure `json:"failed"` // Shards missing or whose challenge failed
+	Error  string    `json:"error,omitempty"`
+}
+
+// OK reports whether the holder answered every challenge and could be queried
+func (r *HolderReport) OK() bool {
+	return len(r.Failed) == 0 && r.Error == ""
+}
+
+// Report is the outcome of an audit round
+type Report struct {
+	ObjectID util.ObjectID  `json:"object_id"`
+	Holders  []HolderReport `json:"holders"`
+	Unheld   []int          `json:"unheld"` // Shards no holder proved it holds
+}
+
+// OK reports whether every holder passed and every shard is held
+func (r *Report) OK() bool {
+	for i := range r.Holders {
+		if !r.Holders[i].OK() {
+			return false
+		}
+	}
+	return len(r.Unheld) == 0
+}
+
+// Check runs an audit round: every shard a holder is expected to hold, or else claims, is
+// challenged with the next unused challenge of the shard, so a round uses one challenge per shard whatever the number of
+// holders. The audit is updated with the used challenges and must be saved afterwards.
+func Check(key []byte, audit *Audit, holders []Holder) (*Report, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid audit key: %d bytes, expected %d", len(key), KeySize)
+	}
+	for i := range audit.Shards {
+		if audit.Shards[i].Remaining() == 0 {
+			return nil, fmt.Errorf("shard %d: %w", i, ErrExhausted)
+		}
+	}
+
+	report := &Report{ObjectID: audit.ObjectID, Holders: make([]HolderReport, len(holders)), Unheld: []int{}}
+	held := make(map[int]bool)
+	for i, holder := range holders {
+		result := HolderReport{Holder: holder.Name, Passed: []int{}, Failed: []Failure{}}
+		sizes, err := holder.Store.Shards(audit.ObjectID)
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		indices := holder.Indices
+		if indices == nil {
+			for index := range sizes {
+				indices = append(indices, index)
+			}
+			sort.Ints(indices)
+		}
+		for _, index := range indices {
+			if index < 0 || index >= len(audit.Shards) {
+				continue
+			}
+			if _, ok := sizes[index]; !ok {
+				if result.Error == "" {
+					result.Failed = append(result.Failed, Failure{Index: index, Reason: "shard is missing"})
+				}
+				continue
+			}
+			if err := audit.challenge(key, holder.Store, index, sizes[index]); err != nil {
+				result.Failed = append(result.Failed, Failure{Index: index, Reason: err.Error()})
+				continue
+			}
+			result.Passed = append(result.Passed, index)
+			held[index] = true
+		}
+		report.Holders[i] = result
+	}
+
+	for i := range audit.Shards {
+		audit.Shards[i].Next++
+		if !held[i] {
+			report.Unheld = append(report.Unheld, i)
+		}
+	}
+	return report, nil
+}
+
+// challenge runs the next challenge of a shard against a store claiming a payload of length bytes
+func (a *Audit) challenge(key []byte, store Store, index int, length int64) error {
+	shard := &a.Shards[index]
+	if length != shard.Length {
+		return fmt.Errorf("payload has %d bytes, expected %d", length, shard.Length)
+	}
+	ranges := a.ranges(key, shard, shard.Next)
+	sampled := make([][]byte, len(ranges))
+	for i, r := range ranges {
+		data, err := store.ReadRange(a.ObjectID, index, r.Offset, r.Length)
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) != r.Length {
+			return fmt.Errorf("range %d+%d: got %d bytes", r.Offset, r.Length, len(data))
+		}
+		sampled[i] = data
+	}
+	tag := a.tag(key, index, shard.Next, sampled)
+	if !hmac.Equal(tag[:], shard.Tags[shard.Next][:]) {
+		return fmt.Errorf("challenge %d: sampled bytes do not match", shard.Next)
+	}
+	return nil
+}
+
+// WriteAudit saves an audit to a file
+func WriteAudit(path string, audit *Audit) error {
+	data, err := json.MarshalIndent(audit, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON encoding failed: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write audit: %v", err)
+	}
+	return nil
+}
+
+// ReadAudit reads an audit file
+func ReadAudit(path string) (*Audit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var audit Audit
+	if err := json.Unmarshal(data, &audit); err != nil {
+		return nil, fmt.Errorf("invalid audit file %s: %v", path, err)
+	}
+	for i := range audit.Shards {
+		if audit.Shards[i].Index != i || audit.Shards[i].Next < 0 || audit.Shards[i].Next > len(audit.Shards[i].Tags) {
+			return nil, fmt.Errorf("invalid audit file %s: shard %d", path, i)
+		}
+	}
+	return &audit, nil
+}
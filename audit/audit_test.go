@@ -0,0 +1,279 @@
+package audit
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"rs-encoder/util"
+	"testing"
+)
+
+// testShards is the number of shards of the test object, 4 data and 2 parity shards
+const testShards = 6
+
+// testKey returns a fixed audit key
+func testKey() []byte {
+	key := make([]byte, KeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	return key
+}
+
+// writeObject encodes a random object into a new shard directory and returns the directory
+// and the object ID
+func writeObject(t *testing.T) (string, util.ObjectID) {
+	t.Helper()
+	data := make([]byte, 40000)
+	rand.New(rand.NewSource(1)).Read(data)
+	header, shards, err := util.EncodeObject(data, util.DefaultCodec, util.DefaultPrimitivePoly, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := util.WriteShardFiles(dir, "object", header, shards); err != nil {
+		t.Fatal(err)
+	}
+	return dir, header.ObjectID
+}
+
+// prepare writes an object and prepares its audit
+func prepare(t *testing.T, params Params) (string, *Audit) {
+	t.Helper()
+	dir, id := writeObject(t)
+	audit, err := Prepare(testKey(), NewDirStore(dir), id, testShards, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, audit
+}
+
+// check runs an audit round against a single holder of every shard
+func check(t *testing.T, audit *Audit, dir string) *Report {
+	t.Helper()
+	report, err := Check(testKey(), audit, []Holder{{Name: "holder", Store: NewDirStore(dir)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// shardPath returns the path of a shard file of the test object
+func shardPath(dir string, index int) string {
+	return filepath.Join(dir, util.ShardFileName("object", index))
+}
+
+// failedIndices returns the indices of the shards a holder failed
+func failedIndices(report *HolderReport) []int {
+	indices := []int{}
+	for _, failure := range report.Failed {
+		indices = append(indices, failure.Index)
+	}
+	return indices
+}
+
+func TestCheckPasses(t *testing.T) {
+	dir, audit := prepare(t, DefaultParams())
+	for round := 0; round < 3; round++ {
+		report := check(t, audit, dir)
+		if !report.OK() {
+			t.Fatalf("round %d: audit of intact shards failed: %+v", round, report)
+		}
+		if got := len(report.Holders[0].Passed); got != testShards {
+			t.Fatalf("round %d: %d shards passed, want %d", round, got, testShards)
+		}
+	}
+	for i := range audit.Shards {
+		if got := audit.Shards[i].Remaining(); got != DefaultChallenges-3 {
+			t.Errorf("shard %d: %d challenges left, want %d", i, got, DefaultChallenges-3)
+		}
+	}
+}
+
+func TestCheckFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		damage   func(t *testing.T, dir string, audit *Audit)
+		expected bool // The holder is expected to hold every shard
+		failed   []int
+		unheld   []int
+	}{
+		{
+			name: "missing shard",
+			damage: func(t *testing.T, dir string, audit *Audit) {
+				if err := os.Remove(shardPath(dir, 2)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			failed: []int{},
+			unheld: []int{2},
+		},
+		{
+			name: "missing expected shard",
+			damage: func(t *testing.T, dir string, audit *Audit) {
+				if err := os.Remove(shardPath(dir, 2)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: true,
+			failed:   []int{2},
+			unheld:   []int{2},
+		},
+		{
+			name: "flipped byte in a sampled range",
+			damage: func(t *testing.T, dir string, audit *Audit) {
+				shard := &audit.Shards[4]
+				r := audit.ranges(testKey(), shard, shard.Next)[0]
+				flipByte(t, shardPath(dir, 4), util.ShardHeaderSize+r.Offset+r.Length/2)
+			},
+			failed: []int{4},
+			unheld: []int{4},
+		},
+		{
+			name: "truncated payload",
+			damage: func(t *testing.T, dir string, audit *Audit) {
+				if err := os.Truncate(shardPath(dir, 1), util.ShardHeaderSize+10); err != nil {
+					t.Fatal(err)
+				}
+			},
+			failed: []int{1},
+			unheld: []int{1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, audit := prepare(t, DefaultParams())
+			test.damage(t, dir, audit)
+
+			holder := Holder{Name: "holder", Store: NewDirStore(dir)}
+			if test.expected {
+				holder.Indices = []int{0, 1, 2, 3, 4, 5}
+			}
+			report, err := Check(testKey(), audit, []Holder{holder})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.OK() {
+				t.Fatal("audit of damaged shards passed")
+			}
+			if got := failedIndices(&report.Holders[0]); !equalInts(got, test.failed) {
+				t.Errorf("failed shards %v, want %v", got, test.failed)
+			}
+			if !equalInts(report.Unheld, test.unheld) {
+				t.Errorf("unheld shards %v, want %v", report.Unheld, test.unheld)
+			}
+		})
+	}
+}
+
+func TestCheckSeveralHolders(t *testing.T) {
+	dir, audit := prepare(t, DefaultParams())
+
+	// Two holders of three shards each, the second one lost a shard
+	holders := []Holder{{Name: "first", Store: NewDirStore(t.TempDir())}, {Name: "second", Store: NewDirStore(t.TempDir())}}
+	for i := 0; i < testShards; i++ {
+		if i == 5 {
+			continue
+		}
+		data, err := os.ReadFile(shardPath(dir, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		holderDir := holders[i/3].Store.(*DirStore).dir
+		if err := os.WriteFile(filepath.Join(holderDir, util.ShardFileName("object", i)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	holders[1].Indices = []int{3, 4, 5}
+
+	report, err := Check(testKey(), audit, holders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Holders[0].OK() || report.Holders[1].OK() {
+		t.Fatalf("expected the first holder to pass and the second to fail: %+v", report.Holders)
+	}
+	if got := failedIndices(&report.Holders[1]); !equalInts(got, []int{5}) {
+		t.Errorf("second holder failed shards %v, want [5]", got)
+	}
+}
+
+func TestCheckExhausted(t *testing.T) {
+	params := DefaultParams()
+	params.Challenges = 3
+	dir, audit := prepare(t, params)
+	for round := 0; round < params.Challenges; round++ {
+		if report := check(t, audit, dir); !report.OK() {
+			t.Fatalf("round %d failed", round)
+		}
+	}
+	if _, err := Check(testKey(), audit, []Holder{{Name: "holder", Store: NewDirStore(dir)}}); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("round %d: got %v, want ErrExhausted", params.Challenges, err)
+	}
+}
+
+func TestCheckWrongKey(t *testing.T) {
+	dir, audit := prepare(t, DefaultParams())
+	key := testKey()
+	key[0] ^= 1
+	report, err := Check(key, audit, []Holder{{Name: "holder", Store: NewDirStore(dir)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := failedIndices(&report.Holders[0]); len(got) != testShards {
+		t.Errorf("with the wrong key, failed shards %v, want all", got)
+	}
+}
+
+func TestAuditFileRoundTrip(t *testing.T) {
+	dir, audit := prepare(t, DefaultParams())
+	check(t, audit, dir)
+
+	path := filepath.Join(t.TempDir(), "object.audit.json")
+	if err := WriteAudit(path, audit); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadAudit(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ObjectID != audit.ObjectID || read.Params != audit.Params || len(read.Shards) != testShards {
+		t.Fatalf("read audit %+v differs from %+v", read, audit)
+	}
+	for i := range read.Shards {
+		if read.Shards[i].Next != 1 || read.Shards[i].Tags[5] != audit.Shards[i].Tags[5] {
+			t.Fatalf("shard %d differs after the round trip", i)
+		}
+	}
+	if report := check(t, read, dir); !report.OK() {
+		t.Fatal("audit read back from its file failed")
+	}
+}
+
+// flipByte flips the lowest bit of the byte at offset of a file
+func flipByte(t *testing.T, path string, offset int64) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[offset] ^= 1
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// equalInts reports whether two int slices are equal
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
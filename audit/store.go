@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rs-encoder/util"
+)
+
+// Store is a shard holder the challenger can query. The payloads are the shard payloads as
+// stored, encrypted or not, so holders are audited without the encryption key.
+type Store interface {
+	// Shards returns the payload sizes of the shards of the object the store holds, by index
+	Shards(id util.ObjectID) (map[int]int64, error)
+	// ReadRange reads length bytes at offset of the payload of a shard of the object
+	ReadRange(id util.ObjectID, index int, offset, length int64) ([]byte, error)
+}
+
+// DirStore is a Store backed by a local shard directory
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore creates a Store over the shard files of dir
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+// Shards returns the payload sizes of the shard files of the object in the directory.
+// Files with an unreadable header, or whose header does not match their name, do not count.
+func (s *DirStore) Shards(id util.ObjectID) (map[int]int64, error) {
+	_, paths, err := util.FindShardFiles(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[int]int64)
+	for index, path := range paths {
+		header, err := util.ReadShardHeader(path)
+		if err == nil && header.ObjectID == id && header.Index == index {
+			sizes[index] = header.ShardLength
+		}
+	}
+	return sizes, nil
+}
+
+// ReadRange reads a byte range of the payload of a shard file, without reading the rest
+func (s *DirStore) ReadRange(id util.ObjectID, index int, offset, length int64) ([]byte, error) {
+	name, _, err := util.FindShardFiles(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(s.dir, util.ShardFileName(name, index)))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, util.ShardHeaderSize)
+	if _, err := io.ReadFull(file, buf); err != nil {
+		return nil, fmt.Errorf("failed to read shard header: %v", err)
+	}
+	header, err := util.ParseShardHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	if header.ObjectID != id || header.Index != index {
+		return nil, fmt.Errorf("shard file holds shard %d of object %s, not shard %d of %s", header.Index, header.ObjectID, index, id)
+	}
+	if offset < 0 || length < 0 || offset+length > header.ShardLength {
+		return nil, fmt.Errorf("range %d+%d is outside the %d bytes payload", offset, length, header.ShardLength)
+	}
+
+	data := make([]byte, length)
+	if _, err := file.ReadAt(data, util.ShardHeaderSize+offset); err != nil {
+		return nil, fmt.Errorf("failed to read shard %d: %v", index, err)
+	}
+	return data, nil
+}
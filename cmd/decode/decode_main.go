@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rs-encoder/rs"
+	"rs-encoder/util"
+	"strconv"
+)
+
+func main() {
+	flag.Int("k", util.DefaultDataShards, "number of data shards")
+	flag.Int("m", util.DefaultParityShards, "number of parity shards")
+	flag.String("poly", util.FormatPrimitivePoly(util.DefaultPrimitivePoly), "primitive polynomial of GF(2^8) without the x^8 term")
+	flag.String("codec", util.DefaultCodec.String(), "encoding construction: lagrange, vandermonde or horner")
+	traceFlag := flag.Bool("trace", false, "print the evaluation points, matrices and per-position results of the decoder")
+	rootFlag := flag.String("root", "", "trusted Merkle root of a shard directory in hexadecimal, obtained out of band (default: the root of its manifest, if any, which only detects accidental corruption: whoever can rewrite the shards can rewrite the manifest)")
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [flags] <input_file> <output_file>\n", os.Args[0])
+		fmt.Printf("       %s <shard dir> <output_file>\n", os.Args[0])
+		fmt.Println("\nThe parameters recorded in the input JSON (or in the shard headers) are used unless overridden by flags.")
+		fmt.Println("\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Check command line arguments
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	inputFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
+	opts := util.TraceOptions(*traceFlag, os.Stdout)
+
+	// A shard directory as input selects file mode: the original file is rebuilt from its shard files
+	if info, err := os.Stat(inputFile); err == nil && info.IsDir() {
+		decodeFile(inputFile, outputFile, *rootFlag, opts)
+		return
+	}
+
+	// Read input from specified JSON file, versioned or legacy format
+	if *rootFlag != "" {
+		fmt.Println("Invalid -root: only shard directories have a Merkle root")
+		os.Exit(1)
+	}
+	codeword, err := util.LoadCodeword(inputFile)
+	if err != nil {
+		fmt.Printf("Cannot read input file: %v\n", err)
+		os.Exit(1)
+	}
+
+	codec, dataShards, parityShards, poly, err := resolveParams(codeword.CodeParams)
+	if err != nil {
+		fmt.Printf("Invalid parameters: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Decoding %s code with %d data and %d parity shards, polynomial 0x%02x\n", codec, dataShards, parityShards, poly)
+
+	// Reconstruct the whole codeword from any pattern of available shards
+	decoded, err := util.DecodeCodeword(codeword, codec, poly, dataShards, parityShards, opts...)
+	if err != nil {
+		fmt.Printf("Cannot decode: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print input encoded shards and indices
+	fmt.Println("Input encoded shards:")
+	util.PrintBytes(os.Stdout, decoded.Values)
+	fmt.Println("Used shard indices:", decoded.Indices)
+
+	// Print decoding result
+	fmt.Println("\nDecoding result (original message):")
+	util.PrintBytes(os.Stdout, decoded.Message)
+	fmt.Println("Recovered shard indices:", decoded.Recovered)
+
+	// Save to specified output file
+	err = util.WriteJSON(outputFile, decoded.DecodedData(util.NewCodeParams(codec, dataShards, parityShards, poly)))
+	if err != nil {
+		fmt.Printf("Cannot save output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\nDecoding result saved to", outputFile)
+}
+
+// Combine the parameters recorded in the input with the command line flags.
+// Explicitly set flags take precedence, then the input, then the flag defaults.
+func resolveParams(params util.CodeParams) (util.CodecID, int, int, byte, error) {
+	if params.Codec != "" {
+		setDefault("codec", params.Codec)
+	}
+	if params.DataShards != 0 {
+		setDefault("k", strconv.Itoa(params.DataShards))
+	}
+	if params.ParityShards != 0 {
+		setDefault("m", strconv.Itoa(params.ParityShards))
+	}
+	if params.PrimitivePoly != "" {
+		setDefault("poly", params.PrimitivePoly)
+	}
+
+	codec, err := util.ParseCodec(flag.Lookup("codec").Value.String())
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	poly, err := util.ParsePrimitivePoly(flag.Lookup("poly").Value.String())
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	dataShards := flag.Lookup("k").Value.(flag.Getter).Get().(int)
+	parityShards := flag.Lookup("m").Value.(flag.Getter).Get().(int)
+	if err := util.ValidateShardCounts(dataShards, parityShards); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return codec, dataShards, parityShards, poly, nil
+}
+
+// Set a flag to value unless it was given on the command line
+func setDefault(name, value string) {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	if !set {
+		flag.Set(name, value)
+	}
+}
+
+// Rebuild the original file from any dataShards shard files of a shard directory,
+// the parameters are taken from the shard headers. With a Merkle root, given or recorded in
+// the manifest of the object, shards without a valid inclusion proof are rejected. The root of
+// the manifest only detects accidental corruption, as it sits next to the shards.
+func decodeFile(inputDir, outputFile, rootHex string, opts []rs.Option) {
+	root, err := trustedRoot(inputDir, rootHex)
+	if err != nil {
+		fmt.Printf("Cannot read Merkle root: %v\n", err)
+		os.Exit(1)
+	}
+	var shardOpts []util.ShardOption
+	if rootHex != "" {
+		fmt.Println("Checking the shards against the given Merkle root", root)
+	} else if root != nil {
+		fmt.Println("Checking the shards against the Merkle root of their manifest", root)
+		fmt.Println("This only detects accidental corruption, give -root to detect tampered shards")
+	}
+	if root != nil {
+		shardOpts = append(shardOpts, util.WithProofs(*root))
+	}
+
+	header, shards, rejected, err := util.ReadShardFiles(inputDir, shardOpts...)
+	if err != nil {
+		fmt.Printf("Cannot read shard files: %v\n", err)
+		os.Exit(1)
+	}
+	for index, reason := range rejected {
+		fmt.Printf("Rejected shard %d: %v\n", index, reason)
+	}
+
+	// Count the available shards and report the missing ones
+	var missing []int
+	for i, shard := range shards {
+		if shard == nil {
+			missing = append(missing, i)
+		}
+	}
+	if len(shards)-len(missing) < header.DataShards {
+		fmt.Printf("Not enough shards: have %d, need %d\n", len(shards)-len(missing), header.DataShards)
+		os.Exit(1)
+	}
+	fmt.Printf("Object %s: %s code with %d+%d shards, polynomial 0x%02x\n", header.ObjectID, header.Codec, header.DataShards, header.ParityShards, header.PrimitivePoly)
+	fmt.Println("Missing shard indices:", missing)
+
+	data, err := util.DecodeObject(header, shards, opts...)
+	if err != nil {
+		fmt.Printf("Cannot decode: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := ioutil.WriteFile(outputFile, data, 0644); err != nil {
+		fmt.Printf("Cannot save output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nDecoded %d bytes from %d+%d shards, saved to %s\n",
+		len(data), header.DataShards, header.ParityShards, outputFile)
+}
+
+// Return the Merkle root given with -root, or else the one recorded in the manifest of the
+// object in dir, or nil if there is neither
+func trustedRoot(dir, rootHex string) (*util.MerkleHash, error) {
+	if rootHex != "" {
+		root, err := util.ParseMerkleHash(rootHex)
+		if err != nil {
+			return nil, err
+		}
+		return &root, nil
+	}
+
+	name, _, err := util.FindShardFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := util.ReadObjectManifest(filepath.Join(dir, util.ManifestFileName(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &manifest.MerkleRoot, nil
+}
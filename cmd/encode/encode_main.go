@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rs-encoder/rs"
+	"rs-encoder/util"
+	"strings"
+)
+
+func main() {
+	dataShardsFlag := flag.Int("k", 0, "number of data shards (default: message length, or 6 in file mode)")
+	parityShardsFlag := flag.Int("m", util.DefaultParityShards, "number of parity shards")
+	polyFlag := flag.String("poly", util.FormatPrimitivePoly(util.DefaultPrimitivePoly), "primitive polynomial of GF(2^8) without the x^8 term")
+	codecFlag := flag.String("codec", util.DefaultCodec.String(), "encoding construction: lagrange, vandermonde or horner (JSON messages only, it is not MDS)")
+	compressFlag := flag.String("compress", "none", "compress a file before encoding it: none, gzip, zlib or flate")
+	maxRatioFlag := flag.Float64("max-ratio", util.DefaultMaxCompressionRatio, "store the file uncompressed if compression does not get it below this fraction of its size")
+	traceFlag := flag.Bool("trace", false, "print the evaluation points, matrices and per-position results of the encoder and decoder")
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [flags] <input file> <output file>\n", os.Args[0])
+		fmt.Printf("       %s [flags] <input file> <output dir>/\n", os.Args[0])
+		fmt.Println("\nEncodes a JSON message into a JSON codeword, or any file into shard files when the output is a directory.")
+		fmt.Println("\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Check command line arguments
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	inputFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
+
+	// Validate the code parameters
+	poly, err := util.ParsePrimitivePoly(*polyFlag)
+	if err != nil {
+		fmt.Printf("Invalid -poly: %v\n", err)
+		os.Exit(1)
+	}
+	codec, err := util.ParseCodec(*codecFlag)
+	if err != nil {
+		fmt.Printf("Invalid -codec: %v\n", err)
+		os.Exit(1)
+	}
+	compression, err := util.ParseCompression(*compressFlag)
+	if err != nil {
+		fmt.Printf("Invalid -compress: %v\n", err)
+		os.Exit(1)
+	}
+
+	// An output directory selects file mode: any file is split into shard files
+	if isDirectory(outputFile) {
+		dataShards := *dataShardsFlag
+		if dataShards == 0 {
+			dataShards = util.DefaultDataShards
+		}
+		if err := util.ValidateShardCounts(dataShards, *parityShardsFlag); err != nil {
+			fmt.Printf("Invalid shard counts: %v\n", err)
+			os.Exit(1)
+		}
+		encodeFile(codec, poly, compression, *maxRatioFlag, inputFile, outputFile, dataShards, *parityShardsFlag, util.TraceOptions(*traceFlag, os.Stdout))
+		return
+	}
+
+	if compression != util.CompressionNone {
+		fmt.Println("Invalid -compress: only files encoded into a shard directory can be compressed")
+		os.Exit(1)
+	}
+
+	// Read input from specified JSON file, every message byte is one data shard
+	message, err := util.ReadMessageFromJSON(inputFile)
+	if err != nil {
+		fmt.Printf("Unable to read input file: %v\n", err)
+		os.Exit(1)
+	}
+	if *dataShardsFlag != 0 && *dataShardsFlag != len(message) {
+		fmt.Printf("Invalid -k: the message has %d bytes, so it is encoded into %d data shards, not %d\n", len(message), len(message), *dataShardsFlag)
+		os.Exit(1)
+	}
+
+	// Encode into the versioned codeword, including the parameters needed to decode it
+	codeword, err := util.EncodeMessage(message, codec, poly, *parityShardsFlag, util.TraceOptions(*traceFlag, os.Stdout)...)
+	if err != nil {
+		fmt.Printf("Unable to encode: %v\n", err)
+		os.Exit(1)
+	}
+	encodedData, _, err := codeword.ShardValues()
+	if err != nil {
+		fmt.Printf("Unable to encode: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print original message
+	fmt.Println("Original message (message shards):")
+	util.PrintBytes(os.Stdout, message)
+
+	// Print encoding result
+	fmt.Printf("\nEncoding result generated by %s method (codeword shards):\n", codec)
+	util.PrintBytes(os.Stdout, encodedData)
+
+	// Save to specified output file
+	err = util.WriteCodewordToJSON(outputFile, codeword)
+	if err != nil {
+		fmt.Printf("Unable to save output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\nEncoding result has been saved to", outputFile)
+}
+
+// Split a file, compressed if worth it, into dataShards data shard files and parityShards parity shard files
+func encodeFile(codec util.CodecID, poly byte, compression util.CompressionID, maxRatio float64, inputFile, outputDir string, dataShards, parityShards int, opts []rs.Option) {
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		fmt.Printf("Unable to read input file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Split into padded data shards and calculate the parity shards,
+	// every shard file describes the whole object in its header
+	header, shards, err := util.EncodeCompressedObject(data, compression, maxRatio, codec, poly, dataShards, parityShards, opts...)
+	if err != nil {
+		fmt.Printf("Unable to encode: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := util.WriteShardFiles(outputDir, filepath.Base(inputFile), header, shards); err != nil {
+		fmt.Printf("Unable to save shard files: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nEncoded %d bytes into %d data and %d parity shards of %d bytes (%s) in %s\n",
+		len(data), dataShards, parityShards, len(shards[0]), codec, outputDir)
+	if compression != util.CompressionNone {
+		fmt.Println("Compression:", header.Compression)
+	}
+	fmt.Println("Object ID:", header.ObjectID)
+}
+
+// Check whether the output path names a directory (existing, or ending with a path separator)
+func isDirectory(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(os.PathSeparator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
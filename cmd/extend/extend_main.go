@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"rs-encoder/util"
+	"strconv"
+)
+
+func main() {
+	traceFlag := flag.Bool("trace", false, "print the evaluation points, matrices and per-position results of the encoder and decoder")
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [flags] <encoded file> <extra parity count> <output file>\n", os.Args[0])
+		fmt.Println("\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Check command line arguments
+	if flag.NArg() < 3 {
+		flag.Usage()
+		return
+	}
+
+	inputFile := flag.Arg(0)
+	outputFile := flag.Arg(2)
+	opts := util.TraceOptions(*traceFlag, os.Stdout)
+
+	count, err := strconv.Atoi(flag.Arg(1))
+	if err != nil || count <= 0 {
+		fmt.Printf("Invalid extra parity count: %s\n", flag.Arg(1))
+		return
+	}
+
+	// Read previously encoded codeword from specified JSON file, versioned or legacy format
+	codeword, err := util.LoadCodeword(inputFile)
+	if err != nil {
+		fmt.Printf("Unable to read input file: %v\n", err)
+		return
+	}
+
+	// Files written before the parameters were recorded use the default field and codec
+	poly := byte(util.DefaultPrimitivePoly)
+	if codeword.PrimitivePoly != "" {
+		poly, err = util.ParsePrimitivePoly(codeword.PrimitivePoly)
+		if err != nil {
+			fmt.Printf("Invalid input file: %v\n", err)
+			return
+		}
+	}
+	codec := util.DefaultCodec
+	if codeword.Codec != "" {
+		codec, err = util.ParseCodec(codeword.Codec)
+		if err != nil {
+			fmt.Printf("Invalid input file: %v\n", err)
+			return
+		}
+	}
+	if codec != util.CodecVandermonde && codec != util.CodecLagrange {
+		fmt.Printf("Unsupported codec %s: only lagrange and vandermonde codewords can be extended\n", codec)
+		return
+	}
+	dataShards := codeword.DataShards
+	fromIndex := dataShards + codeword.ParityShards
+	if dataShards <= 0 {
+		fmt.Println("Invalid input file: the number of data shards is not recorded")
+		return
+	}
+
+	values, indices, err := codeword.ShardValues()
+	if err != nil {
+		fmt.Printf("Invalid input file: %v\n", err)
+		return
+	}
+	if len(values) < dataShards {
+		fmt.Printf("Not enough shards: have %d, need %d\n", len(values), dataShards)
+		return
+	}
+	for _, index := range indices {
+		if index >= fromIndex {
+			fromIndex = index + 1
+		}
+	}
+
+	if fromIndex+count > rs.MaxTotalShards {
+		fmt.Printf("Cannot extend %d shards by %d: at most %d shards are supported\n", fromIndex, count, rs.MaxTotalShards)
+		return
+	}
+
+	// Initialize finite field GF(2^8)
+	field := gf.NewGF(poly)
+
+	// The encoding is systematic: the message is the data part of the codeword, decoded if data shards are missing
+	decoder := rs.NewVandermondeDecoder(field, dataShards, fromIndex+count, opts...)
+	message := decoder.Decode(values, indices)
+
+	// Create encoder with the original parity count, the new shards are placed after the existing ones
+	encoder := rs.NewRSEncoder(field, dataShards, fromIndex-dataShards, opts...)
+	extraParity := encoder.ExtendParity(message, fromIndex, count)
+
+	// Print the additional parity shards
+	fmt.Printf("Additional parity shards (indices %d to %d):\n", fromIndex, fromIndex+count-1)
+	util.PrintBytes(os.Stdout, extraParity)
+
+	// Output codeword: the input shards followed by the additional parity shards
+	extraIndices := make([]int, count)
+	for i := range extraIndices {
+		extraIndices[i] = fromIndex + i
+	}
+	params := util.NewCodeParams(codec, dataShards, fromIndex+count-dataShards, poly)
+	outputData := util.NewCodeword(params, decoder.AlphaPoints(), append(values, extraParity...), append(indices, extraIndices...))
+
+	// Save to specified output file
+	err = util.WriteCodewordToJSON(outputFile, outputData)
+	if err != nil {
+		fmt.Printf("Unable to save output file: %v\n", err)
+		return
+	}
+
+	fmt.Println("\nExtended encoding result has been saved to", outputFile)
+}
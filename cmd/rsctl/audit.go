@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rs-encoder/audit"
+	"rs-encoder/rs"
+	"rs-encoder/util"
+	"sort"
+)
+
+// auditResult is the JSON result of audit prepare and keygen
+type auditResult struct {
+	Op         string `json:"op"`
+	ObjectID   string `json:"object_id,omitempty"`
+	Shards     int    `json:"shards,omitempty"`
+	Challenges int    `json:"challenges,omitempty"` // Challenges per shard
+	Output     string `json:"output"`
+}
+
+// auditCheckResult is the JSON result of audit check
+type auditCheckResult struct {
+	*audit.Report
+	Remaining int  `json:"remaining"` // Audit rounds left
+	OK        bool `json:"ok"`
+}
+
+func runAudit(args []string) error {
+	fs := newFlagSet("audit")
+	keyFile := fs.String("key", "", "audit key file, 32 bytes raw or hexadecimal (prepare, check)")
+	params := audit.DefaultParams()
+	fs.IntVar(&params.Challenges, "challenges", params.Challenges, "number of challenges per shard, one is used per check (prepare)")
+	fs.IntVar(&params.Samples, "samples", params.Samples, "number of byte ranges sampled per challenge (prepare)")
+	fs.Int64Var(&params.RangeSize, "range", params.RangeSize, "size in bytes of the sampled ranges (prepare)")
+	// Every store holds at least one shard
+	if err := parseFlags(fs, args, 2, rs.MaxTotalShards+2); err != nil {
+		return err
+	}
+
+	switch op := fs.Arg(0); op {
+	case "prepare":
+		if fs.NArg() > 3 {
+			return usageErrorf("prepare takes <shard dir> [audit file], got %d arguments", fs.NArg()-1)
+		}
+		key, err := readAuditKey(*keyFile)
+		if err != nil {
+			return err
+		}
+		return auditPrepare(key, fs.Arg(1), fs.Arg(2), params)
+	case "check":
+		if fs.NArg() < 3 {
+			return usageErrorf("check takes <audit file> <store dir...>, got %d arguments", fs.NArg()-1)
+		}
+		key, err := readAuditKey(*keyFile)
+		if err != nil {
+			return err
+		}
+		return auditCheck(key, fs.Arg(1), fs.Args()[2:])
+	case "keygen":
+		if fs.NArg() != 2 {
+			return usageErrorf("keygen takes <key file>, got %d arguments", fs.NArg()-1)
+		}
+		return auditKeygen(fs.Arg(1))
+	default:
+		return usageErrorf("unknown operation %q (expected prepare, check or keygen)", op)
+	}
+}
+
+// readAuditKey reads the audit key given with -key
+func readAuditKey(keyFile string) ([]byte, error) {
+	if keyFile == "" {
+		return nil, usageErrorf("give the audit key with -key")
+	}
+	return util.ReadKeyFile(keyFile)
+}
+
+// auditPrepare precomputes the challenges of the object in a shard directory and writes them
+// to auditFile, by default next to the manifest of the object
+func auditPrepare(key []byte, dir, auditFile string, params audit.Params) error {
+	name, paths, err := util.FindShardFiles(dir)
+	if err != nil {
+		return err
+	}
+	indices := make([]int, 0, len(paths))
+	for index := range paths {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	header, err := util.ReadShardHeader(paths[indices[0]])
+	if err != nil {
+		return err
+	}
+
+	shards := header.DataShards + header.ParityShards
+	a, err := audit.Prepare(key, audit.NewDirStore(dir), header.ObjectID, shards, params)
+	if err != nil {
+		return err
+	}
+	if auditFile == "" {
+		auditFile = filepath.Join(dir, name+".audit.json")
+	}
+	if err := audit.WriteAudit(auditFile, a); err != nil {
+		return err
+	}
+
+	out.printf("Prepared %d challenges for each of the %d shards of object %s, %d ranges of %d bytes each\n",
+		params.Challenges, shards, header.ObjectID, params.Samples, params.RangeSize)
+	out.printf("Audit written to %s; keep the audit key secret\n", auditFile)
+	out.result(auditResult{Op: "prepare", ObjectID: header.ObjectID.String(), Shards: shards, Challenges: params.Challenges, Output: auditFile})
+	return nil
+}
+
+// auditCheck runs an audit round against the shard holders in storeDirs and saves the used
+// challenges to the audit file
+func auditCheck(key []byte, auditFile string, storeDirs []string) error {
+	a, err := audit.ReadAudit(auditFile)
+	if err != nil {
+		return err
+	}
+	holders := make([]audit.Holder, len(storeDirs))
+	for i, dir := range storeDirs {
+		holders[i] = audit.Holder{Name: dir, Store: audit.NewDirStore(dir)}
+	}
+
+	report, err := audit.Check(key, a, holders)
+	if errors.Is(err, audit.ErrExhausted) {
+		return fmt.Errorf("%s: %v", auditFile, err)
+	}
+	if err != nil {
+		return err
+	}
+	// The challenges of this round are known to the holders now, they must not be reused
+	if err := audit.WriteAudit(auditFile, a); err != nil {
+		return err
+	}
+
+	remaining := a.Challenges
+	for i := range a.Shards {
+		remaining = min(remaining, a.Shards[i].Remaining())
+	}
+	for _, holder := range report.Holders {
+		switch {
+		case holder.Error != "":
+			out.printf("Holder %s: FAILED, %s\n", holder.Holder, holder.Error)
+		case len(holder.Failed) > 0:
+			out.printf("Holder %s: FAILED, passed %v\n", holder.Holder, holder.Passed)
+		default:
+			out.printf("Holder %s: passed %v\n", holder.Holder, holder.Passed)
+		}
+		for _, failure := range holder.Failed {
+			out.printf("  shard %d: %s\n", failure.Index, failure.Reason)
+		}
+	}
+	if len(report.Unheld) > 0 {
+		out.printf("Shards no holder proved it holds: %v\n", report.Unheld)
+	}
+	out.printf("%d audit rounds left\n", remaining)
+	out.result(auditCheckResult{Report: report, Remaining: remaining, OK: report.OK()})
+
+	if !report.OK() {
+		return &exitError{exitDamaged, fmt.Errorf("object %s failed the audit", a.ObjectID)}
+	}
+	return nil
+}
+
+// auditKeygen writes a new random audit key in hexadecimal
+func auditKeygen(keyFile string) error {
+	key := make([]byte, audit.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return err
+	}
+	out.printf("Audit key written to %s\n", keyFile)
+	out.result(auditResult{Op: "keygen", Output: keyFile})
+	return nil
+}
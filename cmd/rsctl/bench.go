@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"rs-encoder/util"
+	"testing"
+)
+
+// benchResult is the result of one benchmark
+type benchResult struct {
+	Name        string  `json:"name"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     int64   `json:"ns_per_op"`
+	MBPerSecond float64 `json:"mb_per_second"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+}
+
+// benchmark is a benchmark of bench processing bytes bytes per operation
+type benchmark struct {
+	name  string
+	bytes int64
+	fn    func(b *testing.B)
+}
+
+// benchReport is the JSON result of bench
+type benchReport struct {
+	util.CodeParams
+	Size       int           `json:"size"`
+	Lost       int           `json:"lost"`
+	Benchmarks []benchResult `json:"benchmarks"`
+}
+
+func runBench(args []string) error {
+	fs := newFlagSet("bench")
+	params := addCodeFlags(fs, util.DefaultDataShards, "number of data shards")
+	size := fs.Int("size", 1<<20, "object size in bytes")
+	lost := fs.Int("lost", -1, "number of shards lost before reconstruction (default: the number of parity shards)")
+	constantTime := fs.Bool("constant-time", false, "use the constant-time field operations")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	codec, dataShards, parityShards, poly, err := params.resolve(util.CodeParams{})
+	if err != nil {
+		return err
+	}
+	if *size <= 0 {
+		return usageErrorf("invalid -size %d", *size)
+	}
+	if *lost < 0 {
+		*lost = parityShards
+	}
+	if *lost > parityShards {
+		return usageErrorf("cannot lose %d shards with %d parity shards", *lost, parityShards)
+	}
+
+	field := gf.NewGF(poly)
+	if *constantTime {
+		field = gf.NewConstantTimeGF(poly)
+	}
+	coder, err := rs.NewCodec(codec.String(), field, dataShards, parityShards)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, *size)
+	rand.Read(data)
+
+	shards := rs.SplitShards(data, dataShards, dataShards+parityShards)
+	if err := coder.Encode(shards); err != nil {
+		return err
+	}
+	stripe := make([][]byte, len(shards))
+	loseShards := func() {
+		// Lose the first shards, data shards first, so that they must be recalculated
+		copy(stripe, shards)
+		for j := 0; j < *lost; j++ {
+			stripe[j] = nil
+		}
+	}
+
+	// Codes that are not MDS cannot reconstruct every pattern
+	loseShards()
+	if err := coder.Reconstruct(stripe); err != nil {
+		return err
+	}
+
+	report := benchReport{CodeParams: util.NewCodeParams(codec, dataShards, parityShards, poly), Size: *size, Lost: *lost}
+	benchmarks := []benchmark{
+		{"encode", int64(*size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				coder.Encode(shards)
+			}
+		}},
+		{"reconstruct", int64(*size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				loseShards()
+				if err := coder.Reconstruct(stripe); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}},
+	}
+	if codec == util.CodecVandermonde || codec == util.CodecLagrange {
+		intoBenchmarks, err := newIntoBenchmarks(field, shards, dataShards, *lost)
+		if err != nil {
+			return err
+		}
+		benchmarks = append(benchmarks, intoBenchmarks...)
+	}
+
+	out.printf("Benchmarking %s %d+%d, %d bytes, %d shards lost\n", codec, dataShards, parityShards, *size, *lost)
+	for _, bm := range benchmarks {
+		bm := bm
+		r := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(bm.bytes)
+			bm.fn(b)
+		})
+		result := benchResult{
+			Name:        bm.name,
+			Iterations:  r.N,
+			NsPerOp:     r.NsPerOp(),
+			BytesPerOp:  r.AllocedBytesPerOp(),
+			AllocsPerOp: r.AllocsPerOp(),
+		}
+		if r.T > 0 {
+			result.MBPerSecond = float64(r.Bytes) * float64(r.N) / 1e6 / r.T.Seconds()
+		}
+		report.Benchmarks = append(report.Benchmarks, result)
+		out.printf("%-16s %8d iterations %12d ns/op %10.2f MB/s %10d B/op %6d allocs/op\n",
+			result.Name, result.Iterations, result.NsPerOp, result.MBPerSecond, result.BytesPerOp, result.AllocsPerOp)
+	}
+	out.result(report)
+	return nil
+}
+
+// newIntoBenchmarks creates the benchmarks of the allocation-free APIs of the Vandermonde
+// encoder and decoder on the stripe of an encoded object: one codeword per operation for
+// EncodeInto and DecodeInto, and ReconstructInto on buffers from a ShardPool. Each result
+// is checked once before benchmarking; steady state must report 0 allocs/op.
+func newIntoBenchmarks(field *gf.GF, shards [][]byte, dataShards, lost int) ([]benchmark, error) {
+	totalShards := len(shards)
+	encoder := rs.NewRSEncoder(field, dataShards, totalShards-dataShards)
+	decoder := rs.NewVandermondeDecoder(field, dataShards, totalShards)
+
+	// The first codeword (column) of the stripe, decoded from its last dataShards shards
+	message := make([]byte, dataShards)
+	codeword := make([]byte, totalShards)
+	for i := range codeword {
+		codeword[i] = shards[i][0]
+	}
+	copy(message, codeword)
+	indices := make([]int, dataShards)
+	for j := range indices {
+		indices[j] = totalShards - dataShards + j
+	}
+	available := codeword[totalShards-dataShards:]
+
+	encoded := make([]byte, totalShards)
+	encoder.EncodeInto(encoded, message)
+	decoded := make([]byte, dataShards)
+	decoder.DecodeInto(decoded, available, indices)
+	if !bytes.Equal(encoded, codeword) || !bytes.Equal(decoded, message) {
+		return nil, fmt.Errorf("EncodeInto and DecodeInto do not match the codec")
+	}
+
+	// Read the present shards into pooled buffers and recover the lost ones in place
+	pool := rs.NewShardPool()
+	stripe := make([]*[]byte, totalShards)
+	buffers := make([][]byte, totalShards)
+	present := make([]bool, totalShards)
+	for i := range present {
+		present[i] = i >= lost
+	}
+	reconstruct := func() {
+		pool.GetStripe(stripe, len(shards[0]))
+		for i, buf := range stripe {
+			buffers[i] = *buf
+			if present[i] {
+				copy(buffers[i], shards[i])
+			}
+		}
+		decoder.ReconstructInto(buffers, present)
+	}
+	reconstruct()
+	for i := range shards {
+		if !bytes.Equal(buffers[i], shards[i]) {
+			return nil, fmt.Errorf("ReconstructInto does not match shard %d", i)
+		}
+	}
+	pool.PutStripe(stripe)
+
+	return []benchmark{
+		{"encode-into", int64(dataShards), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				encoder.EncodeInto(encoded, message)
+			}
+		}},
+		{"decode-into", int64(dataShards), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				decoder.DecodeInto(decoded, available, indices)
+			}
+		}},
+		{"reconstruct-into", int64(dataShards * len(shards[0])), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				reconstruct()
+				pool.PutStripe(stripe)
+			}
+		}},
+	}, nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"rs-encoder/util"
+	"strconv"
+	"strings"
+)
+
+// codeFlags are the code parameter flags of encode, decode and bench
+type codeFlags struct {
+	fs *flag.FlagSet
+}
+
+// addCodeFlags registers -k, -m, -poly and -codec
+func addCodeFlags(fs *flag.FlagSet, dataShards int, dataShardsUsage string) *codeFlags {
+	fs.Int("k", dataShards, dataShardsUsage)
+	fs.Int("m", util.DefaultParityShards, "number of parity shards")
+	fs.String("poly", util.FormatPrimitivePoly(util.DefaultPrimitivePoly), "primitive polynomial of GF(2^8) without the x^8 term")
+	fs.String("codec", util.DefaultCodec.String(), "encoding construction: lagrange, vandermonde or horner (JSON messages only, it is not MDS)")
+	return &codeFlags{fs}
+}
+
+// resolve combines the parameters recorded in an input with the flags.
+// Explicitly set flags take precedence, then the input, then the flag defaults.
+func (c *codeFlags) resolve(params util.CodeParams) (util.CodecID, int, int, byte, error) {
+	set := make(map[string]bool)
+	c.fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	setDefault := func(name, value string) {
+		if !set[name] && value != "" && value != "0" {
+			c.fs.Set(name, value)
+		}
+	}
+	setDefault("codec", params.Codec)
+	setDefault("k", strconv.Itoa(params.DataShards))
+	setDefault("m", strconv.Itoa(params.ParityShards))
+	setDefault("poly", params.PrimitivePoly)
+
+	codec, err := util.ParseCodec(c.fs.Lookup("codec").Value.String())
+	if err != nil {
+		return 0, 0, 0, 0, usageErrorf("invalid -codec: %v", err)
+	}
+	poly, err := util.ParsePrimitivePoly(c.fs.Lookup("poly").Value.String())
+	if err != nil {
+		return 0, 0, 0, 0, usageErrorf("invalid -poly: %v", err)
+	}
+	dataShards := c.fs.Lookup("k").Value.(flag.Getter).Get().(int)
+	parityShards := c.fs.Lookup("m").Value.(flag.Getter).Get().(int)
+	if err := util.ValidateShardCounts(dataShards, parityShards); err != nil {
+		return 0, 0, 0, 0, usageErrorf("invalid shard counts: %v", err)
+	}
+	return codec, dataShards, parityShards, poly, nil
+}
+
+// encodeResult is the JSON result of encode
+type encodeResult struct {
+	util.CodeParams
+	Output      string `json:"output"`
+	ObjectID    string `json:"object_id,omitempty"`
+	ObjectSize  int64  `json:"object_size,omitempty"`
+	ShardSize   int    `json:"shard_size,omitempty"`
+	Compression string `json:"compression,omitempty"` // Compression used, none if it was skipped
+	Encrypted   bool   `json:"encrypted,omitempty"`
+	MerkleRoot  string `json:"merkle_root,omitempty"`
+}
+
+func runEncode(args []string) error {
+	fs := newFlagSet("encode")
+	params := addCodeFlags(fs, 0, "number of data shards (default: message length, or 6 in file mode)")
+	crypt := addCipherFlags(fs)
+	compressFlag := fs.String("compress", "none", "compress a file before encoding it: none, gzip, zlib or flate")
+	maxRatio := fs.Float64("max-ratio", util.DefaultMaxCompressionRatio, "store the file uncompressed if compression does not get it below this fraction of its size")
+	withProofs := fs.Bool("proofs", false, "write a manifest with the Merkle root of the shards and an inclusion proof per shard")
+	if err := parseFlags(fs, args, 2, 2); err != nil {
+		return err
+	}
+	input, output := fs.Arg(0), fs.Arg(1)
+	compression, err := util.ParseCompression(*compressFlag)
+	if err != nil {
+		return usageErrorf("invalid -compress: %v", err)
+	}
+
+	// An output directory selects file mode: any file is split into shard files
+	if isDirectory(output) {
+		codec, dataShards, parityShards, poly, err := params.resolve(util.CodeParams{DataShards: util.DefaultDataShards})
+		if err != nil {
+			return err
+		}
+		if !codec.MDS() {
+			return usageErrorf("invalid -codec: %s is not MDS and only encodes JSON messages, use vandermonde or lagrange for files", codec)
+		}
+		shardOpts, err := crypt.options()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return err
+		}
+		header, shards, err := util.EncodeCompressedObject(data, compression, *maxRatio, codec, poly, dataShards, parityShards, out.options()...)
+		if err != nil {
+			return err
+		}
+		if err := util.WriteShardFiles(output, filepath.Base(input), header, shards, shardOpts...); err != nil {
+			return err
+		}
+		var manifest *util.ObjectManifest
+		if *withProofs {
+			if manifest, err = util.WriteProofFiles(output, filepath.Base(input), header, shards); err != nil {
+				return err
+			}
+		}
+
+		out.printf("Encoded %d bytes into %d data and %d parity shards of %d bytes (%s) in %s\n",
+			len(data), dataShards, parityShards, len(shards[0]), codec, output)
+		switch {
+		case header.Compression != util.CompressionNone:
+			out.printf("Compressed with %s before encoding\n", header.Compression)
+		case compression != util.CompressionNone:
+			out.printf("Stored uncompressed: %s does not get the file below %.2f of its size\n", compression, *maxRatio)
+		}
+		if crypt.set() {
+			out.printf("Shards encrypted with AES-256-GCM\n")
+		}
+		out.printf("Object ID: %s\n", header.ObjectID)
+		result := encodeResult{
+			CodeParams:  util.NewCodeParams(codec, dataShards, parityShards, poly),
+			Output:      output,
+			ObjectID:    header.ObjectID.String(),
+			ObjectSize:  header.ObjectSize,
+			ShardSize:   len(shards[0]),
+			Compression: header.Compression.String(),
+			Encrypted:   crypt.set(),
+		}
+		if manifest != nil {
+			out.printf("Merkle root: %s (manifest %s)\n", manifest.MerkleRoot, filepath.Join(output, util.ManifestFileName(filepath.Base(input))))
+			result.MerkleRoot = manifest.MerkleRoot.String()
+		}
+		out.result(result)
+		return nil
+	}
+	if crypt.set() {
+		return usageErrorf("-key and -passphrase-file only apply to shard directories")
+	}
+	if compression != util.CompressionNone {
+		return usageErrorf("-compress only applies to shard directories")
+	}
+	if *withProofs {
+		return usageErrorf("-proofs only applies to shard directories")
+	}
+
+	// Every message byte is one data shard
+	message, err := util.ReadMessageFromJSON(input)
+	if err != nil {
+		return err
+	}
+	codec, dataShards, parityShards, poly, err := params.resolve(util.CodeParams{DataShards: len(message)})
+	if err != nil {
+		return err
+	}
+	if dataShards != len(message) {
+		return usageErrorf("invalid -k: the message has %d bytes, so it is encoded into %d data shards, not %d", len(message), len(message), dataShards)
+	}
+	codeword, err := util.EncodeMessage(message, codec, poly, parityShards, out.options()...)
+	if err != nil {
+		return err
+	}
+	if err := util.WriteCodewordToJSON(output, codeword); err != nil {
+		return err
+	}
+
+	encoded, _, err := codeword.ShardValues()
+	if err != nil {
+		return err
+	}
+	out.bytes("Original message (message shards):", message)
+	out.bytes("Encoding result (codeword shards):", encoded)
+	out.printf("Encoded %d data and %d parity shards (%s) to %s\n", dataShards, parityShards, codec, output)
+	out.result(encodeResult{CodeParams: codeword.CodeParams, Output: output})
+	return nil
+}
+
+// decodeFileResult is the JSON result of decoding a shard directory
+type decodeFileResult struct {
+	util.CodeParams
+	Output     string         `json:"output"`
+	ObjectID   string         `json:"object_id"`
+	ObjectSize int64          `json:"object_size"`
+	Missing    []int          `json:"missing"`
+	Rejected   []rejectedInfo `json:"rejected"`
+	MerkleRoot string         `json:"merkle_root,omitempty"`
+}
+
+// rejectedInfo is a shard file left out of the decoding
+type rejectedInfo struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+func runDecode(args []string) error {
+	fs := newFlagSet("decode")
+	params := addCodeFlags(fs, util.DefaultDataShards, "number of data shards")
+	crypt := addCipherFlags(fs)
+	proofs := addProofFlags(fs)
+	if err := parseFlags(fs, args, 2, 2); err != nil {
+		return err
+	}
+	input, output := fs.Arg(0), fs.Arg(1)
+
+	// A shard directory as input selects file mode, the parameters come from the shard headers
+	if info, err := os.Stat(input); err == nil && info.IsDir() {
+		shardOpts, err := crypt.options()
+		if err != nil {
+			return err
+		}
+		object, err := readObject(input, proofs, shardOpts...)
+		if err != nil {
+			return err
+		}
+		data, err := util.DecodeObject(object.header, object.shards, out.options()...)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return err
+		}
+
+		object.print()
+		out.printf("Decoded %d bytes from %d+%d shards, saved to %s\n", len(data), object.header.DataShards, object.header.ParityShards, output)
+		result := decodeFileResult{
+			CodeParams: headerParams(object.header),
+			Output:     output,
+			ObjectID:   object.header.ObjectID.String(),
+			ObjectSize: object.header.ObjectSize,
+			Missing:    object.missing,
+			Rejected:   object.rejectedList(),
+		}
+		if object.root != nil {
+			result.MerkleRoot = object.root.String()
+		}
+		out.result(result)
+		return nil
+	}
+
+	if crypt.set() {
+		return usageErrorf("-key and -passphrase-file only apply to shard directories")
+	}
+	if *proofs.root != "" {
+		return usageErrorf("-root only applies to shard directories")
+	}
+	codeword, err := util.LoadCodeword(input)
+	if err != nil {
+		return err
+	}
+	codec, dataShards, parityShards, poly, err := params.resolve(codeword.CodeParams)
+	if err != nil {
+		return err
+	}
+	decoded, err := util.DecodeCodeword(codeword, codec, poly, dataShards, parityShards, out.options()...)
+	if err != nil {
+		return err
+	}
+	result := decoded.DecodedData(util.NewCodeParams(codec, dataShards, parityShards, poly))
+	if err := util.WriteJSON(output, result); err != nil {
+		return err
+	}
+
+	out.printf("Decoding %s code with %d data and %d parity shards, polynomial 0x%02x\n", codec, dataShards, parityShards, poly)
+	out.bytes("Input encoded shards:", decoded.Values)
+	out.printf("Used shard indices: %v\n", decoded.Indices)
+	out.bytes("Decoding result (original message):", decoded.Message)
+	out.printf("Recovered shard indices: %v\n", decoded.Recovered)
+	out.result(result)
+	return nil
+}
+
+// headerParams returns the code parameters recorded in a shard header
+func headerParams(header util.ShardHeader) util.CodeParams {
+	return util.NewCodeParams(header.Codec, header.DataShards, header.ParityShards, header.PrimitivePoly)
+}
+
+// isDirectory checks whether the output path names a directory (existing, or ending with a path separator)
+func isDirectory(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(os.PathSeparator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"rs-encoder/util"
+)
+
+// cipherFlags are the shard encryption flags of encode, decode, verify and repair
+type cipherFlags struct {
+	key        *string
+	passphrase *string
+}
+
+// addCipherFlags registers -key and -passphrase-file
+func addCipherFlags(fs *flag.FlagSet) *cipherFlags {
+	return &cipherFlags{
+		key:        fs.String("key", "", "encrypt or decrypt the shards with the 32 bytes key in this file (raw or hexadecimal)"),
+		passphrase: fs.String("passphrase-file", "", "encrypt or decrypt the shards with a key derived from the passphrase in this file (- for stdin)"),
+	}
+}
+
+// set reports whether a key or a passphrase was given
+func (c *cipherFlags) set() bool {
+	return *c.key != "" || *c.passphrase != ""
+}
+
+// options returns the shard options selected by the flags, none without a key or passphrase
+func (c *cipherFlags) options() ([]util.ShardOption, error) {
+	switch {
+	case *c.key != "" && *c.passphrase != "":
+		return nil, usageErrorf("-key and -passphrase-file cannot be combined")
+	case *c.key != "":
+		key, err := util.ReadKeyFile(*c.key)
+		if err != nil {
+			return nil, err
+		}
+		defer clear(key)
+		cipher, err := util.NewShardCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return []util.ShardOption{util.WithCipher(cipher)}, nil
+	case *c.passphrase != "":
+		passphrase, err := readPassphrase(*c.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		defer clear(passphrase)
+		cipher, err := util.NewPassphraseCipher(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []util.ShardOption{util.WithCipher(cipher)}, nil
+	}
+	return nil, nil
+}
+
+// readPassphrase reads the first line of a passphrase file, or of stdin for "-"
+func readPassphrase(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if end := bytes.IndexAny(data, "\r\n"); end >= 0 {
+		data = data[:end]
+	}
+	return data, nil
+}
+
+// keyError explains why no shard of a directory could be read when they all failed for want
+// of the right key, and returns nil otherwise
+func keyError(dir string, rejected map[int]error) error {
+	required, plain, failed := 0, 0, 0
+	for _, err := range rejected {
+		switch {
+		case errors.Is(err, util.ErrKeyRequired):
+			required++
+		case errors.Is(err, util.ErrNotEncrypted):
+			plain++
+		case errors.Is(err, util.ErrAuthentication):
+			failed++
+		}
+	}
+	switch {
+	case required > 0 && required == len(rejected):
+		return usageErrorf("the shards in %s are encrypted: give -key or -passphrase-file", dir)
+	case plain > 0 && plain == len(rejected):
+		return usageErrorf("the shards in %s are not encrypted: drop -key and -passphrase-file", dir)
+	case failed > 0 && failed == len(rejected):
+		return fmt.Errorf("no shard in %s could be authenticated: wrong key or passphrase, or tampered shards", dir)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"rs-encoder/gf"
+	"rs-encoder/util"
+	"strconv"
+)
+
+// gfResult is the JSON result of a field operation
+type gfResult struct {
+	PrimitivePoly string   `json:"primitive_poly"`
+	Op            string   `json:"op"`
+	Operands      []string `json:"operands,omitempty"`
+	Result        string   `json:"result,omitempty"`
+	Polys         []string `json:"polys,omitempty"`      // Primitive polynomials, for "polys" and "check"
+	Mismatches    []string `json:"mismatches,omitempty"` // Differences found by "check"
+}
+
+// gfArity is the number of operands of every operation
+var gfArity = map[string]int{
+	"add":   2,
+	"sub":   2,
+	"mul":   2,
+	"div":   2,
+	"inv":   1,
+	"pow":   2,
+	"polys": 0,
+	"check": 0,
+}
+
+func runGF(args []string) error {
+	fs := newFlagSet("gf")
+	polyFlag := fs.String("poly", util.FormatPrimitivePoly(util.DefaultPrimitivePoly), "primitive polynomial of GF(2^8) without the x^8 term")
+	constantTime := fs.Bool("constant-time", false, "calculate with the constant-time field operations")
+	if err := parseFlags(fs, args, 1, 3); err != nil {
+		return err
+	}
+
+	op := fs.Arg(0)
+	arity, ok := gfArity[op]
+	if !ok {
+		return usageErrorf("unknown operation %q (expected add, sub, mul, div, inv, pow, polys or check)", op)
+	}
+	if fs.NArg()-1 != arity {
+		return usageErrorf("%s takes %d operands, got %d", op, arity, fs.NArg()-1)
+	}
+	poly, err := util.ParsePrimitivePoly(*polyFlag)
+	if err != nil {
+		return usageErrorf("invalid -poly: %v", err)
+	}
+	result := gfResult{PrimitivePoly: util.FormatPrimitivePoly(poly), Op: op, Operands: fs.Args()[1:]}
+
+	// polys lists every primitive polynomial that can be passed to -poly
+	if op == "polys" {
+		for p := 0; p < 256; p++ {
+			if gf.IsPrimitive(byte(p)) {
+				result.Polys = append(result.Polys, util.FormatPrimitivePoly(byte(p)))
+				out.printf("%s\n", util.FormatPrimitivePoly(byte(p)))
+			}
+		}
+		out.result(result)
+		return nil
+	}
+
+	// check compares the constant-time and table-based fields of every primitive polynomial
+	if op == "check" {
+		return checkConstantTime(result)
+	}
+
+	// Operands are field elements in hexadecimal or decimal, the exponent of pow is an integer
+	a, err := parseElement(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	var b byte
+	var exponent int
+	if op == "pow" {
+		if exponent, err = strconv.Atoi(fs.Arg(2)); err != nil {
+			return usageErrorf("invalid exponent %q", fs.Arg(2))
+		}
+	} else if arity == 2 {
+		if b, err = parseElement(fs.Arg(2)); err != nil {
+			return err
+		}
+	}
+
+	field := gf.NewGF(poly)
+	if *constantTime {
+		field = gf.NewConstantTimeGF(poly)
+	}
+	var value byte
+	switch op {
+	case "add":
+		value = field.Add(a, b)
+	case "sub":
+		value = field.Sub(a, b)
+	case "mul":
+		value = field.Mul(a, b)
+	case "div":
+		if b == 0 {
+			return usageErrorf("division by zero")
+		}
+		value = field.Div(a, b)
+	case "inv":
+		if a == 0 {
+			return usageErrorf("0 has no multiplicative inverse")
+		}
+		value = field.Inv(a)
+	case "pow":
+		value = field.Pow(a, exponent)
+	}
+
+	result.Result = fmt.Sprintf("0x%02x", value)
+	out.printf("0x%02x (%d)\n", value, value)
+	out.result(result)
+	return nil
+}
+
+// checkConstantTime compares every operation of the constant-time fields with the
+// table-based fields for all operands and every primitive polynomial
+func checkConstantTime(result gfResult) error {
+	for p := 0; p < 256; p++ {
+		if !gf.IsPrimitive(byte(p)) {
+			continue
+		}
+		poly := byte(p)
+		result.Polys = append(result.Polys, util.FormatPrimitivePoly(poly))
+		tables, constantTime := gf.NewGF(poly), gf.NewConstantTimeGF(poly)
+		mismatch := func(format string, args ...interface{}) {
+			result.Mismatches = append(result.Mismatches, util.FormatPrimitivePoly(poly)+": "+fmt.Sprintf(format, args...))
+		}
+
+		src := make([]byte, 256)
+		for a := 0; a < 256; a++ {
+			src[a] = byte(a)
+		}
+		for a := 0; a < 256; a++ {
+			x := byte(a)
+			for b := 0; b < 256; b++ {
+				y := byte(b)
+				if got, want := constantTime.Mul(x, y), tables.Mul(x, y); got != want {
+					mismatch("mul(0x%02x, 0x%02x) = 0x%02x, expected 0x%02x", x, y, got, want)
+				}
+				if (y != 0 || x == 0) && constantTime.Div(x, y) != tables.Div(x, y) {
+					mismatch("div(0x%02x, 0x%02x) = 0x%02x, expected 0x%02x", x, y, constantTime.Div(x, y), tables.Div(x, y))
+				}
+			}
+			if x != 0 && constantTime.Inv(x) != tables.Inv(x) {
+				mismatch("inv(0x%02x) = 0x%02x, expected 0x%02x", x, constantTime.Inv(x), tables.Inv(x))
+			}
+			for e := -300; e <= 300; e++ {
+				if constantTime.Pow(x, e) != tables.Pow(x, e) {
+					mismatch("pow(0x%02x, %d) = 0x%02x, expected 0x%02x", x, e, constantTime.Pow(x, e), tables.Pow(x, e))
+				}
+			}
+
+			// MulAddSlice of every element with coefficient a
+			got, want := make([]byte, 256), make([]byte, 256)
+			constantTime.MulAddSlice(got, src, x)
+			tables.MulAddSlice(want, src, x)
+			if !bytes.Equal(got, want) {
+				mismatch("MulAddSlice with coefficient 0x%02x differs", x)
+			}
+		}
+	}
+
+	if len(result.Mismatches) > 0 {
+		for _, m := range result.Mismatches {
+			out.printf("%s\n", m)
+		}
+		out.result(result)
+		return fmt.Errorf("constant-time field differs from the table-based field in %d operations", len(result.Mismatches))
+	}
+	out.printf("Constant-time field matches the table-based field for %d primitive polynomials\n", len(result.Polys))
+	out.result(result)
+	return nil
+}
+
+// parseElement parses a field element given in hexadecimal ("0x1d") or decimal ("29")
+func parseElement(s string) (byte, error) {
+	value, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, usageErrorf("invalid field element %q: must be a byte such as 0x1d", s)
+	}
+	return byte(value), nil
+}
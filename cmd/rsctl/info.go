@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"rs-encoder/util"
+	"sort"
+)
+
+// shardInfo is the JSON representation of a shard header
+type shardInfo struct {
+	util.CodeParams
+	Index       int    `json:"index"`
+	ShardLength int64  `json:"shard_length"`
+	ObjectSize  int64  `json:"object_size"`
+	ObjectID    string `json:"object_id"`
+	Checksum    string `json:"checksum"`
+	Compression string `json:"compression"`
+	Encrypted   bool   `json:"encrypted"`
+	Error       string `json:"error,omitempty"`
+}
+
+// infoResult is the JSON result of info
+type infoResult struct {
+	Kind     string        `json:"kind"` // "shard_dir", "shard" or "codeword"
+	Shards   []shardInfo   `json:"shards,omitempty"`
+	Codeword *codewordInfo `json:"codeword,omitempty"`
+}
+
+// codewordInfo describes a JSON codeword
+type codewordInfo struct {
+	util.CodeParams
+	FormatVersion int   `json:"format_version"`
+	ShardIndices  []int `json:"shard_indices"`
+}
+
+func runInfo(args []string) error {
+	fs := newFlagSet("info")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	// Shard directory: the header of every shard file, without reading the payloads
+	if info.IsDir() {
+		_, paths, err := util.FindShardFiles(path)
+		if err != nil {
+			return err
+		}
+		indices := make([]int, 0, len(paths))
+		for index := range paths {
+			indices = append(indices, index)
+		}
+		sort.Ints(indices)
+
+		result := infoResult{Kind: "shard_dir"}
+		for _, index := range indices {
+			shard := shardInfo{Index: index}
+			if header, err := util.ReadShardHeader(paths[index]); err != nil {
+				shard.Error = err.Error()
+			} else {
+				shard = newShardInfo(header)
+			}
+			result.Shards = append(result.Shards, shard)
+			printShardInfo(paths[index], shard)
+		}
+		out.result(result)
+		return nil
+	}
+
+	// Shard file, recognized by its magic
+	if isShardFile(path) {
+		header, err := util.ReadShardHeader(path)
+		if err != nil {
+			return err
+		}
+		shard := newShardInfo(header)
+		printShardInfo(path, shard)
+		out.result(infoResult{Kind: "shard", Shards: []shardInfo{shard}})
+		return nil
+	}
+
+	// Otherwise a JSON codeword, versioned or legacy
+	codeword, err := util.LoadCodeword(path)
+	if err != nil {
+		return err
+	}
+	_, indices, err := codeword.ShardValues()
+	if err != nil {
+		return err
+	}
+	result := &codewordInfo{CodeParams: codeword.CodeParams, FormatVersion: codeword.FormatVersion, ShardIndices: indices}
+	if codeword.FormatVersion == 0 {
+		out.printf("Codeword (legacy format)\n")
+	} else {
+		out.printf("Codeword (format version %d)\n", codeword.FormatVersion)
+	}
+	out.printf("  codec: %s, data shards: %d, parity shards: %d, polynomial: %s\n",
+		valueOrUnknown(codeword.Codec), codeword.DataShards, codeword.ParityShards, valueOrUnknown(codeword.PrimitivePoly))
+	out.printf("  shard indices: %v\n", indices)
+	out.result(infoResult{Kind: "codeword", Codeword: result})
+	return nil
+}
+
+// newShardInfo returns the JSON representation of a shard header
+func newShardInfo(header util.ShardHeader) shardInfo {
+	return shardInfo{
+		CodeParams:  headerParams(header),
+		Index:       header.Index,
+		ShardLength: header.ShardLength,
+		ObjectSize:  header.ObjectSize,
+		ObjectID:    header.ObjectID.String(),
+		Checksum:    fmt.Sprintf("%08x", header.Checksum),
+		Compression: header.Compression.String(),
+		Encrypted:   header.Flags&util.FlagEncrypted != 0,
+	}
+}
+
+// printShardInfo prints a shard header on one line
+func printShardInfo(path string, shard shardInfo) {
+	if shard.Error != "" {
+		out.printf("%s: %s\n", path, shard.Error)
+		return
+	}
+	storage := ""
+	if shard.Compression != util.CompressionNone.String() {
+		storage += ", " + shard.Compression + " compressed"
+	}
+	if shard.Encrypted {
+		storage += ", encrypted"
+	}
+	out.printf("%s: shard %d of a %d+%d %s code (polynomial %s), %d bytes%s, crc32c %s, object %s (%d bytes)\n",
+		path, shard.Index, shard.DataShards, shard.ParityShards, shard.Codec, shard.PrimitivePoly,
+		shard.ShardLength, storage, shard.Checksum, shard.ObjectID, shard.ObjectSize)
+}
+
+// isShardFile reports whether the file starts with the shard magic
+func isShardFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(util.ShardMagic))
+	n, _ := file.Read(magic)
+	return bytes.Equal(magic[:n], []byte(util.ShardMagic))
+}
+
+// valueOrUnknown returns the value, or "unknown" if it is not recorded
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
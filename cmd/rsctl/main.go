@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"rs-encoder/rs"
+	"rs-encoder/util"
+)
+
+// Exit codes shared by all commands
+const (
+	exitOK      = 0 // Success
+	exitFailure = 1 // The operation failed
+	exitUsage   = 2 // Invalid command line
+	exitDamaged = 3 // The object has missing, corrupted or inconsistent shards
+)
+
+// command is an rsctl subcommand
+type command struct {
+	name    string
+	args    string // Argument synopsis shown in the usage
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order shown by the usage
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"encode", "<input> <output>", "encode a JSON message into a codeword, or a file into a shard directory", runEncode},
+		{"decode", "<input> <output>", "decode a JSON codeword, or rebuild a file from its shard directory", runDecode},
+		{"verify", "<shard dir | shard file>", "check the shards of an object for missing, corrupted or inconsistent shards, or the inclusion proof of one shard", runVerify},
+		{"repair", "<shard dir>", "rewrite the missing and corrupted shard files of an object", runRepair},
+		{"info", "<shard dir | shard file | codeword file>", "show the parameters of an object, a shard or a codeword", runInfo},
+		{"gf", "<op> <a> [b]", "calculate in GF(2^8): add, sub, mul, div, inv, pow, or list primitive polynomials", runGF},
+		{"bench", "", "measure encoding and reconstruction throughput", runBench},
+		{"sss", "<split <secret file> <output dir> | combine <share dir | share file...> <output> | refresh <share dir | share file...> | keygen <key file>>", "split a secret into Shamir shares, combine shares into the secret detecting forged shares, or refresh shares", runSSS},
+		{"audit", "<prepare <shard dir> [audit file] | check <audit file> <store dir...> | keygen <key file>>", "prepare proof-of-retrievability challenges, or audit that shard holders still hold their shards", runAudit},
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			os.Exit(exit(cmd, cmd.run(os.Args[2:])))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "rsctl: unknown command %q\n\n", name)
+	usage()
+	os.Exit(exitUsage)
+}
+
+// usage prints the list of commands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: rsctl <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nEvery command accepts --json, --quiet and --trace; run \"rsctl <command> -h\" for its flags.")
+	fmt.Fprintf(os.Stderr, "\nExit codes: %d success, %d failure, %d invalid command line, %d damaged object\n",
+		exitOK, exitFailure, exitUsage, exitDamaged)
+}
+
+// exitError carries the exit code of a failed command
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// usageErrorf returns an error reported with exitUsage
+func usageErrorf(format string, args ...interface{}) error {
+	return &exitError{exitUsage, fmt.Errorf(format, args...)}
+}
+
+// exit reports the error returned by a command and returns the process exit code
+func exit(cmd *command, err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	code := exitFailure
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.code
+	}
+
+	fmt.Fprintf(os.Stderr, "rsctl %s: %v\n", cmd.name, err)
+	if code == exitUsage {
+		fmt.Fprintf(os.Stderr, "Usage: rsctl %s [flags] %s\n", cmd.name, cmd.args)
+	}
+	if out.json && !out.wrote {
+		out.result(struct {
+			Error    string `json:"error"`
+			ExitCode int    `json:"exit_code"`
+		}{err.Error(), code})
+	}
+	return code
+}
+
+// output controls what a command prints. Human readable messages go to stdout unless
+// --quiet or --json is given; with --json the command prints a single JSON document.
+// The diagnostics of the encoders and decoders are only shown with --trace, on stderr.
+type output struct {
+	json  bool
+	quiet bool
+	trace bool
+	wrote bool // A JSON document was printed
+}
+
+// out is the output of the running command
+var out output
+
+// newFlagSet creates the flag set of a command with the output flags registered
+func newFlagSet(cmd string) *flag.FlagSet {
+	fs := flag.NewFlagSet("rsctl "+cmd, flag.ContinueOnError)
+	fs.Usage = func() {
+		for _, c := range commands {
+			if c.name == cmd {
+				fmt.Fprintf(os.Stderr, "Usage: rsctl %s [flags] %s\n\n%s\n\nFlags:\n", c.name, c.args, c.summary)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&out.json, "json", false, "print a machine-readable JSON result")
+	fs.BoolVar(&out.quiet, "quiet", false, "print nothing but errors")
+	fs.BoolVar(&out.trace, "trace", false, "also print the encoder and decoder diagnostics to stderr")
+	return fs
+}
+
+// parseFlags parses the command line of a command and checks its number of arguments
+func parseFlags(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &exitError{exitUsage, err}
+	}
+	if out.quiet && out.trace {
+		return usageErrorf("--quiet and --trace cannot be combined")
+	}
+	if fs.NArg() < minArgs || fs.NArg() > maxArgs {
+		if minArgs == maxArgs {
+			return usageErrorf("expected %d arguments, got %d", minArgs, fs.NArg())
+		}
+		return usageErrorf("expected %d to %d arguments, got %d", minArgs, maxArgs, fs.NArg())
+	}
+	return nil
+}
+
+// options returns the options that route the encoder and decoder diagnostics to stderr with --trace
+func (o *output) options() []rs.Option {
+	return util.TraceOptions(o.trace, os.Stderr)
+}
+
+// printf prints a human readable message
+func (o *output) printf(format string, args ...interface{}) {
+	if !o.quiet && !o.json {
+		fmt.Printf(format, args...)
+	}
+}
+
+// bytes prints a labelled array of bytes
+func (o *output) bytes(label string, values []byte) {
+	if !o.quiet && !o.json {
+		fmt.Println(label)
+		util.PrintBytes(os.Stdout, values)
+	}
+}
+
+// result prints the JSON result of a command when --json is given
+func (o *output) result(v interface{}) {
+	if !o.json {
+		return
+	}
+	o.wrote = true
+	writeJSON(os.Stdout, v)
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"rs-encoder/util"
+	"sort"
+)
+
+// object holds the shards read from a shard directory
+type object struct {
+	dir          string
+	name         string
+	header       util.ShardHeader
+	shards       [][]byte // One entry per shard index, nil if missing or rejected
+	missing      []int    // Indices without a valid shard file
+	rejected     map[int]error
+	root         *util.MerkleHash // Merkle root the shards were checked against, nil without proofs
+	manifestRoot bool             // Whether root is the one of the manifest rather than -root
+}
+
+// readObject reads and verifies the shard files of a shard directory, decrypting them with the
+// cipher of the options if any. If the object has a trusted Merkle root, shards without a
+// valid inclusion proof are rejected.
+func readObject(dir string, proofs *proofFlags, opts ...util.ShardOption) (*object, error) {
+	name, _, err := util.FindShardFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, err := proofs.trustedRoot(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if root != nil {
+		opts = append(opts, util.WithProofs(*root))
+	}
+	header, shards, rejected, err := util.ReadShardFiles(dir, opts...)
+	if err != nil {
+		if keyErr := keyError(dir, rejected); keyErr != nil {
+			return nil, keyErr
+		}
+		if proofErr := proofError(dir, root, rejected); proofErr != nil {
+			return nil, proofErr
+		}
+		return nil, err
+	}
+
+	obj := &object{dir: dir, name: name, header: header, shards: shards, missing: []int{}, rejected: rejected, root: root, manifestRoot: *proofs.root == ""}
+	for i, shard := range shards {
+		if shard == nil {
+			obj.missing = append(obj.missing, i)
+		}
+	}
+	return obj, nil
+}
+
+// available returns the number of valid shards
+func (o *object) available() int {
+	return len(o.shards) - len(o.missing)
+}
+
+// print prints the object parameters and the damaged shards
+func (o *object) print() {
+	out.printf("Object %s: %s code with %d+%d shards, polynomial 0x%02x, %d bytes\n",
+		o.header.ObjectID, o.header.Codec, o.header.DataShards, o.header.ParityShards, o.header.PrimitivePoly, o.header.ObjectSize)
+	if o.header.Compression != util.CompressionNone {
+		out.printf("Object compressed with %s before encoding\n", o.header.Compression)
+	}
+	if o.root != nil && o.manifestRoot {
+		out.printf("Shards checked against the Merkle root of their manifest %s, which only detects accidental corruption\n", o.root)
+	} else if o.root != nil {
+		out.printf("Shards checked against the given Merkle root %s\n", o.root)
+	}
+	for _, rejected := range o.rejectedList() {
+		out.printf("Rejected shard %d: %s\n", rejected.Index, rejected.Reason)
+	}
+	out.printf("Missing shard indices: %v\n", o.missing)
+}
+
+// rejectedList returns the rejected shard files in index order
+func (o *object) rejectedList() []rejectedInfo {
+	list := make([]rejectedInfo, 0, len(o.rejected))
+	for index, reason := range o.rejected {
+		list = append(list, rejectedInfo{index, reason.Error()})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Index < list[j].Index
+	})
+	return list
+}
+
+// verifyResult is the JSON result of verify
+type verifyResult struct {
+	util.CodeParams
+	ObjectID     string         `json:"object_id"`
+	ObjectSize   int64          `json:"object_size"`
+	Available    int            `json:"available"`
+	Missing      []int          `json:"missing"`
+	Rejected     []rejectedInfo `json:"rejected"`
+	Inconsistent []int          `json:"inconsistent"`
+	Healthy      bool           `json:"healthy"`
+	Recoverable  bool           `json:"recoverable"`
+	MerkleRoot   string         `json:"merkle_root,omitempty"`
+}
+
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	crypt := addCipherFlags(fs)
+	proofs := addProofFlags(fs)
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	shardOpts, err := crypt.options()
+	if err != nil {
+		return err
+	}
+
+	// A single shard file is checked against the Merkle root alone
+	if info, err := os.Stat(fs.Arg(0)); err == nil && !info.IsDir() {
+		return verifyShardProof(fs.Arg(0), proofs, shardOpts...)
+	}
+	obj, err := readObject(fs.Arg(0), proofs, shardOpts...)
+	if err != nil {
+		return err
+	}
+	result := verifyResult{
+		CodeParams:   headerParams(obj.header),
+		ObjectID:     obj.header.ObjectID.String(),
+		ObjectSize:   obj.header.ObjectSize,
+		Available:    obj.available(),
+		Missing:      obj.missing,
+		Rejected:     obj.rejectedList(),
+		Inconsistent: []int{},
+	}
+	if obj.root != nil {
+		result.MerkleRoot = obj.root.String()
+	}
+
+	// Recalculate the redundant shards to check that all valid shards belong to one codeword
+	if obj.available() >= obj.header.DataShards {
+		result.Inconsistent, err = util.VerifyObject(obj.header, obj.shards, out.options()...)
+		if err != nil {
+			return err
+		}
+		result.Recoverable = len(result.Inconsistent) == 0
+	}
+	result.Healthy = result.Recoverable && len(obj.missing) == 0
+
+	obj.print()
+	if len(result.Inconsistent) > 0 {
+		out.printf("Shards inconsistent with the others: %v\n", result.Inconsistent)
+	}
+	switch {
+	case result.Healthy:
+		out.printf("All %d shards are valid\n", len(obj.shards))
+	case result.Recoverable:
+		out.printf("%d of %d shards are valid, the object can be repaired\n", obj.available(), len(obj.shards))
+	}
+	out.result(result)
+
+	if !result.Recoverable {
+		if len(result.Inconsistent) > 0 {
+			return &exitError{exitDamaged, fmt.Errorf("the valid shards do not form one codeword")}
+		}
+		return fmt.Errorf("not enough shards: have %d, need %d", obj.available(), obj.header.DataShards)
+	}
+	if !result.Healthy {
+		return &exitError{exitDamaged, fmt.Errorf("%d shards are missing or corrupted", len(obj.missing))}
+	}
+	return nil
+}
+
+// repairResult is the JSON result of repair
+type repairResult struct {
+	ObjectID string `json:"object_id"`
+	Repaired []int  `json:"repaired"`
+}
+
+func runRepair(args []string) error {
+	fs := newFlagSet("repair")
+	crypt := addCipherFlags(fs)
+	proofs := addProofFlags(fs)
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	shardOpts, err := crypt.options()
+	if err != nil {
+		return err
+	}
+
+	obj, err := readObject(fs.Arg(0), proofs, shardOpts...)
+	if err != nil {
+		return err
+	}
+	obj.print()
+
+	// Rebuilding from shards that disagree would spread the damage to the rewritten shards
+	if obj.available() >= obj.header.DataShards {
+		inconsistent, err := util.VerifyObject(obj.header, obj.shards, out.options()...)
+		if err != nil {
+			return err
+		}
+		if len(inconsistent) > 0 {
+			return &exitError{exitDamaged, fmt.Errorf("the valid shards do not form one codeword, shards %v disagree", inconsistent)}
+		}
+	}
+	if err := util.ReconstructObject(obj.header, obj.shards, out.options()...); err != nil {
+		return err
+	}
+
+	// Nothing is written unless the rebuilt codeword hashes to the trusted Merkle root
+	if obj.root != nil && len(obj.missing) > 0 {
+		if root := util.NewShardTree(obj.header, obj.shards).Root(); root != *obj.root {
+			return &exitError{exitDamaged, fmt.Errorf("the repaired shards have Merkle root %s, not %s", root, obj.root)}
+		}
+	}
+
+	header := obj.header
+	for _, index := range obj.missing {
+		header.Index = index
+		path := filepath.Join(obj.dir, util.ShardFileName(obj.name, index))
+		if err := util.WriteShardFile(path, header, obj.shards[index], shardOpts...); err != nil {
+			return err
+		}
+		out.printf("Repaired shard %d: %s\n", index, path)
+	}
+	if obj.root != nil && len(obj.missing) > 0 {
+		if _, err := util.WriteProofFiles(obj.dir, obj.name, obj.header, obj.shards, obj.missing...); err != nil {
+			return err
+		}
+	}
+	if len(obj.missing) == 0 {
+		out.printf("Nothing to repair\n")
+	}
+	out.result(repairResult{ObjectID: obj.header.ObjectID.String(), Repaired: obj.missing})
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rs-encoder/util"
+	"strings"
+)
+
+// proofFlags are the inclusion proof flags of decode, verify and repair
+type proofFlags struct {
+	root *string
+}
+
+// addProofFlags registers -root
+func addProofFlags(fs *flag.FlagSet) *proofFlags {
+	return &proofFlags{
+		root: fs.String("root", "", "trusted Merkle root of the object in hexadecimal, obtained out of band: reject shards without an inclusion proof of it (default: the root of the manifest next to the shards, if any, which only detects accidental corruption: whoever can rewrite the shards can rewrite the manifest)"),
+	}
+}
+
+// trustedRoot returns the Merkle root shards of the object name in dir must prove inclusion
+// in: the -root given, or else the root recorded in the manifest of the object. It returns
+// nil if there is neither. The root of the manifest only detects accidental corruption:
+// whoever can rewrite the shards can rewrite the manifest.
+func (p *proofFlags) trustedRoot(dir, name string) (*util.MerkleHash, error) {
+	if *p.root != "" {
+		root, err := util.ParseMerkleHash(*p.root)
+		if err != nil {
+			return nil, usageErrorf("invalid -root: %v", err)
+		}
+		return &root, nil
+	}
+
+	manifestFile := filepath.Join(dir, util.ManifestFileName(name))
+	manifest, err := util.ReadObjectManifest(manifestFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "rsctl: no -root given, checking the shards against the Merkle root recorded in %s: this only detects accidental corruption, give -root to detect tampered shards\n", manifestFile)
+	return &manifest.MerkleRoot, nil
+}
+
+// proofError explains why no shard of a directory could be read when they all lack a valid
+// inclusion proof, and returns nil otherwise
+func proofError(dir string, root *util.MerkleHash, rejected map[int]error) error {
+	if root == nil || len(rejected) == 0 {
+		return nil
+	}
+	for _, err := range rejected {
+		if !errors.Is(err, util.ErrProof) {
+			return nil
+		}
+	}
+	return &exitError{exitDamaged, fmt.Errorf("no shard in %s has a valid inclusion proof of Merkle root %s", dir, root)}
+}
+
+// proofResult is the JSON result of verifying a single shard file
+type proofResult struct {
+	ObjectID   string `json:"object_id"`
+	Index      int    `json:"index"`
+	MerkleRoot string `json:"merkle_root"`
+	Valid      bool   `json:"valid"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// verifyShardProof checks that a single shard file belongs to its object, with its inclusion
+// proof and the trusted Merkle root, without reading the other shards
+func verifyShardProof(path string, proofs *proofFlags, opts ...util.ShardOption) error {
+	// "<name>.NNN" names the manifest "<name>.manifest.json"
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	root, err := proofs.trustedRoot(filepath.Dir(path), name)
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return usageErrorf("%s has no manifest next to it: give the Merkle root of the object with -root", path)
+	}
+
+	header, _, err := util.ReadShardFile(path, append(opts, util.WithProofs(*root))...)
+	result := proofResult{ObjectID: header.ObjectID.String(), Index: header.Index, MerkleRoot: root.String(), Valid: err == nil}
+	if errors.Is(err, util.ErrKeyRequired) || errors.Is(err, util.ErrNotEncrypted) {
+		return usageErrorf("%s: %v", path, err)
+	}
+	if err != nil {
+		if !errors.Is(err, util.ErrProof) && !errors.Is(err, util.ErrChecksum) && !errors.Is(err, util.ErrAuthentication) {
+			return err
+		}
+		result.Reason = err.Error()
+		out.result(result)
+		return &exitError{exitDamaged, fmt.Errorf("%s: %v", path, err)}
+	}
+
+	out.printf("Shard %d belongs to object %s with Merkle root %s\n", header.Index, header.ObjectID, root)
+	out.result(result)
+	return nil
+}
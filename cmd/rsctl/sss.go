@@ -0,0 +1,496 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rs-encoder/sss"
+	"strings"
+)
+
+// sssResult is the JSON result of sss split, combine, refresh and keygen
+type sssResult struct {
+	Op           string   `json:"op"`
+	SecretID     string   `json:"secret_id,omitempty"`
+	Threshold    int      `json:"threshold,omitempty"`
+	Shares       []int    `json:"shares,omitempty"` // Indices of the shares written or read
+	Files        []string `json:"files,omitempty"`
+	Manifest     string   `json:"manifest,omitempty"`
+	Verified     bool     `json:"verified"`               // The shares were checked against a signed manifest
+	Inconsistent []int    `json:"inconsistent,omitempty"` // Shares excluded as forged or corrupted
+	Stale        []int    `json:"stale,omitempty"`        // Shares skipped for being from before a refresh
+	Epoch        uint32   `json:"epoch,omitempty"`
+	Size         int      `json:"size,omitempty"` // Secret size in bytes
+	Output       string   `json:"output,omitempty"`
+	PublicKey    string   `json:"public_key,omitempty"` // Dealer public key, for keygen
+}
+
+func runSSS(args []string) error {
+	fs := newFlagSet("sss")
+	shares := fs.Int("n", 5, "number of shares to create (split)")
+	threshold := fs.Int("t", 3, "number of shares needed to recover the secret (split)")
+	sign := fs.String("sign", "", "dealer private key file: sign a manifest committing to the shares (split, refresh)")
+	manifest := fs.String("manifest", "", "manifest to check the shares against (combine, refresh, default: the manifest next to the shares)")
+	pubkey := fs.String("pubkey", "", "trusted dealer public key file (combine, refresh, default: trust the key of the manifest)")
+	if err := parseFlags(fs, args, 2, sss.MaxShares+2); err != nil {
+		return err
+	}
+
+	switch op := fs.Arg(0); op {
+	case "split":
+		if fs.NArg() != 3 {
+			return usageErrorf("split takes <secret file> <output dir>, got %d arguments", fs.NArg()-1)
+		}
+		return sssSplit(fs.Arg(1), fs.Arg(2), *shares, *threshold, *sign)
+	case "combine":
+		if fs.NArg() < 3 {
+			return usageErrorf("combine takes <share dir | share file...> <output>, got %d arguments", fs.NArg()-1)
+		}
+		return sssCombine(fs.Args()[1:fs.NArg()-1], fs.Arg(fs.NArg()-1), *manifest, *pubkey)
+	case "refresh":
+		return sssRefresh(fs.Args()[1:], *manifest, *pubkey, *sign)
+	case "keygen":
+		if fs.NArg() != 2 {
+			return usageErrorf("keygen takes <key file>, got %d arguments", fs.NArg()-1)
+		}
+		return sssKeygen(fs.Arg(1))
+	default:
+		return usageErrorf("unknown operation %q (expected split, combine, refresh or keygen)", op)
+	}
+}
+
+// sssSplit splits a secret file into share files written to outputDir, with a signed
+// manifest if a dealer key file is given
+func sssSplit(input, outputDir string, n, t int, keyFile string) error {
+	if t < sss.MinThreshold || t > n || n > sss.MaxShares {
+		return usageErrorf("invalid -t %d of -n %d: need %d <= t <= n <= %d", t, n, sss.MinThreshold, sss.MaxShares)
+	}
+	secret, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	defer clear(secret)
+
+	var shares []sss.Share
+	var manifest *sss.Manifest
+	if keyFile != "" {
+		key, err := readPrivateKey(keyFile)
+		if err != nil {
+			return err
+		}
+		shares, manifest, err = sss.SplitVerifiable(secret, n, t, key)
+		if err != nil {
+			return err
+		}
+	} else if shares, err = sss.Split(secret, n, t); err != nil {
+		return err
+	}
+
+	name := filepath.Base(input)
+	if err := sss.WriteShareFiles(outputDir, name, shares); err != nil {
+		return err
+	}
+	result := sssResult{Op: "split", SecretID: shares[0].ID.String(), Threshold: t, Size: len(secret)}
+	for _, share := range shares {
+		result.Shares = append(result.Shares, share.Index)
+		result.Files = append(result.Files, filepath.Join(outputDir, sss.ShareFileName(name, share.Index)))
+	}
+	if manifest != nil {
+		result.Manifest = filepath.Join(outputDir, sss.ManifestFileName(name))
+		if err := sss.WriteManifest(result.Manifest, manifest); err != nil {
+			return err
+		}
+		result.Verified = true
+	}
+
+	out.printf("Split %d bytes into %d shares, any %d recover the secret\n", len(secret), n, t)
+	out.printf("Secret %s, share files written to %s\n", result.SecretID, outputDir)
+	if manifest != nil {
+		out.printf("Signed manifest written to %s\n", result.Manifest)
+	}
+	out.result(result)
+	return nil
+}
+
+// sssCombine recovers a secret from share files, or the share files of a directory.
+// Unreadable or corrupted share files of a directory are reported and skipped. Forged
+// shares are excluded using the manifest, found next to the shares unless given, and the
+// redundancy of the shares beyond the threshold.
+func sssCombine(inputs []string, output, manifestFile, pubkeyFile string) error {
+	set, err := loadShares(inputs, manifestFile)
+	if err != nil {
+		return err
+	}
+	shares, manifestFile := set.shares, set.manifest
+
+	result := sssResult{Op: "combine", Output: output, Manifest: manifestFile, Stale: set.stale}
+	var secret []byte
+	if manifestFile != "" {
+		manifest, publicKey, err := readManifest(manifestFile, pubkeyFile)
+		if err != nil {
+			return err
+		}
+		secret, result.Inconsistent, err = sss.CombineVerified(shares, manifest, publicKey)
+		if err != nil {
+			return err
+		}
+		result.Verified = true
+	} else {
+		if pubkeyFile != "" {
+			return usageErrorf("-pubkey needs a manifest")
+		}
+		if secret, result.Inconsistent, err = sss.CombineDetect(shares); err != nil {
+			return err
+		}
+	}
+	defer clear(secret)
+
+	if err := os.WriteFile(output, secret, 0600); err != nil {
+		return fmt.Errorf("failed to write secret: %v", err)
+	}
+
+	result.SecretID = shares[0].ID.String()
+	result.Threshold = shares[0].Threshold
+	result.Epoch = shares[0].Epoch
+	result.Size = len(secret)
+	for _, share := range shares {
+		result.Shares = append(result.Shares, share.Index)
+	}
+	out.printf("Recovered %d bytes of secret %s from %d shares, saved to %s\n", len(secret), result.SecretID, len(shares), output)
+	if result.Verified {
+		out.printf("Shares checked against the signed manifest %s\n", manifestFile)
+	}
+	out.result(result)
+	if len(result.Inconsistent) > 0 {
+		return &exitError{exitDamaged, fmt.Errorf("shares %v are forged or corrupted and were excluded", result.Inconsistent)}
+	}
+	return nil
+}
+
+// sssRefresh refreshes share files in place: the holders of the shares run a refresh
+// together, so that the shares left out and every copy of the old shares can no longer be
+// combined with the new ones. Shares failing the manifest are left out; a manifest is
+// re-signed for the new shares.
+func sssRefresh(inputs []string, manifestFile, pubkeyFile, keyFile string) error {
+	set, err := loadShares(inputs, manifestFile)
+	if err != nil {
+		return err
+	}
+	if set.manifest != "" && keyFile == "" {
+		return usageErrorf("%s would no longer match the refreshed shares: give -sign to re-sign it", set.manifest)
+	}
+	var key ed25519.PrivateKey
+	if keyFile != "" {
+		if key, err = readPrivateKey(keyFile); err != nil {
+			return err
+		}
+	}
+
+	result := sssResult{Op: "refresh", Stale: set.stale}
+	shares, paths := set.shares, set.paths
+	total := 0
+	for _, share := range shares {
+		total = max(total, share.Index)
+	}
+	if set.manifest != "" {
+		manifest, publicKey, err := readManifest(set.manifest, pubkeyFile)
+		if err != nil {
+			return err
+		}
+		if err := manifest.Verify(publicKey); err != nil {
+			return err
+		}
+		var validPaths []string
+		var valid []sss.Share
+		for i, share := range shares {
+			if err := manifest.CheckShare(share); err != nil {
+				fmt.Fprintf(os.Stderr, "rsctl sss: leaving out %s: %v\n", paths[i], err)
+				result.Inconsistent = append(result.Inconsistent, share.Index)
+				continue
+			}
+			valid = append(valid, share)
+			validPaths = append(validPaths, paths[i])
+		}
+		shares, paths = valid, validPaths
+		total = len(manifest.Commitments)
+		result.Verified = true
+	}
+
+	refreshed, err := sss.Refresh(shares)
+	if err != nil {
+		return err
+	}
+	files := make([]replacement, len(refreshed))
+	for i, share := range refreshed {
+		buf, err := share.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		files[i] = replacement{path: paths[i], data: buf, perm: 0600}
+	}
+	if key != nil {
+		manifest, err := sss.NewManifest(refreshed, total, key)
+		if err != nil {
+			return err
+		}
+		result.Manifest = set.manifest
+		if result.Manifest == "" {
+			name := strings.TrimSuffix(filepath.Base(paths[0]), filepath.Ext(paths[0]))
+			result.Manifest = filepath.Join(filepath.Dir(paths[0]), sss.ManifestFileName(name))
+		}
+		data, err := sss.EncodeManifest(manifest)
+		if err != nil {
+			return err
+		}
+		files = append(files, replacement{path: result.Manifest, data: data, perm: 0644})
+	}
+	// The shares and the manifest are replaced together, so that a failure leaves the old
+	// epoch usable rather than a mix of epochs
+	if err := replaceFiles(files); err != nil {
+		return err
+	}
+
+	first := refreshed[0]
+	result.SecretID = first.ID.String()
+	result.Threshold = first.Threshold
+	result.Epoch = first.Epoch
+	result.Files = paths
+	for _, share := range refreshed {
+		result.Shares = append(result.Shares, share.Index)
+	}
+	out.printf("Refreshed %d shares of secret %s to epoch %d, any %d recover the secret\n",
+		len(refreshed), result.SecretID, result.Epoch, result.Threshold)
+	if result.Manifest != "" {
+		out.printf("Signed manifest written to %s\n", result.Manifest)
+	}
+	out.result(result)
+	if len(result.Inconsistent) > 0 {
+		return &exitError{exitDamaged, fmt.Errorf("shares %v failed the manifest and were left out of the refresh", result.Inconsistent)}
+	}
+	return nil
+}
+
+// replacement is the new content of a file replaced by replaceFiles
+type replacement struct {
+	path string
+	data []byte
+	perm os.FileMode
+}
+
+// replaceFiles replaces files with their new content, keeping the old files until every
+// replacement succeeded. The new files are written next to the old ones first; the old ones
+// are then moved to a .old file and the new ones moved in place. If a rename fails, the
+// files replaced so far are restored from their .old files.
+func replaceFiles(files []replacement) error {
+	for i, f := range files {
+		if err := os.WriteFile(f.path+".tmp", f.data, f.perm); err != nil {
+			for _, written := range files[:i+1] {
+				os.Remove(written.path + ".tmp")
+			}
+			return fmt.Errorf("failed to write %s: %v", f.path, err)
+		}
+	}
+
+	existed := make([]bool, len(files)) // Whether the old file was moved to .old
+	for i, f := range files {
+		err := os.Rename(f.path, f.path+".old")
+		existed[i] = err == nil
+		if err == nil || os.IsNotExist(err) {
+			err = os.Rename(f.path+".tmp", f.path)
+		}
+		if err == nil {
+			continue
+		}
+
+		for _, pending := range files[i:] {
+			os.Remove(pending.path + ".tmp")
+		}
+		if existed[i] {
+			if restoreErr := os.Rename(f.path+".old", f.path); restoreErr != nil {
+				return fmt.Errorf("failed to replace %s: %v, and to restore it from %s.old: %v", f.path, err, f.path, restoreErr)
+			}
+		}
+		for j := i - 1; j >= 0; j-- {
+			restoreErr := os.Remove(files[j].path)
+			if existed[j] {
+				restoreErr = os.Rename(files[j].path+".old", files[j].path)
+			}
+			if restoreErr != nil {
+				return fmt.Errorf("failed to replace %s: %v, and to restore %s: %v", f.path, err, files[j].path, restoreErr)
+			}
+		}
+		return fmt.Errorf("failed to replace %s, the old files were kept: %v", f.path, err)
+	}
+
+	for i, f := range files {
+		if existed[i] {
+			os.Remove(f.path + ".old")
+		}
+	}
+	return nil
+}
+
+// shareSet is a set of shares read by loadShares
+type shareSet struct {
+	shares   []sss.Share
+	paths    []string // File of each share
+	stale    []int    // Indices of the shares of a directory skipped for an older epoch
+	manifest string   // Manifest to check the shares against, if any
+}
+
+// loadShares reads share files, or the share files of a directory. Unreadable or corrupted
+// share files of a directory are reported and skipped, as are shares from before the last
+// refresh. Without a manifest file, the single manifest next to the shares is used if any.
+func loadShares(inputs []string, manifestFile string) (*shareSet, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no share files given")
+	}
+	dirMode := len(inputs) == 1 && isDirectory(inputs[0])
+	shareDir := filepath.Dir(inputs[0])
+	if dirMode {
+		shareDir = inputs[0]
+		paths, err := sss.FindShareFiles(shareDir)
+		if err != nil {
+			return nil, err
+		}
+		inputs = paths
+	}
+	set := &shareSet{manifest: manifestFile}
+	if set.manifest == "" {
+		manifests, _ := filepath.Glob(filepath.Join(shareDir, "*.manifest.json"))
+		if len(manifests) == 1 {
+			set.manifest = manifests[0]
+		}
+	}
+
+	var epoch uint32
+	for _, path := range inputs {
+		share, err := sss.ReadShareFile(path)
+		if err != nil {
+			if !dirMode {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "rsctl sss: rejected %v\n", err)
+			continue
+		}
+		set.shares = append(set.shares, share)
+		set.paths = append(set.paths, path)
+		epoch = max(epoch, share.Epoch)
+	}
+	if len(set.shares) == 0 {
+		return nil, fmt.Errorf("no valid shares")
+	}
+
+	if dirMode {
+		var shares []sss.Share
+		var paths []string
+		for i, share := range set.shares {
+			if share.Epoch < epoch {
+				fmt.Fprintf(os.Stderr, "rsctl sss: skipped %s: epoch %d is older than %d\n", set.paths[i], share.Epoch, epoch)
+				set.stale = append(set.stale, share.Index)
+				continue
+			}
+			shares = append(shares, share)
+			paths = append(paths, set.paths[i])
+		}
+		set.shares, set.paths = shares, paths
+	}
+	return set, nil
+}
+
+// readManifest reads a manifest and the trusted dealer public key, if a key file is given
+func readManifest(manifestFile, pubkeyFile string) (*sss.Manifest, ed25519.PublicKey, error) {
+	manifest, err := sss.ReadManifest(manifestFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pubkeyFile == "" {
+		fmt.Fprintf(os.Stderr, "rsctl sss: no -pubkey given, trusting the dealer key recorded in %s\n", manifestFile)
+		return manifest, nil, nil
+	}
+	publicKey, err := readPublicKey(pubkeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, publicKey, nil
+}
+
+// sssKeygen creates a dealer key pair: the private key in keyFile and the public key in keyFile.pub
+func sssKeygen(keyFile string) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		return err
+	}
+
+	result := sssResult{Op: "keygen", Files: []string{keyFile, keyFile + ".pub"}, PublicKey: hex.EncodeToString(publicKey)}
+	out.printf("Dealer key written to %s, public key %s to %s.pub\n", keyFile, result.PublicKey, keyFile)
+	out.result(result)
+	return nil
+}
+
+// readPEM reads the DER content of a PEM file of the given type
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s: expected a PEM %s", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// readPrivateKey reads an ed25519 private key written by keygen
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 key", path)
+	}
+	return privateKey, nil
+}
+
+// readPublicKey reads an ed25519 public key written by keygen
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 key", path)
+	}
+	return publicKey, nil
+}
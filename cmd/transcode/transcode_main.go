@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"rs-encoder/util"
+	"strconv"
+)
+
+func main() {
+	codecFlag := flag.String("codec", "", "codec of the new layout: lagrange or vandermonde (default: the codec of the input shards)")
+	traceFlag := flag.Bool("trace", false, "print the evaluation points, matrices and per-position results of the encoder and decoders")
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [flags] <input dir> <new data shards> <new parity shards> <output dir>\n", os.Args[0])
+		fmt.Println("\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Check command line arguments
+	if flag.NArg() < 4 {
+		flag.Usage()
+		return
+	}
+
+	inputDir := flag.Arg(0)
+	newDataShards, newParityShards, err := parseLayout(flag.Arg(1), flag.Arg(2))
+	if err != nil {
+		fmt.Printf("Invalid new layout: %v\n", err)
+		return
+	}
+	outputDir := flag.Arg(3)
+
+	// Find the shards of the old layout
+	name, paths, err := util.FindShardFiles(inputDir)
+	if err != nil {
+		fmt.Printf("Unable to read input shards: %v\n", err)
+		return
+	}
+
+	// Open and verify the available shards, the old layout is taken from their headers
+	var header util.ShardHeader
+	var in []io.ReaderAt
+	for index, path := range paths {
+		shard, err := util.OpenShardFile(path)
+		if err != nil {
+			fmt.Printf("Rejected shard %d: %v\n", index, err)
+			continue
+		}
+		defer shard.Close()
+
+		if in == nil {
+			header = shard.Header
+			in = make([]io.ReaderAt, header.DataShards+header.ParityShards)
+		} else if !shard.Header.SameObject(header) {
+			fmt.Printf("Rejected shard %d: belongs to object %s, not %s\n", index, shard.Header.ObjectID, header.ObjectID)
+			continue
+		}
+		in[shard.Header.Index] = shard
+	}
+	if in == nil {
+		fmt.Println("No valid shards found")
+		return
+	}
+	newCodecID := header.Codec
+	if *codecFlag != "" {
+		if newCodecID, err = util.ParseCodec(*codecFlag); err != nil {
+			fmt.Printf("Invalid new codec: %v\n", err)
+			return
+		}
+	}
+	if !newCodecID.MDS() {
+		fmt.Printf("Unsupported codec %s: shards can only be transcoded to lagrange or vandermonde\n", newCodecID)
+		return
+	}
+
+	available := 0
+	for _, shard := range in {
+		if shard != nil {
+			available++
+		}
+	}
+	fmt.Printf("Found %d of %d shards of object %s (%d bytes each)\n", available, len(in), header.ObjectID, header.ShardLength)
+
+	// The new layout keeps the field and object identity, the old shards are decoded with their codec
+	field := gf.NewGF(header.PrimitivePoly)
+	opts := util.TraceOptions(*traceFlag, os.Stdout)
+	oldCodec, err := rs.NewCodec(header.Codec.String(), field, header.DataShards, header.ParityShards, opts...)
+	if err != nil {
+		fmt.Printf("Unsupported old layout: %v\n", err)
+		return
+	}
+	newCodec, err := rs.NewCodec(newCodecID.String(), field, newDataShards, newParityShards, opts...)
+	if err != nil {
+		fmt.Printf("Invalid new layout: %v\n", err)
+		return
+	}
+	transcoder := rs.NewCodecTranscoder(oldCodec, newCodec)
+
+	// Create the shard files of the new layout
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Printf("Unable to create output directory: %v\n", err)
+		return
+	}
+	newHeader := header
+	newHeader.Codec = newCodecID
+	newHeader.DataShards = newDataShards
+	newHeader.ParityShards = newParityShards
+	writers := make([]*util.ShardFileWriter, newDataShards+newParityShards)
+	out := make([]io.Writer, len(writers))
+	for i := range writers {
+		newHeader.Index = i
+		writers[i], err = util.CreateShardFile(filepath.Join(outputDir, util.ShardFileName(name, i)), newHeader)
+		if err != nil {
+			fmt.Printf("Unable to create output shard: %v\n", err)
+			return
+		}
+		out[i] = writers[i]
+	}
+
+	err = transcoder.Transcode(in, header.ShardLength, header.DataSize(), out)
+	for _, writer := range writers {
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Printf("Transcoding failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\nTranscoded %s %d+%d layout to %s %d+%d layout (%d bytes per shard) in %s\n",
+		header.Codec, header.DataShards, header.ParityShards, newCodecID, newDataShards, newParityShards, transcoder.NewShardSize(header.DataSize()), outputDir)
+}
+
+// Parse data and parity shard counts
+func parseLayout(dataArg, parityArg string) (int, int, error) {
+	dataShards, err := strconv.Atoi(dataArg)
+	if err != nil || dataShards <= 0 {
+		return 0, 0, fmt.Errorf("invalid data shard count %q", dataArg)
+	}
+	parityShards, err := strconv.Atoi(parityArg)
+	if err != nil || parityShards < 0 {
+		return 0, 0, fmt.Errorf("invalid parity shard count %q", parityArg)
+	}
+	if dataShards+parityShards > rs.MaxTotalShards {
+		return 0, 0, fmt.Errorf("at most %d shards are supported", rs.MaxTotalShards)
+	}
+	return dataShards, parityShards, nil
+}
@@ -0,0 +1,159 @@
+// Package dispersal implements secure information dispersal with the all-or-nothing
+// transform of AONT-RS: fewer than DataShards shards of an object reveal nothing about it.
+//
+// The object is encrypted with a random AES-256-GCM key, and the key is folded into the
+// package by XORing it with the SHA-256 hash of the ciphertext:
+//
+//	package = ciphertext | key XOR SHA-256(ciphertext) | 0x80 | zero padding
+//
+// The package is then split into data shards and erasure coded with an rs.Codec. The key,
+// and so any part of the object, can only be recovered with the whole ciphertext, i.e. with
+// at least DataShards shards; the GCM tag detects corrupted shards.
+package dispersal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"rs-encoder/rs"
+)
+
+// KeySize is the size in bytes of the random AES-256 key of an object
+const KeySize = 32
+
+// Overhead is the number of bytes a package adds to the object, before the padding
+// of at most DataShards bytes: the GCM tag and the folded key
+const Overhead = 16 + KeySize
+
+// ErrAuthentication is returned when the recovered package fails GCM authentication,
+// because shards are corrupted or belong to different objects
+var ErrAuthentication = errors.New("dispersal: package authentication failed")
+
+// Disperser disperses objects across the shards of an rs.Codec
+type Disperser struct {
+	codec rs.Codec
+}
+
+// NewDisperser creates a Disperser over codec; at least codec.DataShards() shards are
+// needed to recover an object
+func NewDisperser(codec rs.Codec) *Disperser {
+	return &Disperser{codec: codec}
+}
+
+// Codec returns the codec the shards are encoded with
+func (d *Disperser) Codec() rs.Codec {
+	return d.codec
+}
+
+// Disperse encrypts data into an AONT package and returns its DataShards data shards
+// followed by ParityShards parity shards
+func (d *Disperser) Disperse(data []byte) ([][]byte, error) {
+	pkg, err := seal(data, d.codec.DataShards())
+	if err != nil {
+		return nil, err
+	}
+
+	dataShards := d.codec.DataShards()
+	shards := rs.SplitShards(pkg, dataShards, dataShards+d.codec.ParityShards())
+	if err := d.codec.Encode(shards); err != nil {
+		return nil, fmt.Errorf("failed to encode package: %v", err)
+	}
+	return shards, nil
+}
+
+// Recover decodes the package from at least DataShards shards, missing shards being nil,
+// and returns the decrypted object. It returns ErrAuthentication if the shards are
+// corrupted.
+func (d *Disperser) Recover(shards [][]byte) ([]byte, error) {
+	dataShards := d.codec.DataShards()
+	if len(shards) != dataShards+d.codec.ParityShards() {
+		return nil, fmt.Errorf("expected %d shards, got %d", dataShards+d.codec.ParityShards(), len(shards))
+	}
+	present, size := 0, -1
+	for _, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if size >= 0 && len(shard) != size {
+			return nil, fmt.Errorf("shards have different sizes: %d and %d", size, len(shard))
+		}
+		size = len(shard)
+		present++
+	}
+	if present < dataShards {
+		return nil, fmt.Errorf("not enough shards: have %d, need %d", present, dataShards)
+	}
+
+	shards = append([][]byte(nil), shards...)
+	if err := d.codec.Decode(shards); err != nil {
+		return nil, fmt.Errorf("failed to decode package: %v", err)
+	}
+	return open(rs.JoinShards(shards, dataShards, size*dataShards))
+}
+
+// seal encrypts data with a random key into a package padded to a multiple of dataShards bytes
+func seal(data []byte, dataShards int) ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	defer clear(key)
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key encrypts a single object, so a fixed nonce is safe
+	length := len(data) + Overhead + 1
+	length += (dataShards - length%dataShards) % dataShards
+	pkg := make([]byte, 0, length)
+	pkg = aead.Seal(pkg, make([]byte, aead.NonceSize()), data, nil)
+
+	hash := sha256.Sum256(pkg)
+	for i := range key {
+		pkg = append(pkg, key[i]^hash[i])
+	}
+	pkg = append(pkg, 0x80)
+	return pkg[:length], nil
+}
+
+// open removes the padding of a package, unfolds the key and decrypts the object
+func open(pkg []byte) ([]byte, error) {
+	end := len(pkg)
+	for end > 0 && pkg[end-1] == 0 {
+		end--
+	}
+	if end == 0 || pkg[end-1] != 0x80 || end-1 < Overhead {
+		return nil, ErrAuthentication
+	}
+	ciphertext := pkg[:end-1-KeySize]
+	folded := pkg[end-1-KeySize : end-1]
+
+	hash := sha256.Sum256(ciphertext)
+	key := make([]byte, KeySize)
+	defer clear(key)
+	for i := range key {
+		key[i] = folded[i] ^ hash[i]
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext, nil)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return data, nil
+}
+
+// newAEAD creates AES-256-GCM with key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
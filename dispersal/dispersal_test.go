@@ -0,0 +1,148 @@
+package dispersal
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"strings"
+	"testing"
+)
+
+const (
+	testDataShards   = 4
+	testParityShards = 2
+)
+
+// newTestDisperser returns a 4+2 Disperser
+func newTestDisperser(t *testing.T) *Disperser {
+	t.Helper()
+	codec, err := rs.NewCodec("vandermonde", gf.NewGF(0x1d), testDataShards, testParityShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDisperser(codec)
+}
+
+// erasures returns every set of at most max shard indices out of total
+func erasures(total, max int) [][]int {
+	patterns := [][]int{nil}
+	for _, pattern := range patterns {
+		if len(pattern) == max {
+			continue
+		}
+		start := 0
+		if len(pattern) > 0 {
+			start = pattern[len(pattern)-1] + 1
+		}
+		for i := start; i < total; i++ {
+			patterns = append(patterns, append(append([]int(nil), pattern...), i))
+		}
+	}
+	return patterns
+}
+
+// without returns a copy of shards with the shards at lost set to nil
+func without(shards [][]byte, lost []int) [][]byte {
+	erased := append([][]byte(nil), shards...)
+	for _, i := range lost {
+		erased[i] = nil
+	}
+	return erased
+}
+
+func TestDisperseRecover(t *testing.T) {
+	d := newTestDisperser(t)
+	for _, size := range []int{0, 1, testDataShards, 10 * testDataShards, 1000} {
+		data := make([]byte, size)
+		rand.New(rand.NewSource(int64(size))).Read(data)
+
+		shards, err := d.Disperse(data)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if len(shards) != testDataShards+testParityShards {
+			t.Fatalf("%d bytes: %d shards", size, len(shards))
+		}
+		if want := (size + Overhead + 1 + testDataShards - 1) / testDataShards; len(shards[0]) != want {
+			t.Errorf("%d bytes: shards of %d bytes, want %d", size, len(shards[0]), want)
+		}
+		if size >= 16 {
+			for i, shard := range shards {
+				if bytes.Contains(shard, data[:16]) {
+					t.Errorf("%d bytes: shard %d holds the data in the clear", size, i)
+				}
+			}
+		}
+
+		// Up to ParityShards lost shards
+		for _, lost := range erasures(len(shards), testParityShards) {
+			got, err := d.Recover(without(shards, lost))
+			if err != nil {
+				t.Fatalf("%d bytes, shards %v lost: %v", size, lost, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("%d bytes, shards %v lost: recovered data differs", size, lost)
+			}
+		}
+	}
+}
+
+func TestRecoverNeedsDataShards(t *testing.T) {
+	d := newTestDisperser(t)
+	shards, err := d.Disperse([]byte("dispersed object"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lost := range erasures(len(shards), testParityShards+1) {
+		if len(lost) != testParityShards+1 {
+			continue
+		}
+		if _, err := d.Recover(without(shards, lost)); err == nil || !strings.Contains(err.Error(), "not enough shards: have 3, need 4") {
+			t.Errorf("shards %v lost: got error %v, want not enough shards", lost, err)
+		}
+	}
+	if _, err := d.Recover(shards[:5]); err == nil {
+		t.Error("Recover accepted 5 of 6 shards")
+	}
+}
+
+// A flipped byte in any shard the package is decoded from fails authentication
+func TestRecoverDetectsCorruption(t *testing.T) {
+	d := newTestDisperser(t)
+	shards, err := d.Disperse(bytes.Repeat([]byte("dispersed object "), 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range shards {
+		for _, pos := range []int{0, len(shards[i]) / 2, len(shards[i]) - 1} {
+			corrupted := append([][]byte(nil), shards...)
+			corrupted[i] = append([]byte(nil), shards[i]...)
+			corrupted[i][pos] ^= 1
+
+			// Lose ParityShards shards other than i, so that shard i is decoded from
+			var lost []int
+			for j := len(shards) - 1; j >= 0 && len(lost) < testParityShards; j-- {
+				if j != i {
+					lost = append(lost, j)
+				}
+			}
+			if _, err := d.Recover(without(corrupted, lost)); !errors.Is(err, ErrAuthentication) {
+				t.Errorf("shard %d, byte %d flipped: got error %v, want ErrAuthentication", i, pos, err)
+			}
+		}
+	}
+
+	// Shards of another object
+	other, err := d.Disperse(bytes.Repeat([]byte("dispersed object "), 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed := append([][]byte(nil), shards...)
+	mixed[0] = other[0]
+	if _, err := d.Recover(without(mixed, []int{4, 5})); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("shard of another object: got error %v, want ErrAuthentication", err)
+	}
+}
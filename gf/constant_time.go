@@ -0,0 +1,58 @@
+package gf
+
+// NewConstantTimeGF creates a GF(2^8) finite field whose Mul, Div, Inv and Pow run in
+// constant time: they use carry-less shift-and-reduce multiplication with masks instead
+// of the log/exp tables, with no branch or memory access depending on the operands.
+// Use it wherever the operands are secret, e.g. secret sharing coefficients; it gives
+// the same results as NewGF but is several times slower.
+//
+// The only operand-dependent branch is the division by zero check of Div and Inv,
+// which panic like their table-based counterparts.
+func NewConstantTimeGF(primitivePoly byte) *GF {
+	field := NewGF(primitivePoly)
+	field.constantTime = true
+	return field
+}
+
+// ConstantTime reports whether the field was created with NewConstantTimeGF
+func (f *GF) ConstantTime() bool {
+	return f.constantTime
+}
+
+// mask returns 0xff if a is non-zero and 0 otherwise, without branching
+func mask(a byte) byte {
+	// The top bit of a | -a is set exactly when a != 0
+	return byte(int8(a|-a) >> 7)
+}
+
+// mulConstantTime multiplies a and b by shifting a and adding it for every bit of b,
+// reducing with the primitive polynomial when the shift carries out of x^7
+func (f *GF) mulConstantTime(a, b byte) byte {
+	var result byte
+	for i := 0; i < 8; i++ {
+		result ^= -(b & 1) & a
+		b >>= 1
+		carry := -(a >> 7)
+		a = (a << 1) ^ (carry & f.primitivePoly)
+	}
+	return result
+}
+
+// powConstantTime calculates a^exponent for a public exponent by square-and-multiply;
+// the branches depend on the exponent only
+func (f *GF) powConstantTime(a byte, exponent int) byte {
+	result := byte(1)
+	for bit := 7; bit >= 0; bit-- {
+		result = f.mulConstantTime(result, result)
+		if exponent>>uint(bit)&1 != 0 {
+			result = f.mulConstantTime(result, a)
+		}
+	}
+	// 0 to any power is 0, like the table-based Pow
+	return result & mask(a)
+}
+
+// invConstantTime calculates a^254, the inverse of a non-zero a
+func (f *GF) invConstantTime(a byte) byte {
+	return f.powConstantTime(a, 254)
+}
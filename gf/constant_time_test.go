@@ -0,0 +1,91 @@
+package gf
+
+import "testing"
+
+// primitivePolys returns every primitive polynomial of GF(2^8), without the x^8 term
+func primitivePolys() []byte {
+	var polys []byte
+	for p := 1; p < 256; p++ {
+		if IsPrimitive(byte(p)) {
+			polys = append(polys, byte(p))
+		}
+	}
+	return polys
+}
+
+func TestConstantTimeMatchesTables(t *testing.T) {
+	polys := primitivePolys()
+	if len(polys) != 16 {
+		t.Fatalf("found %d primitive polynomials, expected 16", len(polys))
+	}
+
+	for _, poly := range polys {
+		table, constant := NewGF(poly), NewConstantTimeGF(poly)
+		if table.ConstantTime() || !constant.ConstantTime() {
+			t.Fatalf("poly 0x%02x: ConstantTime() does not report the mode", poly)
+		}
+
+		for a := 0; a < 256; a++ {
+			for b := 0; b < 256; b++ {
+				if got, want := constant.Mul(byte(a), byte(b)), table.Mul(byte(a), byte(b)); got != want {
+					t.Fatalf("poly 0x%02x: Mul(0x%02x, 0x%02x) = 0x%02x, want 0x%02x", poly, a, b, got, want)
+				}
+				if b == 0 {
+					continue
+				}
+				if got, want := constant.Div(byte(a), byte(b)), table.Div(byte(a), byte(b)); got != want {
+					t.Fatalf("poly 0x%02x: Div(0x%02x, 0x%02x) = 0x%02x, want 0x%02x", poly, a, b, got, want)
+				}
+			}
+
+			if a != 0 {
+				if got, want := constant.Inv(byte(a)), table.Inv(byte(a)); got != want {
+					t.Fatalf("poly 0x%02x: Inv(0x%02x) = 0x%02x, want 0x%02x", poly, a, got, want)
+				}
+			}
+			for power := -255; power <= 510; power++ {
+				if got, want := constant.Pow(byte(a), power), table.Pow(byte(a), power); got != want {
+					t.Fatalf("poly 0x%02x: Pow(0x%02x, %d) = 0x%02x, want 0x%02x", poly, a, power, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestConstantTimeMulAddSlice(t *testing.T) {
+	table, constant := NewGF(0x1d), NewConstantTimeGF(0x1d)
+	src := make([]byte, 256)
+	for i := range src {
+		src[i] = byte(i)
+	}
+	for c := 0; c < 256; c++ {
+		want, got := make([]byte, len(src)), make([]byte, len(src))
+		for i := range want {
+			want[i], got[i] = byte(255-i), byte(255-i)
+		}
+		table.MulAddSlice(want, src, byte(c))
+		constant.MulAddSlice(got, src, byte(c))
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("MulAddSlice with c=0x%02x: byte %d is 0x%02x, want 0x%02x", c, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestConstantTimeZeroDivisionPanics(t *testing.T) {
+	field := NewConstantTimeGF(0x1d)
+	for name, op := range map[string]func(){
+		"Div": func() { field.Div(1, 0) },
+		"Inv": func() { field.Inv(0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s by zero did not panic", name)
+				}
+			}()
+			op()
+		}()
+	}
+}
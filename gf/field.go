@@ -7,6 +7,8 @@ type GF struct {
 	logTable [256]byte
 	// Primitive polynomial
 	primitivePoly byte
+	// Use the constant-time operations instead of the tables
+	constantTime bool
 }
 
 // NewGF creates and initializes a GF(2^8) finite field
@@ -18,6 +20,29 @@ func NewGF(primitivePoly byte) *GF {
 	return field
 }
 
+// IsPrimitive reports whether x^8 + primitivePoly is a primitive polynomial,
+// i.e. whether x generates all 255 non-zero elements of the field it defines
+func IsPrimitive(primitivePoly byte) bool {
+	x := byte(1)
+	for i := 1; i <= 255; i++ {
+		// Multiply by x, reducing with the polynomial
+		if x&0x80 != 0 {
+			x = (x << 1) ^ primitivePoly
+		} else {
+			x = x << 1
+		}
+		if x == 1 {
+			return i == 255
+		}
+	}
+	return false
+}
+
+// PrimitivePoly returns the primitive polynomial (without the x^8 term) the field was created with
+func (f *GF) PrimitivePoly() byte {
+	return f.primitivePoly
+}
+
 // Add performs addition operation in GF(2^8) (XOR)
 func (f *GF) Add(a, b byte) byte {
 	return a ^ b
@@ -30,6 +55,9 @@ func (f *GF) Sub(a, b byte) byte {
 
 // Mul performs multiplication operation in GF(2^8)
 func (f *GF) Mul(a, b byte) byte {
+	if f.constantTime {
+		return f.mulConstantTime(a, b)
+	}
 	if a == 0 || b == 0 {
 		return 0
 	}
@@ -41,8 +69,37 @@ func (f *GF) Mul(a, b byte) byte {
 	return f.expTable[sum]
 }
 
+// MulAddSlice calculates dst[i] += c * src[i] for every i of dst, src must be at least as long.
+// The mode of the field is checked once per slice instead of once per byte.
+func (f *GF) MulAddSlice(dst, src []byte, c byte) {
+	src = src[:len(dst)]
+	if f.constantTime {
+		for i, s := range src {
+			dst[i] ^= f.mulConstantTime(c, s)
+		}
+		return
+	}
+	if c == 0 {
+		return
+	}
+	logC := int(f.logTable[c])
+	for i, s := range src {
+		if s == 0 {
+			continue
+		}
+		sum := logC + int(f.logTable[s])
+		if sum >= 255 {
+			sum -= 255
+		}
+		dst[i] ^= f.expTable[sum]
+	}
+}
+
 // Div performs division operation in GF(2^8)
 func (f *GF) Div(a, b byte) byte {
+	if f.constantTime && b != 0 {
+		return f.mulConstantTime(a, f.invConstantTime(b))
+	}
 	if a == 0 {
 		return 0
 	}
@@ -59,6 +116,13 @@ func (f *GF) Div(a, b byte) byte {
 
 // Pow calculates power operation in GF(2^8)
 func (f *GF) Pow(a byte, power int) byte {
+	if f.constantTime {
+		exponent := power % 255
+		if exponent < 0 {
+			exponent += 255
+		}
+		return f.powConstantTime(a, exponent)
+	}
 	if a == 0 {
 		return 0
 	}
@@ -79,6 +143,9 @@ func (f *GF) Inv(a byte) byte {
 	if a == 0 {
 		panic("0 has no multiplicative inverse")
 	}
+	if f.constantTime {
+		return f.invConstantTime(a)
+	}
 	// In GF(2^8), the inverse of a is a^254
 	return f.expTable[255-f.logTable[a]]
 }
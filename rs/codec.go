@@ -0,0 +1,193 @@
+package rs
+
+import (
+	"bytes"
+	"fmt"
+	"rs-encoder/gf"
+	"sort"
+	"sync"
+)
+
+// Codec is a systematic erasure code over GF(2^8) working on stripes of
+// DataShards()+ParityShards() equal length shards, data shards first.
+// Missing shards are nil.
+type Codec interface {
+	// Name returns the name the codec is registered under
+	Name() string
+	// DataShards returns the number of data shards of a stripe
+	DataShards() int
+	// ParityShards returns the number of parity shards of a stripe
+	ParityShards() int
+	// Encode calculates the parity shards from the data shards, allocating nil parity shards
+	Encode(shards [][]byte) error
+	// Decode recovers the missing data shards, missing parity shards are left nil
+	Decode(shards [][]byte) error
+	// Reconstruct recovers all missing shards
+	Reconstruct(shards [][]byte) error
+	// Verify reports whether the parity shards match the data shards; all shards must be present
+	Verify(shards [][]byte) (bool, error)
+}
+
+// CodecFactory creates a codec with the given field, shard counts and diagnostics options
+type CodecFactory func(field *gf.GF, dataShards, parityShards int, opts ...Option) (Codec, error)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]CodecFactory)
+)
+
+// RegisterCodec makes a codec available by name to NewCodec.
+// It panics if the name is registered twice or the factory is nil.
+func RegisterCodec(name string, factory CodecFactory) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if factory == nil {
+		panic("rs: RegisterCodec factory is nil")
+	}
+	if _, dup := codecs[name]; dup {
+		panic("rs: RegisterCodec called twice for codec " + name)
+	}
+	codecs[name] = factory
+}
+
+// NewCodec creates the codec registered under name. The codec is silent unless a
+// WithLogger or WithTrace option is given.
+func NewCodec(name string, field *gf.GF, dataShards, parityShards int, opts ...Option) (Codec, error) {
+	codecsMu.RLock()
+	factory, ok := codecs[name]
+	codecsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	if dataShards <= 0 || parityShards < 0 || dataShards+parityShards > MaxTotalShards {
+		return nil, fmt.Errorf("invalid shard counts %d+%d: at most %d shards are supported", dataShards, parityShards, MaxTotalShards)
+	}
+	return factory(field, dataShards, parityShards, opts...)
+}
+
+// CodecNames returns the names of the registered codecs, sorted
+func CodecNames() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EvaluationPoints returns the evaluation point of every shard index used by the
+// registered codecs: the consecutive integers 1, 2, 3, ...
+func EvaluationPoints(totalShards int) []byte {
+	points := make([]byte, totalShards)
+	for i := range points {
+		points[i] = byte(i + 1)
+	}
+	return points
+}
+
+// CheckInteroperable checks that two codecs produce the same codewords, so that shards
+// encoded by one can be decoded by the other. Codes are linear, so it is enough to
+// compare the parity of every unit data stripe.
+func CheckInteroperable(a, b Codec) error {
+	if a.DataShards() != b.DataShards() || a.ParityShards() != b.ParityShards() {
+		return fmt.Errorf("%s %d+%d and %s %d+%d have different layouts",
+			a.Name(), a.DataShards(), a.ParityShards(), b.Name(), b.DataShards(), b.ParityShards())
+	}
+
+	total := a.DataShards() + a.ParityShards()
+	for j := 0; j < a.DataShards(); j++ {
+		stripeA := make([][]byte, total)
+		stripeB := make([][]byte, total)
+		for i := 0; i < a.DataShards(); i++ {
+			stripeA[i] = []byte{0}
+			stripeB[i] = []byte{0}
+		}
+		stripeA[j][0] = 1
+		stripeB[j][0] = 1
+		if err := a.Encode(stripeA); err != nil {
+			return err
+		}
+		if err := b.Encode(stripeB); err != nil {
+			return err
+		}
+		for i := a.DataShards(); i < total; i++ {
+			if !bytes.Equal(stripeA[i], stripeB[i]) {
+				return fmt.Errorf("%s and %s produce different parity shard %d", a.Name(), b.Name(), i)
+			}
+		}
+	}
+	return nil
+}
+
+// checkStripe checks the number of shards and that the present shards have the same length.
+// It returns the shard length and the number of present shards.
+func checkStripe(c Codec, shards [][]byte) (int, int, error) {
+	if len(shards) != c.DataShards()+c.ParityShards() {
+		return 0, 0, fmt.Errorf("expected %d shards, got %d", c.DataShards()+c.ParityShards(), len(shards))
+	}
+
+	shardSize, present := -1, 0
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if shardSize < 0 {
+			shardSize = len(shard)
+		} else if len(shard) != shardSize {
+			return 0, 0, fmt.Errorf("shard %d has %d bytes, expected %d", i, len(shard), shardSize)
+		}
+		present++
+	}
+	return shardSize, present, nil
+}
+
+// checkData checks a stripe before encoding: all data shards must be present with the same length
+func checkData(c Codec, shards [][]byte) error {
+	if len(shards) != c.DataShards()+c.ParityShards() {
+		return fmt.Errorf("expected %d shards, got %d", c.DataShards()+c.ParityShards(), len(shards))
+	}
+	for i := 0; i < c.DataShards(); i++ {
+		if shards[i] == nil {
+			return fmt.Errorf("data shard %d is missing", i)
+		}
+		if len(shards[i]) != len(shards[0]) {
+			return fmt.Errorf("shard %d has %d bytes, expected %d", i, len(shards[i]), len(shards[0]))
+		}
+	}
+	return nil
+}
+
+// checkReconstruct checks a stripe before reconstruction: at least DataShards shards must be present
+func checkReconstruct(c Codec, shards [][]byte) error {
+	_, present, err := checkStripe(c, shards)
+	if err != nil {
+		return err
+	}
+	if present < c.DataShards() {
+		return fmt.Errorf("not enough shards: have %d, need %d", present, c.DataShards())
+	}
+	return nil
+}
+
+// verifyStripe re-encodes the data shards of a complete stripe and compares the parity shards
+func verifyStripe(c Codec, shards [][]byte) (bool, error) {
+	if _, present, err := checkStripe(c, shards); err != nil {
+		return false, err
+	} else if present < len(shards) {
+		return false, fmt.Errorf("all shards must be present to verify, have %d of %d", present, len(shards))
+	}
+
+	stripe := make([][]byte, len(shards))
+	copy(stripe, shards[:c.DataShards()])
+	if err := c.Encode(stripe); err != nil {
+		return false, err
+	}
+	for i := c.DataShards(); i < len(shards); i++ {
+		if !bytes.Equal(stripe[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
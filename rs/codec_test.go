@@ -0,0 +1,174 @@
+package rs
+
+import (
+	"reflect"
+	"rs-encoder/gf"
+	"strings"
+	"testing"
+)
+
+func TestCodecNames(t *testing.T) {
+	want := []string{CodecHorner, CodecLagrange, CodecVandermonde}
+	if names := CodecNames(); !reflect.DeepEqual(names, want) {
+		t.Errorf("CodecNames() = %v, want %v", names, want)
+	}
+	for _, name := range want {
+		codec, err := NewCodec(name, newTestField(), testDataShards, testParityShards)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if codec.Name() != name || codec.DataShards() != testDataShards || codec.ParityShards() != testParityShards {
+			t.Errorf("%s: got %s %d+%d", name, codec.Name(), codec.DataShards(), codec.ParityShards())
+		}
+	}
+}
+
+func TestNewCodecErrors(t *testing.T) {
+	tests := []struct {
+		name                     string
+		dataShards, parityShards int
+		err                      string
+	}{
+		{"reed-solomon", 4, 2, `unknown codec "reed-solomon"`},
+		{"", 4, 2, `unknown codec ""`},
+		{"Vandermonde", 4, 2, `unknown codec "Vandermonde"`},
+		{CodecVandermonde, 0, 2, "invalid shard counts 0+2"},
+		{CodecLagrange, 4, -1, "invalid shard counts 4+-1"},
+		{CodecVandermonde, 200, MaxTotalShards - 199, "invalid shard counts"},
+	}
+	for _, test := range tests {
+		codec, err := NewCodec(test.name, newTestField(), test.dataShards, test.parityShards)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("NewCodec(%q, %d, %d): got error %v, want %q", test.name, test.dataShards, test.parityShards, err, test.err)
+		}
+		if codec != nil {
+			t.Errorf("NewCodec(%q, %d, %d) returned a codec", test.name, test.dataShards, test.parityShards)
+		}
+	}
+}
+
+func TestRegisterCodecPanics(t *testing.T) {
+	for name, register := range map[string]func(){
+		"a registered name": func() { RegisterCodec(CodecVandermonde, newVandermondeCodec) },
+		"a nil factory":     func() { RegisterCodec("nil-codec", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterCodec with %s did not panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+	for _, name := range CodecNames() {
+		if name == "nil-codec" {
+			t.Error("the nil factory was registered")
+		}
+	}
+}
+
+// The Lagrange and Vandermonde codecs are the same code: each decodes the shards of the other
+func TestLagrangeVandermondeInteroperable(t *testing.T) {
+	tests := []struct {
+		dataShards, parityShards int
+	}{
+		{1, 1},
+		{1, 3},
+		{4, 0},
+		{4, 2},
+		{testDataShards, testParityShards},
+		{6, 12},
+		{20, 5},
+	}
+
+	field := newTestField()
+	for _, test := range tests {
+		vandermonde, err := NewCodec(CodecVandermonde, field, test.dataShards, test.parityShards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lagrange, err := NewCodec(CodecLagrange, field, test.dataShards, test.parityShards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckInteroperable(vandermonde, lagrange); err != nil {
+			t.Errorf("%d+%d: %v", test.dataShards, test.parityShards, err)
+		}
+
+		total := test.dataShards + test.parityShards
+		shards := randomStripe(int64(total), test.dataShards, total, 100)
+		other := append([][]byte(nil), shards...)
+		if err := vandermonde.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		if err := lagrange.Encode(other); err != nil {
+			t.Fatal(err)
+		}
+		if i := firstDifference(other, shards); i >= 0 {
+			t.Fatalf("%d+%d: shard %d differs between the codecs", test.dataShards, test.parityShards, i)
+		}
+
+		for _, lost := range erasurePatterns(total, min(test.parityShards, 3)) {
+			for _, codec := range []Codec{vandermonde, lagrange} {
+				stripe := erase(shards, lost)
+				if err := codec.Reconstruct(stripe); err != nil {
+					t.Fatalf("%d+%d, %s, shards %v lost: %v", test.dataShards, test.parityShards, codec.Name(), lost, err)
+				}
+				if i := firstDifference(stripe, shards); i >= 0 {
+					t.Fatalf("%d+%d, %s, shards %v lost: shard %d differs", test.dataShards, test.parityShards, codec.Name(), lost, i)
+				}
+				if ok, err := codec.Verify(stripe); !ok || err != nil {
+					t.Fatalf("%d+%d, %s: the reconstructed stripe does not verify (%v)", test.dataShards, test.parityShards, codec.Name(), err)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckInteroperableRejects(t *testing.T) {
+	field := newTestField()
+	newCodec := func(name string, dataShards, parityShards int) Codec {
+		codec, err := NewCodec(name, field, dataShards, parityShards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return codec
+	}
+	vandermonde := newCodec(CodecVandermonde, testDataShards, testParityShards)
+	lagrange := newCodec(CodecLagrange, testDataShards, testParityShards)
+	horner := newCodec(CodecHorner, testDataShards, testParityShards)
+	otherField, err := NewCodec(CodecVandermonde, gf.NewGF(0x2b), testDataShards, testParityShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		a, b Codec
+		err  string
+	}{
+		{"horner and vandermonde", horner, vandermonde, "horner and vandermonde produce different parity shard"},
+		{"lagrange and horner", lagrange, horner, "lagrange and horner produce different parity shard"},
+		{"more parity", vandermonde, newCodec(CodecVandermonde, testDataShards, testParityShards+1), "have different layouts"},
+		{"fewer data shards", lagrange, newCodec(CodecVandermonde, testDataShards-1, testParityShards+1), "have different layouts"},
+		{"other field", vandermonde, otherField, "produce different parity shard"},
+	}
+	for _, test := range tests {
+		if err := CheckInteroperable(test.a, test.b); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+
+	// Horner shards do not pass the Verify of the Vandermonde codec
+	shards := randomStripe(1, testDataShards, testDataShards+testParityShards, 64)
+	if err := horner.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := horner.Verify(shards); !ok || err != nil {
+		t.Fatalf("horner does not verify its own stripe (%v)", err)
+	}
+	if ok, err := vandermonde.Verify(shards); ok || err != nil {
+		t.Errorf("vandermonde verifies a horner stripe: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,229 @@
+package rs
+
+import (
+	"rs-encoder/gf"
+)
+
+// Names of the built-in codecs
+const (
+	CodecVandermonde = "vandermonde"
+	CodecLagrange    = "lagrange"
+	CodecHorner      = "horner"
+)
+
+func init() {
+	RegisterCodec(CodecVandermonde, newVandermondeCodec)
+	RegisterCodec(CodecLagrange, newLagrangeCodec)
+	RegisterCodec(CodecHorner, newHornerCodec)
+}
+
+// vandermondeCodec is the Codec of RSEncoder and VandermondeDecoder
+type vandermondeCodec struct {
+	encoder *RSEncoder
+	decoder *VandermondeDecoder
+}
+
+func newVandermondeCodec(field *gf.GF, dataShards, parityShards int, opts ...Option) (Codec, error) {
+	return &vandermondeCodec{
+		encoder: NewRSEncoder(field, dataShards, parityShards, opts...),
+		decoder: NewVandermondeDecoder(field, dataShards, dataShards+parityShards, opts...),
+	}, nil
+}
+
+func (c *vandermondeCodec) Name() string      { return CodecVandermonde }
+func (c *vandermondeCodec) DataShards() int   { return c.encoder.dataShards }
+func (c *vandermondeCodec) ParityShards() int { return c.encoder.parityShards }
+
+func (c *vandermondeCodec) Encode(shards [][]byte) error {
+	if err := checkData(c, shards); err != nil {
+		return err
+	}
+	c.encoder.EncodeShards(shards)
+	return nil
+}
+
+func (c *vandermondeCodec) Decode(shards [][]byte) error {
+	if err := checkReconstruct(c, shards); err != nil {
+		return err
+	}
+	c.decoder.reconstructShards(shards, c.encoder.dataShards)
+	return nil
+}
+
+func (c *vandermondeCodec) Reconstruct(shards [][]byte) error {
+	if err := checkReconstruct(c, shards); err != nil {
+		return err
+	}
+	c.decoder.reconstructShards(shards, c.encoder.totalShards)
+	return nil
+}
+
+func (c *vandermondeCodec) Verify(shards [][]byte) (bool, error) {
+	return verifyStripe(c, shards)
+}
+
+// hornerCodec is the Codec of EncodeShardsHorner and ReconstructShardsHorner. It is a
+// different code from the Vandermonde and Lagrange codecs and is not MDS.
+type hornerCodec struct {
+	vandermondeCodec
+}
+
+func newHornerCodec(field *gf.GF, dataShards, parityShards int, opts ...Option) (Codec, error) {
+	codec, _ := newVandermondeCodec(field, dataShards, parityShards, opts...)
+	return &hornerCodec{*codec.(*vandermondeCodec)}, nil
+}
+
+func (c *hornerCodec) Name() string { return CodecHorner }
+
+func (c *hornerCodec) Encode(shards [][]byte) error {
+	if err := checkData(c, shards); err != nil {
+		return err
+	}
+	c.encoder.EncodeShardsHorner(shards)
+	return nil
+}
+
+func (c *hornerCodec) Decode(shards [][]byte) error {
+	if err := checkReconstruct(c, shards); err != nil {
+		return err
+	}
+	return c.decoder.reconstructShardsHorner(shards, c.encoder.dataShards)
+}
+
+func (c *hornerCodec) Reconstruct(shards [][]byte) error {
+	if err := checkReconstruct(c, shards); err != nil {
+		return err
+	}
+	return c.decoder.reconstructShardsHorner(shards, c.encoder.totalShards)
+}
+
+func (c *hornerCodec) Verify(shards [][]byte) (bool, error) {
+	return verifyStripe(c, shards)
+}
+
+// lagrangeCodec is the Codec of RSEncoder2 and RSDecoder. Their per-byte interpolation is
+// linear, so the coefficient matrices are obtained once by encoding and decoding unit vectors
+// and then applied to whole shards.
+type lagrangeCodec struct {
+	field        *gf.GF
+	encoder      *RSEncoder2
+	decoder      *RSDecoder
+	parityMatrix [][]byte // parity[i] = sum(parityMatrix[i][j] * data[j])
+}
+
+func newLagrangeCodec(field *gf.GF, dataShards, parityShards int, opts ...Option) (Codec, error) {
+	c := &lagrangeCodec{
+		field:   field,
+		encoder: NewRSEncoder2(field, dataShards, parityShards),
+		decoder: NewRSDecoder(field, dataShards, dataShards+parityShards, opts...),
+	}
+
+	c.parityMatrix = make([][]byte, parityShards)
+	for i := range c.parityMatrix {
+		c.parityMatrix[i] = make([]byte, dataShards)
+	}
+	for j := 0; j < dataShards; j++ {
+		unit := make([]byte, dataShards)
+		unit[j] = 1
+		encoded := c.encoder.Encode(unit)
+		for i := range c.parityMatrix {
+			c.parityMatrix[i][j] = encoded[dataShards+i]
+		}
+	}
+	return c, nil
+}
+
+func (c *lagrangeCodec) Name() string      { return CodecLagrange }
+func (c *lagrangeCodec) DataShards() int   { return c.encoder.dataShards }
+func (c *lagrangeCodec) ParityShards() int { return c.encoder.parityShards }
+
+func (c *lagrangeCodec) Encode(shards [][]byte) error {
+	if err := checkData(c, shards); err != nil {
+		return err
+	}
+	c.encodeParity(shards, c.encoder.totalShards, true)
+	return nil
+}
+
+// encodeParity calculates the parity shards among the first limit shards,
+// only the missing ones unless all is set
+func (c *lagrangeCodec) encodeParity(shards [][]byte, limit int, all bool) {
+	shardSize := len(shards[0])
+	for i := c.encoder.dataShards; i < limit; i++ {
+		if shards[i] != nil && !all {
+			continue
+		}
+		parity := shards[i]
+		if len(parity) != shardSize {
+			parity = make([]byte, shardSize)
+			shards[i] = parity
+		} else {
+			for b := range parity {
+				parity[b] = 0
+			}
+		}
+		for j, coefficient := range c.parityMatrix[i-c.encoder.dataShards] {
+			mulAddSlice(c.field, parity, shards[j], coefficient)
+		}
+	}
+}
+
+func (c *lagrangeCodec) Decode(shards [][]byte) error {
+	return c.reconstruct(shards, c.encoder.dataShards)
+}
+
+func (c *lagrangeCodec) Reconstruct(shards [][]byte) error {
+	return c.reconstruct(shards, c.encoder.totalShards)
+}
+
+// reconstruct recovers the missing shards among the first limit shards
+func (c *lagrangeCodec) reconstruct(shards [][]byte, limit int) error {
+	if err := checkReconstruct(c, shards); err != nil {
+		return err
+	}
+
+	// Use the first dataShards available shards
+	dataShards := c.encoder.dataShards
+	indices := make([]int, 0, dataShards)
+	shardSize := 0
+	for i, shard := range shards {
+		if shard != nil && len(indices) < dataShards {
+			indices = append(indices, i)
+			shardSize = len(shard)
+		}
+	}
+
+	var missing []int
+	for i := 0; i < dataShards; i++ {
+		if shards[i] == nil {
+			missing = append(missing, i)
+		}
+	}
+
+	// Column j holds the data recovered when available shard j is 1 and the others 0
+	recovered := make([][]byte, len(missing))
+	for m := range recovered {
+		recovered[m] = make([]byte, shardSize)
+	}
+	for j, index := range indices {
+		if len(missing) == 0 {
+			break
+		}
+		unit := make([]byte, dataShards)
+		unit[j] = 1
+		column := c.decoder.Decode(unit, indices)
+		for m, i := range missing {
+			mulAddSlice(c.field, recovered[m], shards[index], column[i])
+		}
+	}
+	for m, i := range missing {
+		shards[i] = recovered[m]
+	}
+
+	c.encodeParity(shards, limit, false)
+	return nil
+}
+
+func (c *lagrangeCodec) Verify(shards [][]byte) (bool, error) {
+	return verifyStripe(c, shards)
+}
@@ -0,0 +1,123 @@
+package rs
+
+import (
+	"context"
+)
+
+// contextBlockSize is the number of shard columns EncodeContext and ReconstructContext
+// process between two checks of the context
+const contextBlockSize = 64 * 1024
+
+// Progress reports how far a long running operation got
+type Progress struct {
+	Stripes int64 // Number of stripes (or column blocks) completed
+	Bytes   int64 // Number of data bytes processed
+}
+
+// ProgressFunc receives the progress after every completed stripe. It is called from the
+// goroutine running the operation and should return quickly.
+type ProgressFunc func(Progress)
+
+// EncodeContext calculates the parity shards of a stripe like c.Encode, in blocks of
+// columns, checking ctx between blocks. progress may be nil. When ctx is done, ctx.Err()
+// is returned and the parity shards are only valid for the columns of the blocks reported
+// by the last progress call.
+func EncodeContext(ctx context.Context, c Codec, shards [][]byte, progress ProgressFunc) error {
+	if err := checkData(c, shards); err != nil {
+		return err
+	}
+	dataShards := c.DataShards()
+	shardSize := len(shards[0])
+	for i := dataShards; i < len(shards); i++ {
+		if len(shards[i]) != shardSize {
+			shards[i] = make([]byte, shardSize)
+		}
+	}
+
+	block := make([][]byte, len(shards))
+	var p Progress
+	for c0 := 0; c0 < shardSize; c0 += contextBlockSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c1 := c0 + contextBlockSize
+		if c1 > shardSize {
+			c1 = shardSize
+		}
+
+		for i, shard := range shards {
+			block[i] = shard[c0:c1]
+		}
+		if err := c.Encode(block); err != nil {
+			return err
+		}
+		// Codecs may allocate new parity shards instead of filling the given ones
+		for i := dataShards; i < len(shards); i++ {
+			copy(shards[i][c0:c1], block[i])
+		}
+
+		p.Stripes++
+		p.Bytes += int64(dataShards * (c1 - c0))
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return nil
+}
+
+// ReconstructContext recovers all missing shards of a stripe like c.Reconstruct, in blocks
+// of columns, checking ctx between blocks. progress may be nil. When ctx is done, ctx.Err()
+// is returned and the missing shards are left nil, so the stripe is unchanged.
+func ReconstructContext(ctx context.Context, c Codec, shards [][]byte, progress ProgressFunc) error {
+	if err := checkReconstruct(c, shards); err != nil {
+		return err
+	}
+	shardSize, _, _ := checkStripe(c, shards)
+
+	var missing []int
+	for i, shard := range shards {
+		if shard == nil {
+			missing = append(missing, i)
+		}
+	}
+	recovered := make([][]byte, len(shards))
+	for _, i := range missing {
+		recovered[i] = make([]byte, shardSize)
+	}
+
+	block := make([][]byte, len(shards))
+	var p Progress
+	for c0 := 0; c0 < shardSize; c0 += contextBlockSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c1 := c0 + contextBlockSize
+		if c1 > shardSize {
+			c1 = shardSize
+		}
+
+		for i, shard := range shards {
+			block[i] = nil
+			if shard != nil {
+				block[i] = shard[c0:c1]
+			}
+		}
+		if err := c.Reconstruct(block); err != nil {
+			return err
+		}
+		for _, i := range missing {
+			copy(recovered[i][c0:c1], block[i])
+		}
+
+		p.Stripes++
+		p.Bytes += int64(c.DataShards() * (c1 - c0))
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	for _, i := range missing {
+		shards[i] = recovered[i]
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package rs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// cancelAfter returns a context and a ProgressFunc cancelling it after the given number of
+// stripes, recording the last progress reported
+func cancelAfter(stripes int64, last *Progress) (context.Context, ProgressFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	return ctx, func(p Progress) {
+		*last = p
+		if p.Stripes == stripes {
+			cancel()
+		}
+	}
+}
+
+// cancelled returns a context that is already cancelled
+func cancelled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+// streamBuffers returns a buffer and a writer per shard stream
+func streamBuffers() ([]bytes.Buffer, []io.Writer) {
+	bufs := make([]bytes.Buffer, testDataShards+testParityShards)
+	writers := make([]io.Writer, len(bufs))
+	for i := range bufs {
+		writers[i] = &bufs[i]
+	}
+	return bufs, writers
+}
+
+func TestStreamContextAlreadyCancelled(t *testing.T) {
+	s := newTestStreamEncoder(t)
+	size := 3*testDataShards*testStripeShardSize + 17
+	streams := encodeStream(t, s, randomBytes(3, size))
+	noProgress := func(p Progress) { t.Errorf("progress reported: %+v", p) }
+
+	bufs, writers := streamBuffers()
+	err := s.EncodeContext(cancelled(), bytes.NewReader(randomBytes(3, size)), writers[:testDataShards], writers[testDataShards:], noProgress)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("EncodeContext: got error %v, want context.Canceled", err)
+	}
+	for i := range bufs {
+		if bufs[i].Len() != 0 {
+			t.Errorf("EncodeContext wrote %d bytes to shard %d", bufs[i].Len(), i)
+		}
+	}
+
+	bufs, writers = streamBuffers()
+	if err := s.ReconstructContext(cancelled(), streamReaders(erase(streams, []int{0, 11})), writers, noProgress); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReconstructContext: got error %v, want context.Canceled", err)
+	}
+	for i := range bufs {
+		if bufs[i].Len() != 0 {
+			t.Errorf("ReconstructContext wrote %d bytes to shard %d", bufs[i].Len(), i)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := s.JoinContext(cancelled(), &out, streamReaders(streams[:testDataShards]), int64(size), noProgress); !errors.Is(err, context.Canceled) {
+		t.Errorf("JoinContext: got error %v, want context.Canceled", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("JoinContext wrote %d bytes", out.Len())
+	}
+}
+
+// A cancel between stripes leaves every shard stream with the same whole stripes, the
+// ones reported by the last progress call
+func TestStreamContextCancelledMidStream(t *testing.T) {
+	s := newTestStreamEncoder(t)
+	stripeSize := testDataShards * testStripeShardSize
+	size := 5*stripeSize + 17
+	data := randomBytes(4, size)
+	streams := encodeStream(t, s, data)
+	const stripes = 2
+
+	var last Progress
+	ctx, progress := cancelAfter(stripes, &last)
+	bufs, writers := streamBuffers()
+	if err := s.EncodeContext(ctx, bytes.NewReader(data), writers[:testDataShards], writers[testDataShards:], progress); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EncodeContext: got error %v, want context.Canceled", err)
+	}
+	if last.Stripes != stripes || last.Bytes != stripes*int64(stripeSize) {
+		t.Errorf("EncodeContext: last progress %+v, want %d stripes of %d bytes", last, stripes, stripeSize)
+	}
+	for i := range bufs {
+		if !bytes.Equal(bufs[i].Bytes(), streams[i][:stripes*testStripeShardSize]) {
+			t.Errorf("EncodeContext: shard stream %d holds %d bytes, not the first %d stripes", i, bufs[i].Len(), stripes)
+		}
+	}
+
+	ctx, progress = cancelAfter(stripes, &last)
+	bufs, writers = streamBuffers()
+	if err := s.ReconstructContext(ctx, streamReaders(erase(streams, []int{1, 4, 10, 13})), writers, progress); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ReconstructContext: got error %v, want context.Canceled", err)
+	}
+	if last.Stripes != stripes {
+		t.Errorf("ReconstructContext: last progress %+v, want %d stripes", last, stripes)
+	}
+	for i := range bufs {
+		if !bytes.Equal(bufs[i].Bytes(), streams[i][:stripes*testStripeShardSize]) {
+			t.Errorf("ReconstructContext: shard stream %d holds %d bytes, not the first %d stripes", i, bufs[i].Len(), stripes)
+		}
+	}
+
+	ctx, progress = cancelAfter(stripes, &last)
+	var out bytes.Buffer
+	if err := s.JoinContext(ctx, &out, streamReaders(streams[:testDataShards]), int64(size), progress); !errors.Is(err, context.Canceled) {
+		t.Fatalf("JoinContext: got error %v, want context.Canceled", err)
+	}
+	if int64(out.Len()) != last.Bytes || !bytes.Equal(out.Bytes(), data[:out.Len()]) {
+		t.Errorf("JoinContext wrote %d bytes, last progress %+v", out.Len(), last)
+	}
+
+	// Cancelled after the last stripe: the operation is complete
+	ctx, progress = cancelAfter(6, &last)
+	bufs, writers = streamBuffers()
+	if err := s.EncodeContext(ctx, bytes.NewReader(data), writers[:testDataShards], writers[testDataShards:], progress); err != nil {
+		t.Errorf("EncodeContext cancelled after the last stripe: %v", err)
+	}
+}
+
+func TestEncodeContext(t *testing.T) {
+	codec, err := NewCodec("vandermonde", newTestField(), testDataShards, testParityShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardSize := 2*contextBlockSize + 100
+	want := randomStripe(5, testDataShards, testDataShards+testParityShards, shardSize)
+	if err := codec.Encode(want); err != nil {
+		t.Fatal(err)
+	}
+
+	shards := append([][]byte(nil), want[:testDataShards]...)
+	shards = append(shards, make([][]byte, testParityShards)...)
+	var last Progress
+	if err := EncodeContext(context.Background(), codec, shards, func(p Progress) { last = p }); err != nil {
+		t.Fatal(err)
+	}
+	if i := firstDifference(shards, want); i >= 0 {
+		t.Fatalf("shard %d differs from Encode", i)
+	}
+	if last.Stripes != 3 || last.Bytes != int64(testDataShards*shardSize) {
+		t.Errorf("last progress %+v, want 3 blocks of the %d data bytes", last, testDataShards*shardSize)
+	}
+
+	// Already cancelled: the given parity shards are not written
+	for i := testDataShards; i < len(shards); i++ {
+		shards[i] = bytes.Repeat([]byte{0xee}, shardSize)
+	}
+	if err := EncodeContext(cancelled(), codec, shards, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	for i := testDataShards; i < len(shards); i++ {
+		if !bytes.Equal(shards[i], bytes.Repeat([]byte{0xee}, shardSize)) {
+			t.Errorf("parity shard %d was written", i)
+		}
+	}
+
+	// Cancelled after the first block: its columns are encoded, the others untouched
+	ctx, progress := cancelAfter(1, &last)
+	if err := EncodeContext(ctx, codec, shards, progress); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	for i := testDataShards; i < len(shards); i++ {
+		if !bytes.Equal(shards[i][:contextBlockSize], want[i][:contextBlockSize]) {
+			t.Errorf("parity shard %d: the first block is not encoded", i)
+		}
+		if !bytes.Equal(shards[i][contextBlockSize:], bytes.Repeat([]byte{0xee}, shardSize-contextBlockSize)) {
+			t.Errorf("parity shard %d: written past the first block", i)
+		}
+	}
+}
+
+func TestReconstructContext(t *testing.T) {
+	codec, err := NewCodec("vandermonde", newTestField(), testDataShards, testParityShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardSize := 2*contextBlockSize + 100
+	want := randomStripe(6, testDataShards, testDataShards+testParityShards, shardSize)
+	if err := codec.Encode(want); err != nil {
+		t.Fatal(err)
+	}
+	lost := []int{0, 5, 10, 13}
+
+	shards := erase(want, lost)
+	if err := ReconstructContext(context.Background(), codec, shards, nil); err != nil {
+		t.Fatal(err)
+	}
+	if i := firstDifference(shards, want); i >= 0 {
+		t.Fatalf("shard %d differs", i)
+	}
+
+	// Cancelled before or during the reconstruction: the stripe is unchanged
+	var last Progress
+	ctx, progress := cancelAfter(1, &last)
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"already cancelled", cancelled()},
+		{"cancelled after a block", ctx},
+	}
+	for _, test := range tests {
+		shards := erase(want, lost)
+		if err := ReconstructContext(test.ctx, codec, shards, progress); !errors.Is(err, context.Canceled) {
+			t.Fatalf("%s: got error %v, want context.Canceled", test.name, err)
+		}
+		for _, i := range lost {
+			if shards[i] != nil {
+				t.Errorf("%s: missing shard %d was set", test.name, i)
+			}
+		}
+	}
+	if last.Stripes != 1 {
+		t.Errorf("last progress %+v, want 1 block", last)
+	}
+}
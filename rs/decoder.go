@@ -1,7 +1,6 @@
 package rs
 
 import (
-	"fmt"
 	"rs-encoder/gf"
 )
 
@@ -11,14 +10,16 @@ type VandermondeDecoder struct {
 	dataShards  int    // Number of original data shards
 	totalShards int    // Total number of shards
 	alphaPoints []byte // Evaluation points, same as used by the encoder
+	tracer      tracer // Receives the diagnostics
 }
 
 // NewVandermondeDecoder Create a new Vandermonde Reed-Solomon decoder
-func NewVandermondeDecoder(field *gf.GF, dataShards, totalShards int) *VandermondeDecoder {
+func NewVandermondeDecoder(field *gf.GF, dataShards, totalShards int, opts ...Option) *VandermondeDecoder {
 	decoder := &VandermondeDecoder{
 		field:       field,
 		dataShards:  dataShards,
 		totalShards: totalShards,
+		tracer:      newTracer(opts),
 	}
 	decoder.generateAlphaPoints()
 	return decoder
@@ -34,27 +35,41 @@ func (dec *VandermondeDecoder) generateAlphaPoints() {
 	}
 
 	// Output evaluation points information
-	fmt.Println("Vandermonde decoder evaluation points:")
+	dec.tracer.printf("Vandermonde decoder evaluation points:")
 	for i, point := range dec.alphaPoints {
-		fmt.Printf("  Point[%d] = 0x%02x\n", i, point)
+		dec.tracer.printf("  Point[%d] = 0x%02x", i, point)
 	}
 }
 
+// AlphaPoints returns the evaluation point of every shard index
+func (dec *VandermondeDecoder) AlphaPoints() []byte {
+	return append([]byte(nil), dec.alphaPoints...)
+}
+
 // Decode Recover the original message from any dataShards shards
 // availableShards: Available shard data
 // availableIndices: Corresponding shard indices (0-based)
 func (dec *VandermondeDecoder) Decode(availableShards []byte, availableIndices []int) []byte {
+	// Create an array for the recovered original data
+	decodedData := make([]byte, dec.dataShards)
+	dec.DecodeInto(decodedData, availableShards, availableIndices)
+	return decodedData
+}
+
+// DecodeInto recovers the original message like Decode into the caller-owned dst of
+// dataShards bytes, without allocating
+func (dec *VandermondeDecoder) DecodeInto(dst []byte, availableShards []byte, availableIndices []int) {
 	if len(availableShards) < dec.dataShards || len(availableShards) != len(availableIndices) {
 		panic("Not enough shards to reconstruct data")
 	}
+	if len(dst) != dec.dataShards {
+		panic("Message length must equal the number of data shards")
+	}
 
 	// Only dataShards shards are needed to recover the original data
 	shards := availableShards[:dec.dataShards]
 	indices := availableIndices[:dec.dataShards]
 
-	// Create an array for the recovered original data
-	decodedData := make([]byte, dec.dataShards)
-
 	// Recover each original data position
 	for i := 0; i < dec.dataShards; i++ {
 		// Use Lagrange interpolation to calculate the value at the i-th original data position
@@ -94,11 +109,11 @@ func (dec *VandermondeDecoder) Decode(availableShards []byte, availableIndices [
 			result = dec.field.Add(result, term)
 		}
 
-		decodedData[i] = result
-		fmt.Printf("Vandermonde decoded data at position %d: 0x%02x\n", i, result)
+		dst[i] = result
+		if dec.tracer.enabled() {
+			dec.tracer.printf("Vandermonde decoded data at position %d: 0x%02x", i, result)
+		}
 	}
-
-	return decodedData
 }
 
 // DecodeLastShards Recover the original message from the last dataShards shards of the encoded result
@@ -0,0 +1,133 @@
+package rs
+
+import (
+	"bytes"
+	"testing"
+)
+
+// lastShards returns the last dataShards values of a codeword and their indices
+func lastShards(codeword []byte, dataShards int) ([]byte, []int) {
+	indices := make([]int, dataShards)
+	for j := range indices {
+		indices[j] = len(codeword) - dataShards + j
+	}
+	return codeword[len(codeword)-dataShards:], indices
+}
+
+func TestDecodeIntoRecoversMessage(t *testing.T) {
+	field := newTestField()
+	encoder := NewRSEncoder(field, testDataShards, testParityShards)
+	decoder := NewVandermondeDecoder(field, testDataShards, testDataShards+testParityShards)
+	dst := make([]byte, testDataShards)
+	for seed := int64(0); seed < 100; seed++ {
+		message := randomBytes(seed, testDataShards)
+		available, indices := lastShards(encoder.Encode(message), testDataShards)
+		decoder.DecodeInto(dst, available, indices)
+		if !bytes.Equal(dst, message) {
+			t.Fatalf("seed %d: DecodeInto = %x, want %x", seed, dst, message)
+		}
+		if decoded := decoder.Decode(available, indices); !bytes.Equal(decoded, dst) {
+			t.Fatalf("seed %d: Decode = %x, DecodeInto = %x", seed, decoded, dst)
+		}
+	}
+}
+
+func TestDecodeIntoDoesNotAllocate(t *testing.T) {
+	field := newTestField()
+	encoder := NewRSEncoder(field, testDataShards, testParityShards)
+	decoder := NewVandermondeDecoder(field, testDataShards, testDataShards+testParityShards)
+	available, indices := lastShards(encoder.Encode(randomBytes(1, testDataShards)), testDataShards)
+	dst := make([]byte, testDataShards)
+	if allocs := testing.AllocsPerRun(100, func() { decoder.DecodeInto(dst, available, indices) }); allocs != 0 {
+		t.Errorf("DecodeInto: %v allocs/op, want 0", allocs)
+	}
+}
+
+// reconstructFixture is an encoded stripe with its first lost shards to recover with ReconstructInto
+type reconstructFixture struct {
+	decoder *VandermondeDecoder
+	shards  [][]byte // The encoded stripe
+	buffers [][]byte // Caller-owned buffers, the lost ones to be overwritten
+	present []bool
+}
+
+func newReconstructFixture(lost int) *reconstructFixture {
+	field := newTestField()
+	totalShards := testDataShards + testParityShards
+	shards := randomStripe(1, testDataShards, totalShards, testShardSize)
+	NewRSEncoder(field, testDataShards, testParityShards).EncodeShards(shards)
+
+	f := &reconstructFixture{
+		decoder: NewVandermondeDecoder(field, testDataShards, totalShards),
+		shards:  shards,
+		buffers: make([][]byte, totalShards),
+		present: make([]bool, totalShards),
+	}
+	for i := range shards {
+		f.buffers[i] = append([]byte(nil), shards[i]...)
+		f.present[i] = i >= lost
+	}
+	return f
+}
+
+func TestReconstructInto(t *testing.T) {
+	for lost := 0; lost <= testParityShards; lost++ {
+		f := newReconstructFixture(lost)
+		for i := 0; i < lost; i++ {
+			for c := range f.buffers[i] {
+				f.buffers[i][c] = 0xff
+			}
+		}
+		f.decoder.ReconstructInto(f.buffers, f.present)
+		for i := range f.shards {
+			if !bytes.Equal(f.buffers[i], f.shards[i]) {
+				t.Errorf("%d lost shards: shard %d does not match", lost, i)
+			}
+		}
+	}
+}
+
+func TestReconstructIntoDoesNotAllocate(t *testing.T) {
+	f := newReconstructFixture(testParityShards)
+	if allocs := testing.AllocsPerRun(20, func() { f.decoder.ReconstructInto(f.buffers, f.present) }); allocs != 0 {
+		t.Errorf("ReconstructInto: %v allocs/op, want 0", allocs)
+	}
+}
+
+func BenchmarkDecodeInto(b *testing.B) {
+	field := newTestField()
+	encoder := NewRSEncoder(field, testDataShards, testParityShards)
+	decoder := NewVandermondeDecoder(field, testDataShards, testDataShards+testParityShards)
+	available, indices := lastShards(encoder.Encode(randomBytes(1, testDataShards)), testDataShards)
+	dst := make([]byte, testDataShards)
+	b.ReportAllocs()
+	b.SetBytes(testDataShards)
+	for i := 0; i < b.N; i++ {
+		decoder.DecodeInto(dst, available, indices)
+	}
+}
+
+func BenchmarkReconstructInto(b *testing.B) {
+	f := newReconstructFixture(testParityShards)
+	b.ReportAllocs()
+	b.SetBytes(testDataShards * testShardSize)
+	for i := 0; i < b.N; i++ {
+		f.decoder.ReconstructInto(f.buffers, f.present)
+	}
+}
+
+func BenchmarkReconstructShards(b *testing.B) {
+	f := newReconstructFixture(testParityShards)
+	shards := make([][]byte, len(f.shards))
+	b.ReportAllocs()
+	b.SetBytes(testDataShards * testShardSize)
+	for i := 0; i < b.N; i++ {
+		for j := range shards {
+			shards[j] = nil
+			if f.present[j] {
+				shards[j] = f.shards[j]
+			}
+		}
+		f.decoder.ReconstructShards(shards)
+	}
+}
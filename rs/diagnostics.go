@@ -0,0 +1,62 @@
+package rs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Option configures the diagnostics of an encoder, decoder or codec
+type Option func(*tracer)
+
+// WithLogger logs the evaluation points, matrices and per-position results of the
+// encoders and decoders to logger at debug level
+func WithLogger(logger *slog.Logger) Option {
+	return func(t *tracer) {
+		t.logger = logger
+	}
+}
+
+// WithTrace calls trace with every line of the evaluation points, matrices and
+// per-position results of the encoders and decoders
+func WithTrace(trace func(line string)) Option {
+	return func(t *tracer) {
+		t.trace = trace
+	}
+}
+
+// tracer receives the diagnostics of an encoder or decoder.
+// The zero value discards them, so the package is silent by default.
+type tracer struct {
+	logger *slog.Logger
+	trace  func(line string)
+}
+
+// newTracer applies the options
+func newTracer(opts []Option) tracer {
+	var t tracer
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return t
+}
+
+// enabled reports whether the diagnostics are consumed, so that the callers can skip
+// formatting them
+func (t tracer) enabled() bool {
+	return t.trace != nil || (t.logger != nil && t.logger.Enabled(context.Background(), slog.LevelDebug))
+}
+
+// printf formats a line of diagnostics
+func (t tracer) printf(format string, args ...interface{}) {
+	if !t.enabled() {
+		return
+	}
+	line := fmt.Sprintf(format, args...)
+	if t.trace != nil {
+		t.trace(line)
+	}
+	if t.logger != nil {
+		t.logger.Debug(line)
+	}
+}
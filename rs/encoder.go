@@ -0,0 +1,208 @@
+package rs
+
+import (
+	"fmt"
+	"rs-encoder/gf"
+	"strings"
+)
+
+// MaxTotalShards is the largest number of shards that can be given distinct non-zero
+// evaluation points in GF(2^8)
+const MaxTotalShards = 255
+
+// RSEncoder Reed-Solomon encoder
+type RSEncoder struct {
+	field             *gf.GF
+	dataShards        int
+	parityShards      int
+	totalShards       int
+	alphaPoints       []byte
+	vandermondeMatrix [][]byte
+	parityMatrix      [][]byte
+	tracer            tracer
+}
+
+// NewRSEncoder creates a new Reed-Solomon encoder
+func NewRSEncoder(field *gf.GF, dataShards, parityShards int, opts ...Option) *RSEncoder {
+	encoder := &RSEncoder{
+		field:        field,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		tracer:       newTracer(opts),
+	}
+	encoder.generateAlphaPoints()
+	encoder.generateVandermondeMatrix()
+	encoder.generateParityMatrix()
+	return encoder
+}
+
+// AlphaPoints returns the evaluation point of every shard index
+func (enc *RSEncoder) AlphaPoints() []byte {
+	return append([]byte(nil), enc.alphaPoints...)
+}
+
+// Encode encodes the message using Reed-Solomon encoding with Vandermonde matrix
+func (enc *RSEncoder) Encode(message []byte) []byte {
+	if len(message) != enc.dataShards {
+		panic("Message length must equal the number of data shards")
+	}
+
+	// Create encoded result array, first dataShards items are same as original message (systematic encoding)
+	encoded := make([]byte, enc.totalShards)
+	copy(encoded, message)
+
+	// Use the correctly implemented Vandermonde encoding method
+	enc.vandermondeEncode(message, encoded)
+
+	return encoded
+}
+
+// EncodeInto encodes the message src like Encode into the caller-owned codeword dst of
+// totalShards bytes, without allocating. The parity values are calculated with the
+// precomputed parity matrix and are not traced.
+func (enc *RSEncoder) EncodeInto(dst, src []byte) {
+	if len(src) != enc.dataShards {
+		panic("Message length must equal the number of data shards")
+	}
+	if len(dst) != enc.totalShards {
+		panic("Codeword length must equal the total number of shards")
+	}
+
+	copy(dst, src)
+	for i, row := range enc.parityMatrix {
+		result := byte(0)
+		for j, coefficient := range row {
+			result = enc.field.Add(result, enc.field.Mul(coefficient, src[j]))
+		}
+		dst[enc.dataShards+i] = result
+	}
+}
+
+// printVandermondeMatrix prints the Vandermonde matrix for debugging
+func (enc *RSEncoder) printVandermondeMatrix() {
+	if !enc.tracer.enabled() {
+		return
+	}
+	enc.tracer.printf("Vandermonde Matrix:")
+	for i := 0; i < enc.parityShards; i++ {
+		row := make([]string, enc.dataShards)
+		for j := range row {
+			row[j] = fmt.Sprintf("0x%02x", enc.vandermondeMatrix[i][j])
+		}
+		enc.tracer.printf("Row %d: [%s]", i, strings.Join(row, " "))
+	}
+}
+
+// generateAlphaPoints generates alpha evaluation points
+func (enc *RSEncoder) generateAlphaPoints() {
+	enc.alphaPoints = make([]byte, enc.totalShards)
+
+	// Use consecutive integers as evaluation points (1, 2, 3, ...)
+	for i := 0; i < enc.totalShards; i++ {
+		enc.alphaPoints[i] = byte(i + 1)
+	}
+
+	// Print evaluation points
+	enc.tracer.printf("Vandermonde evaluation points (alpha points):")
+	for i, point := range enc.alphaPoints {
+		enc.tracer.printf("  Point[%d] = 0x%02x", i, point)
+	}
+}
+
+// generateVandermondeMatrix generates the Vandermonde matrix for encoding
+func (enc *RSEncoder) generateVandermondeMatrix() {
+	// Create Vandermonde matrix (including parity rows)
+	enc.vandermondeMatrix = make([][]byte, enc.parityShards)
+
+	// Only need to calculate the matrix for parity data rows
+	for i := 0; i < enc.parityShards; i++ {
+		// Get evaluation point
+		x := enc.alphaPoints[i+enc.dataShards]
+
+		enc.vandermondeMatrix[i] = make([]byte, enc.dataShards)
+
+		// First element is x^0 = 1
+		enc.vandermondeMatrix[i][0] = 1
+
+		// For each subsequent column, calculate x^j
+		for j := 1; j < enc.dataShards; j++ {
+			// Calculate x^j
+			enc.vandermondeMatrix[i][j] = enc.field.Pow(x, j)
+		}
+	}
+}
+
+// vandermondeEncode uses Vandermonde matrix to calculate parity data
+func (enc *RSEncoder) vandermondeEncode(message []byte, encoded []byte) {
+	// For each parity position
+	for i := enc.dataShards; i < enc.totalShards; i++ {
+		// Calculate polynomial value at this point using Lagrange interpolation
+		result := enc.evaluateAt(message, enc.alphaPoints[i])
+
+		encoded[i] = result
+		enc.tracer.printf("Vandermonde encoding at position %d: 0x%02x", i, result)
+	}
+}
+
+// evaluateAt calculates the value at point x of the polynomial passing through the message
+// values at the data evaluation points, using Lagrange interpolation
+func (enc *RSEncoder) evaluateAt(message []byte, x byte) byte {
+	result := byte(0)
+
+	// Construct Lagrange interpolation polynomial
+	for j := 0; j < enc.dataShards; j++ {
+		// Get the message value
+		y_j := message[j]
+
+		// Skip if the value is 0 (optimization)
+		if y_j == 0 {
+			continue
+		}
+
+		// Calculate Lagrange basis L_j(x)
+		basis := byte(1)
+
+		for k := 0; k < enc.dataShards; k++ {
+			if j != k {
+				// Calculate (x - x_k)
+				numerator := enc.field.Sub(x, enc.alphaPoints[k])
+				// Calculate (x_j - x_k)
+				denominator := enc.field.Sub(enc.alphaPoints[j], enc.alphaPoints[k])
+				// Division
+				factor := enc.field.Div(numerator, denominator)
+				// Multiply by the current basis
+				basis = enc.field.Mul(basis, factor)
+			}
+		}
+
+		// Calculate this term's contribution: y_j * L_j(x)
+		term := enc.field.Mul(y_j, basis)
+
+		// Add to the result
+		result = enc.field.Add(result, term)
+	}
+
+	return result
+}
+
+// ExtendParity calculates count additional parity shards for an already encoded message.
+// The new shards are evaluated at shard indices fromIndex, fromIndex+1, ..., using the same
+// consecutive integer evaluation points as Encode, so they can be decoded together with the
+// original shards by a VandermondeDecoder created with totalShards >= fromIndex+count.
+func (enc *RSEncoder) ExtendParity(dataShards []byte, fromIndex, count int) []byte {
+	if len(dataShards) != enc.dataShards {
+		panic("Message length must equal the number of data shards")
+	}
+	if fromIndex < enc.dataShards || count < 0 || fromIndex+count > MaxTotalShards {
+		panic("Extended shard indices out of range")
+	}
+
+	parity := make([]byte, count)
+	for i := 0; i < count; i++ {
+		// Evaluation point of shard index n is n+1, same as generateAlphaPoints
+		parity[i] = enc.evaluateAt(dataShards, byte(fromIndex+i+1))
+	}
+
+	return parity
+}
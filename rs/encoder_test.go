@@ -0,0 +1,255 @@
+package rs
+
+import (
+	"bytes"
+	"math/rand"
+	"rs-encoder/gf"
+	"testing"
+)
+
+// Code parameters shared by the tests of the package
+const (
+	testDataShards   = 10
+	testParityShards = 4
+	testShardSize    = 4096
+)
+
+// newTestField returns the field of the tests, with the default primitive polynomial
+func newTestField() *gf.GF {
+	return gf.NewGF(0x1d)
+}
+
+// randomBytes returns n reproducible pseudo-random bytes
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// randomStripe returns a stripe of totalShards shards with random data shards and nil parity shards
+func randomStripe(seed int64, dataShards, totalShards, shardSize int) [][]byte {
+	shards := make([][]byte, totalShards)
+	for i := 0; i < dataShards; i++ {
+		shards[i] = randomBytes(seed+int64(i), shardSize)
+	}
+	return shards
+}
+
+// erasurePatterns returns every set of 1 to maxLost shard indices out of totalShards, smallest
+// sets first
+func erasurePatterns(totalShards, maxLost int) [][]int {
+	var patterns [][]int
+	var lost []int
+	var add func(start, size int)
+	add = func(start, size int) {
+		if len(lost) == size {
+			patterns = append(patterns, append([]int(nil), lost...))
+			return
+		}
+		for i := start; i < totalShards; i++ {
+			lost = append(lost, i)
+			add(i+1, size)
+			lost = lost[:len(lost)-1]
+		}
+	}
+	for size := 1; size <= maxLost; size++ {
+		add(0, size)
+	}
+	return patterns
+}
+
+// erase returns a copy of the stripe, sharing the shards, with the lost shards set to nil
+func erase(shards [][]byte, lost []int) [][]byte {
+	erased := append([][]byte(nil), shards...)
+	for _, index := range lost {
+		erased[index] = nil
+	}
+	return erased
+}
+
+// firstDifference returns the index of the first shard of got that differs from want, or -1
+func firstDifference(got, want [][]byte) int {
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestEncodeIntoMatchesEncode(t *testing.T) {
+	encoder := NewRSEncoder(newTestField(), testDataShards, testParityShards)
+	dst := make([]byte, testDataShards+testParityShards)
+	for seed := int64(0); seed < 100; seed++ {
+		message := randomBytes(seed, testDataShards)
+		encoder.EncodeInto(dst, message)
+		if want := encoder.Encode(message); !bytes.Equal(dst, want) {
+			t.Fatalf("seed %d: EncodeInto = %x, Encode = %x", seed, dst, want)
+		}
+	}
+}
+
+func TestEncodeIntoDoesNotAllocate(t *testing.T) {
+	encoder := NewRSEncoder(newTestField(), testDataShards, testParityShards)
+	message := randomBytes(1, testDataShards)
+	dst := make([]byte, testDataShards+testParityShards)
+	if allocs := testing.AllocsPerRun(100, func() { encoder.EncodeInto(dst, message) }); allocs != 0 {
+		t.Errorf("EncodeInto: %v allocs/op, want 0", allocs)
+	}
+}
+
+func TestExtendParity(t *testing.T) {
+	tests := []struct {
+		dataShards, parityShards int
+		fromIndex, count         int
+	}{
+		{1, 1, 2, 5},
+		{4, 2, 6, 3},
+		{4, 2, 8, 2}, // Leaving shards 6 and 7 out
+		{6, 12, 18, 4},
+		{10, 4, 14, 10},
+		{10, 4, 250, 5},
+	}
+
+	field := newTestField()
+	for _, test := range tests {
+		encoder := NewRSEncoder(field, test.dataShards, test.parityShards)
+		totalShards := test.fromIndex + test.count
+		extended := NewRSEncoder(field, test.dataShards, totalShards-test.dataShards)
+		decoder := NewVandermondeDecoder(field, test.dataShards, totalShards)
+
+		message := randomBytes(int64(totalShards), test.dataShards)
+		codeword := extended.Encode(message)
+		if original := encoder.Encode(message); !bytes.Equal(original, codeword[:len(original)]) {
+			t.Fatalf("%d+%d: the longer code does not extend the original codeword", test.dataShards, test.parityShards)
+		}
+		parity := encoder.ExtendParity(message, test.fromIndex, test.count)
+		if want := codeword[test.fromIndex:]; !bytes.Equal(parity, want) {
+			t.Fatalf("%d+%d, shards %d+%d: ExtendParity = %x, want %x", test.dataShards, test.parityShards, test.fromIndex, test.count, parity, want)
+		}
+
+		// The message decodes from any dataShards of the original and extended shards
+		var indices []int
+		for i := 0; i < test.dataShards+test.parityShards; i++ {
+			indices = append(indices, i)
+		}
+		for i := test.fromIndex; i < totalShards; i++ {
+			indices = append(indices, i)
+		}
+		maxLost := len(indices) - test.dataShards
+		if maxLost > 4 {
+			maxLost = 4
+		}
+		for _, lost := range erasurePatterns(len(indices), maxLost) {
+			isLost := make([]bool, len(indices))
+			for _, i := range lost {
+				isLost[i] = true
+			}
+			var values []byte
+			var used []int
+			for i, index := range indices {
+				if !isLost[i] && len(used) < test.dataShards {
+					values = append(values, codeword[index])
+					used = append(used, index)
+				}
+			}
+			if len(used) < test.dataShards {
+				continue
+			}
+			if decoded := decoder.Decode(values, used); !bytes.Equal(decoded, message) {
+				t.Fatalf("%d+%d, shards %v: decoded %x, want %x", test.dataShards, test.parityShards, used, decoded, message)
+			}
+		}
+	}
+}
+
+func TestExtendParityPanics(t *testing.T) {
+	encoder := NewRSEncoder(newTestField(), 4, 2)
+	message := randomBytes(1, 4)
+	for name, extend := range map[string]func(){
+		"index of a data shard": func() { encoder.ExtendParity(message, 3, 1) },
+		"beyond 255 shards":     func() { encoder.ExtendParity(message, 250, 6) },
+		"short message":         func() { encoder.ExtendParity(message[:3], 6, 1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ExtendParity with %s did not panic", name)
+				}
+			}()
+			extend()
+		}()
+	}
+}
+
+func TestShardPoolDoesNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	pool := NewShardPool()
+	stripe := make([]*[]byte, testDataShards+testParityShards)
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		buf := pool.Get(testShardSize)
+		pool.Put(buf)
+	}); allocs != 0 {
+		t.Errorf("ShardPool Get/Put: %v allocs/op, want 0", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		pool.GetStripe(stripe, testShardSize)
+		pool.PutStripe(stripe)
+	}); allocs != 0 {
+		t.Errorf("ShardPool GetStripe/PutStripe: %v allocs/op, want 0", allocs)
+	}
+}
+
+func TestShardPoolSizes(t *testing.T) {
+	pool := NewShardPool()
+	for _, size := range []int{0, 1, 100, testShardSize} {
+		buf := pool.Get(size)
+		if len(*buf) != size {
+			t.Errorf("Get(%d) returned %d bytes", size, len(*buf))
+		}
+		pool.Put(buf)
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	encoder := NewRSEncoder(newTestField(), testDataShards, testParityShards)
+	message := randomBytes(1, testDataShards)
+	b.ReportAllocs()
+	b.SetBytes(testDataShards)
+	for i := 0; i < b.N; i++ {
+		encoder.Encode(message)
+	}
+}
+
+func BenchmarkEncodeInto(b *testing.B) {
+	encoder := NewRSEncoder(newTestField(), testDataShards, testParityShards)
+	message := randomBytes(1, testDataShards)
+	dst := make([]byte, testDataShards+testParityShards)
+	b.ReportAllocs()
+	b.SetBytes(testDataShards)
+	for i := 0; i < b.N; i++ {
+		encoder.EncodeInto(dst, message)
+	}
+}
+
+func BenchmarkEncodeShards(b *testing.B) {
+	encoder := NewRSEncoder(newTestField(), testDataShards, testParityShards)
+	shards := randomStripe(1, testDataShards, testDataShards+testParityShards, testShardSize)
+	encoder.EncodeShards(shards)
+	b.ReportAllocs()
+	b.SetBytes(testDataShards * testShardSize)
+	for i := 0; i < b.N; i++ {
+		encoder.EncodeShards(shards)
+	}
+}
+
+func BenchmarkShardPool(b *testing.B) {
+	pool := NewShardPool()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pool.Put(pool.Get(testShardSize))
+	}
+}
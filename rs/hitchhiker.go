@@ -0,0 +1,234 @@
+package rs
+
+import (
+	"fmt"
+	"rs-encoder/gf"
+)
+
+// Hitchhiker piggybacked Reed-Solomon code (Hitchhiker-nonXOR).
+// Every shard is split into two substripes a and b of half the shard length, and each substripe
+// is encoded with the same RS code as RSEncoder. The data shards are split into parityShards-1
+// groups, and the b substripe of parity shard dataShards+1+g additionally carries the XOR of the
+// a substripes of group g. A lost data shard is then repaired from the b substripes of the other
+// data shards and the first parity, plus the a substripes of its own group only, instead of
+// reading dataShards whole shards.
+type Hitchhiker struct {
+	field        *gf.GF
+	dataShards   int
+	parityShards int
+	totalShards  int
+	encoder      *RSEncoder
+	decoder      *VandermondeDecoder
+	groups       [][]int // Data shard indices piggybacked on each parity after the first
+	groupOf      []int   // Piggyback group of every data shard
+}
+
+// NewHitchhiker creates a new Hitchhiker code, at least two parity shards are required
+func NewHitchhiker(field *gf.GF, dataShards, parityShards int, opts ...Option) *Hitchhiker {
+	if parityShards < 2 {
+		panic("Hitchhiker code needs at least two parity shards")
+	}
+
+	h := &Hitchhiker{
+		field:        field,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		encoder:      NewRSEncoder(field, dataShards, parityShards, opts...),
+		decoder:      NewVandermondeDecoder(field, dataShards, dataShards+parityShards, opts...),
+	}
+	h.generateGroups()
+	return h
+}
+
+// generateGroups splits the data shards into parityShards-1 groups of (almost) equal size
+func (h *Hitchhiker) generateGroups() {
+	groupCount := h.parityShards - 1
+	if groupCount > h.dataShards {
+		groupCount = h.dataShards
+	}
+	h.groups = make([][]int, groupCount)
+	h.groupOf = make([]int, h.dataShards)
+	for d := 0; d < h.dataShards; d++ {
+		g := d * groupCount / h.dataShards
+		h.groups[g] = append(h.groups[g], d)
+		h.groupOf[d] = g
+	}
+}
+
+// Encode calculates the parity shards of a stripe.
+// shards must hold totalShards entries, the first dataShards are the data and must have the same
+// even length; the parity entries are allocated if nil and overwritten.
+func (h *Hitchhiker) Encode(shards [][]byte) {
+	if len(shards) != h.totalShards {
+		panic("Number of shards must equal the total number of shards")
+	}
+	shardSize := len(shards[0])
+	if shardSize%2 != 0 {
+		panic("Shard length must be even")
+	}
+	for d := 0; d < h.dataShards; d++ {
+		if len(shards[d]) != shardSize {
+			panic("All data shards must have the same length")
+		}
+	}
+	for i := h.dataShards; i < h.totalShards; i++ {
+		if len(shards[i]) != shardSize {
+			shards[i] = make([]byte, shardSize)
+		}
+	}
+
+	// Encode both substripes with the plain RS code
+	a, b := h.substripes(shards)
+	h.encoder.EncodeShards(a)
+	h.encoder.EncodeShards(b)
+
+	// Piggyback the a substripes of every group on the b substripe of its parity
+	for g := range h.groups {
+		h.addPiggyback(a, b[h.dataShards+1+g], g)
+	}
+}
+
+// substripes returns the a (first half) and b (second half) views of the shards, nil shards stay nil
+func (h *Hitchhiker) substripes(shards [][]byte) ([][]byte, [][]byte) {
+	a := make([][]byte, len(shards))
+	b := make([][]byte, len(shards))
+	for i, shard := range shards {
+		if shard != nil {
+			half := len(shard) / 2
+			a[i] = shard[:half:half]
+			b[i] = shard[half:]
+		}
+	}
+	return a, b
+}
+
+// addPiggyback adds the XOR of the a substripes of group g to dst
+func (h *Hitchhiker) addPiggyback(a [][]byte, dst []byte, g int) {
+	for _, d := range h.groups[g] {
+		mulAddSlice(h.field, dst, a[d], 1)
+	}
+}
+
+// RepairData recovers the single lost data shard index in place and returns the number of bytes
+// read from the other shards. All other shards must be present.
+func (h *Hitchhiker) RepairData(shards [][]byte, index int) (int, error) {
+	if len(shards) != h.totalShards {
+		return 0, fmt.Errorf("expected %d shards, got %d", h.totalShards, len(shards))
+	}
+	if index < 0 || index >= h.dataShards {
+		return 0, fmt.Errorf("shard %d is not a data shard", index)
+	}
+	for i, shard := range shards {
+		if i != index && shard == nil {
+			return 0, fmt.Errorf("shard %d is also missing, use Reconstruct", i)
+		}
+	}
+
+	half := len(shards[(index+1)%h.totalShards]) / 2
+	a, b := h.substripes(shards)
+	read := 0
+
+	// Decode the b substripe of the lost shard from the other data shards and the first parity,
+	// whose b substripe carries no piggyback
+	bShards := make([][]byte, h.totalShards)
+	for d := 0; d < h.dataShards; d++ {
+		if d != index {
+			bShards[d] = b[d]
+			read += half
+		}
+	}
+	bShards[h.dataShards] = b[h.dataShards]
+	read += half
+	h.decoder.ReconstructShards(bShards)
+
+	// The piggybacked parity minus its plain b parity is the XOR of the group's a substripes
+	g := h.groupOf[index]
+	parity := h.dataShards + 1 + g
+	plain := make([][]byte, h.totalShards)
+	copy(plain, bShards[:h.dataShards])
+	h.encoder.EncodeShards(plain)
+
+	lostA := make([]byte, half)
+	copy(lostA, b[parity])
+	read += half
+	mulAddSlice(h.field, lostA, plain[parity], 1)
+
+	// Remove the a substripes of the other group members
+	for _, d := range h.groups[g] {
+		if d != index {
+			mulAddSlice(h.field, lostA, a[d], 1)
+			read += half
+		}
+	}
+
+	shard := make([]byte, 2*half)
+	copy(shard, lostA)
+	copy(shard[half:], bShards[index])
+	shards[index] = shard
+	return read, nil
+}
+
+// Reconstruct recovers any lost (nil) shards in place, up to parityShards lost shards
+func (h *Hitchhiker) Reconstruct(shards [][]byte) error {
+	if len(shards) != h.totalShards {
+		return fmt.Errorf("expected %d shards, got %d", h.totalShards, len(shards))
+	}
+
+	available := 0
+	shardSize := -1
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		available++
+		if shardSize < 0 {
+			shardSize = len(shard)
+		} else if len(shard) != shardSize {
+			return fmt.Errorf("shard %d has %d bytes, expected %d", i, len(shard), shardSize)
+		}
+	}
+	if available < h.dataShards {
+		return fmt.Errorf("not enough shards to reconstruct data: have %d, need %d", available, h.dataShards)
+	}
+	if available == h.totalShards {
+		return nil
+	}
+
+	half := shardSize / 2
+	a, b := h.substripes(shards)
+
+	// The a substripes form a plain RS codeword
+	fullA := make([][]byte, h.totalShards)
+	copy(fullA, a)
+	h.decoder.ReconstructShards(fullA)
+
+	// Strip the piggybacks to turn the b substripes into a plain RS codeword
+	plainB := make([][]byte, h.totalShards)
+	for i := range b {
+		if b[i] == nil {
+			continue
+		}
+		plainB[i] = make([]byte, half)
+		copy(plainB[i], b[i])
+		if g := i - h.dataShards - 1; g >= 0 && g < len(h.groups) {
+			h.addPiggyback(fullA, plainB[i], g)
+		}
+	}
+	h.decoder.ReconstructShards(plainB)
+
+	// Rebuild the lost shards, adding back the piggybacks
+	for i := range shards {
+		if shards[i] != nil {
+			continue
+		}
+		shard := make([]byte, 2*half)
+		copy(shard, fullA[i])
+		copy(shard[half:], plainB[i])
+		if g := i - h.dataShards - 1; g >= 0 && g < len(h.groups) {
+			h.addPiggyback(fullA, shard[half:], g)
+		}
+		shards[i] = shard
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package rs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHitchhikerRepairData(t *testing.T) {
+	const shardSize = 256
+	tests := []struct {
+		dataShards, parityShards int
+		index                    int
+		read                     int // Bytes read by RepairData, Decode reads dataShards*shardSize
+	}{
+		// One group: the piggyback saves nothing
+		{6, 2, 0, 6 * shardSize},
+		// Groups {0,1,2} and {3,4,5}
+		{6, 3, 0, 9 * shardSize / 2},
+		{6, 3, 5, 9 * shardSize / 2},
+		// Groups {0,1,2,3}, {4,5,6} and {7,8,9}: 30% and 35% less than RS
+		{10, 4, 0, 7 * shardSize},
+		{10, 4, 3, 7 * shardSize},
+		{10, 4, 4, 13 * shardSize / 2},
+		{10, 4, 9, 13 * shardSize / 2},
+	}
+
+	for _, test := range tests {
+		code := NewHitchhiker(newTestField(), test.dataShards, test.parityShards)
+		shards := randomStripe(int64(test.index), test.dataShards, test.dataShards+test.parityShards, shardSize)
+		code.Encode(shards)
+
+		erased := erase(shards, []int{test.index})
+		read, err := code.RepairData(erased, test.index)
+		if err != nil {
+			t.Fatalf("%d+%d, shard %d: %v", test.dataShards, test.parityShards, test.index, err)
+		}
+		if !bytes.Equal(erased[test.index], shards[test.index]) {
+			t.Errorf("%d+%d, shard %d: repaired shard differs", test.dataShards, test.parityShards, test.index)
+		}
+		if read != test.read {
+			t.Errorf("%d+%d, shard %d: read %d bytes, want %d", test.dataShards, test.parityShards, test.index, read, test.read)
+		}
+		if read > test.dataShards*shardSize {
+			t.Errorf("%d+%d, shard %d: read %d bytes, more than the %d of RS", test.dataShards, test.parityShards, test.index, read, test.dataShards*shardSize)
+		}
+	}
+}
+
+func TestHitchhikerRepairDataErrors(t *testing.T) {
+	code := NewHitchhiker(newTestField(), 4, 2)
+	shards := randomStripe(1, 4, 6, 16)
+	code.Encode(shards)
+
+	if _, err := code.RepairData(erase(shards, []int{4}), 4); err == nil {
+		t.Error("RepairData accepted parity shard 4")
+	}
+	if _, err := code.RepairData(erase(shards, []int{0, 1}), 0); err == nil {
+		t.Error("RepairData accepted a second missing shard")
+	}
+}
+
+func TestHitchhikerReconstruct(t *testing.T) {
+	const shardSize = 64
+	tests := []struct {
+		dataShards, parityShards int
+	}{
+		{4, 2},
+		{6, 3},
+		{10, 4},
+	}
+
+	for _, test := range tests {
+		code := NewHitchhiker(newTestField(), test.dataShards, test.parityShards)
+		totalShards := test.dataShards + test.parityShards
+		shards := randomStripe(int64(totalShards), test.dataShards, totalShards, shardSize)
+		code.Encode(shards)
+
+		// The a substripes are plain RS, the b substripes carry the piggybacks
+		half := shardSize / 2
+		a, b := make([][]byte, totalShards), make([][]byte, totalShards)
+		for i := 0; i < test.dataShards; i++ {
+			a[i], b[i] = shards[i][:half], shards[i][half:]
+		}
+		NewRSEncoder(newTestField(), test.dataShards, test.parityShards).EncodeShards(a)
+		NewRSEncoder(newTestField(), test.dataShards, test.parityShards).EncodeShards(b)
+		for i := test.dataShards; i < totalShards; i++ {
+			if !bytes.Equal(shards[i][:half], a[i]) {
+				t.Errorf("%d+%d: a substripe of parity %d is not the RS parity", test.dataShards, test.parityShards, i)
+			}
+			if piggybacked := !bytes.Equal(shards[i][half:], b[i]); piggybacked != (i > test.dataShards) {
+				t.Errorf("%d+%d: b substripe of parity %d piggybacked %v", test.dataShards, test.parityShards, i, piggybacked)
+			}
+		}
+
+		for _, lost := range erasurePatterns(totalShards, test.parityShards) {
+			erased := erase(shards, lost)
+			if err := code.Reconstruct(erased); err != nil {
+				t.Fatalf("%d+%d, lost %v: %v", test.dataShards, test.parityShards, lost, err)
+			}
+			if i := firstDifference(erased, shards); i >= 0 {
+				t.Fatalf("%d+%d, lost %v: shard %d is reconstructed wrong", test.dataShards, test.parityShards, lost, i)
+			}
+		}
+
+		tooMany := make([]int, test.parityShards+1)
+		for i := range tooMany {
+			tooMany[i] = i
+		}
+		if err := code.Reconstruct(erase(shards, tooMany)); err == nil {
+			t.Errorf("%d+%d: Reconstruct accepted %d lost shards", test.dataShards, test.parityShards, test.parityShards+1)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package rs
+
+import (
+	"fmt"
+)
+
+// EncodeHorner encodes the message with the Vandermonde matrix: the message values are the
+// coefficients of p(x) = message[0] + message[1]*x + ... and each parity shard is p evaluated at
+// its point with Horner's method, same as EncodeEfficient of the Lagrange implementation.
+// The data shards keep the message, so the result is a different code from Encode.
+func (enc *RSEncoder) EncodeHorner(message []byte) []byte {
+	if len(message) != enc.dataShards {
+		panic("Message length must equal the number of data shards")
+	}
+
+	encoded := make([]byte, enc.totalShards)
+	copy(encoded, message)
+
+	for i := enc.dataShards; i < enc.totalShards; i++ {
+		x := enc.alphaPoints[i]
+		result := byte(0)
+		for j := enc.dataShards - 1; j >= 0; j-- {
+			result = enc.field.Add(enc.field.Mul(result, x), message[j])
+		}
+		encoded[i] = result
+	}
+
+	return encoded
+}
+
+// EncodeShardsHorner calculates the parity shards of a stripe like EncodeShards,
+// using the Vandermonde matrix rows of EncodeHorner as coefficients
+func (enc *RSEncoder) EncodeShardsHorner(shards [][]byte) {
+	if len(shards) != enc.totalShards {
+		panic("Number of shards must equal the total number of shards")
+	}
+
+	shardSize := len(shards[0])
+	for i := 0; i < enc.dataShards; i++ {
+		if len(shards[i]) != shardSize {
+			panic("All data shards must have the same length")
+		}
+	}
+
+	for i := 0; i < enc.parityShards; i++ {
+		parity := make([]byte, shardSize)
+		for j := 0; j < enc.dataShards; j++ {
+			mulAddSlice(enc.field, parity, shards[j], enc.vandermondeMatrix[i][j])
+		}
+		shards[enc.dataShards+i] = parity
+	}
+}
+
+// hornerGenerator returns the generator row of shard index for the EncodeHorner code:
+// the unit vector for data shards and the powers of the evaluation point for parity shards
+func (dec *VandermondeDecoder) hornerGenerator(index int) []byte {
+	row := make([]byte, dec.dataShards)
+	if index < dec.dataShards {
+		row[index] = 1
+		return row
+	}
+	for j := range row {
+		row[j] = dec.field.Pow(dec.alphaPoints[index], j)
+	}
+	return row
+}
+
+// hornerInverse selects dataShards independent shards among the available ones and returns
+// them with the inverse of their generator rows. Unlike Encode, the EncodeHorner code is not
+// guaranteed to be MDS, so some combinations of dataShards shards cannot be decoded.
+func (dec *VandermondeDecoder) hornerInverse(availableIndices []int) ([]int, [][]byte, error) {
+	generator := make([][]byte, dec.totalShards)
+	for _, index := range availableIndices {
+		if index < 0 || index >= dec.totalShards {
+			return nil, nil, fmt.Errorf("shard index %d out of range", index)
+		}
+		generator[index] = dec.hornerGenerator(index)
+	}
+
+	selected := selectIndependentRows(dec.field, generator, availableIndices, dec.dataShards)
+	if len(selected) < dec.dataShards {
+		return nil, nil, fmt.Errorf("shards %v do not determine the message (rank %d, need %d)", availableIndices, len(selected), dec.dataShards)
+	}
+
+	matrix := make([][]byte, len(selected))
+	for i, index := range selected {
+		matrix[i] = generator[index]
+	}
+	inverse, err := invertMatrix(dec.field, matrix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return selected, inverse, nil
+}
+
+// DecodeHorner recovers the message encoded by EncodeHorner from the available shards
+func (dec *VandermondeDecoder) DecodeHorner(availableShards []byte, availableIndices []int) ([]byte, error) {
+	if len(availableShards) != len(availableIndices) {
+		return nil, fmt.Errorf("got %d shards but %d indices", len(availableShards), len(availableIndices))
+	}
+
+	selected, inverse, err := dec.hornerInverse(availableIndices)
+	if err != nil {
+		return nil, err
+	}
+
+	// Value of every selected shard
+	values := make(map[int]byte, len(availableIndices))
+	for i, index := range availableIndices {
+		values[index] = availableShards[i]
+	}
+
+	message := make([]byte, dec.dataShards)
+	for i := range message {
+		for j, index := range selected {
+			message[i] = dec.field.Add(message[i], dec.field.Mul(inverse[i][j], values[index]))
+		}
+	}
+	return message, nil
+}
+
+// ReconstructShardsHorner recovers the missing (nil) shards of a stripe encoded by EncodeShardsHorner
+func (dec *VandermondeDecoder) ReconstructShardsHorner(shards [][]byte) error {
+	return dec.reconstructShardsHorner(shards, dec.totalShards)
+}
+
+// reconstructShardsHorner recovers the missing shards among the first limit shards of a stripe
+func (dec *VandermondeDecoder) reconstructShardsHorner(shards [][]byte, limit int) error {
+	if len(shards) != dec.totalShards {
+		return fmt.Errorf("expected %d shards, got %d", dec.totalShards, len(shards))
+	}
+
+	var available []int
+	shardSize := -1
+	for i, shard := range shards {
+		if shard != nil {
+			available = append(available, i)
+			shardSize = len(shard)
+		}
+	}
+
+	selected, inverse, err := dec.hornerInverse(available)
+	if err != nil {
+		return err
+	}
+
+	// Recover the missing data shards, then recalculate the missing parity shards
+	for i := 0; i < dec.dataShards; i++ {
+		if shards[i] != nil {
+			continue
+		}
+		shard := make([]byte, shardSize)
+		for j, index := range selected {
+			mulAddSlice(dec.field, shard, shards[index], inverse[i][j])
+		}
+		shards[i] = shard
+	}
+	for i := dec.dataShards; i < limit; i++ {
+		if shards[i] != nil {
+			continue
+		}
+		shard := make([]byte, shardSize)
+		for j, coefficient := range dec.hornerGenerator(i) {
+			mulAddSlice(dec.field, shard, shards[j], coefficient)
+		}
+		shards[i] = shard
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package rs
 
 import (
-	"fmt"
 	"rs-encoder/gf"
 )
 
@@ -11,14 +10,16 @@ type RSDecoder struct {
 	dataShards  int    // Number of original data shards
 	totalShards int    // Total number of shards
 	evalPoints  []byte // Evaluation points, same as used by the encoder
+	tracer      tracer // Receives the diagnostics
 }
 
 // NewRSDecoder Create a new Reed-Solomon decoder
-func NewRSDecoder(field *gf.GF, dataShards, totalShards int) *RSDecoder {
+func NewRSDecoder(field *gf.GF, dataShards, totalShards int, opts ...Option) *RSDecoder {
 	decoder := &RSDecoder{
 		field:       field,
 		dataShards:  dataShards,
 		totalShards: totalShards,
+		tracer:      newTracer(opts),
 	}
 	decoder.generateEvalPoints()
 	return decoder
@@ -34,9 +35,9 @@ func (dec *RSDecoder) generateEvalPoints() {
 	}
 
 	// Output evaluation points information
-	fmt.Println("Reed-Solomon decoder evaluation points:")
+	dec.tracer.printf("Reed-Solomon decoder evaluation points:")
 	for i, point := range dec.evalPoints {
-		fmt.Printf("  Point[%d] = 0x%02x\n", i, point)
+		dec.tracer.printf("  Point[%d] = 0x%02x", i, point)
 	}
 }
 
@@ -95,7 +96,7 @@ func (dec *RSDecoder) Decode(availableShards []byte, availableIndices []int) []b
 		}
 
 		decodedData[i] = result
-		fmt.Printf("Decoded data at position %d: 0x%02x\n", i, result)
+		dec.tracer.printf("Decoded data at position %d: 0x%02x", i, result)
 	}
 
 	return decodedData
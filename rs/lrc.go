@@ -0,0 +1,323 @@
+package rs
+
+import (
+	"fmt"
+	"rs-encoder/gf"
+	"sort"
+)
+
+// LocalParityType selects how the parity of a local group is calculated
+type LocalParityType int
+
+const (
+	// LocalXOR uses the XOR of the group's data shards as local parity
+	LocalXOR LocalParityType = iota
+	// LocalRS uses a single Reed-Solomon parity shard of the group's data shards as local parity
+	LocalRS
+)
+
+// LRC Locally Repairable Code (Azure-style).
+// The dataShards data shards are split into localGroups groups, each protected by one local parity,
+// and globalShards global Reed-Solomon parities protect all data shards.
+// Shard indices are laid out as: data shards, then one local parity per group, then global parities.
+// The global parities are those of RSEncoder, whose coefficients are not chosen for maximal
+// recoverability, so a few patterns of globalShards+1 failures cannot be decoded.
+type LRC struct {
+	field        *gf.GF
+	dataShards   int
+	localGroups  int
+	globalShards int
+	totalShards  int
+	groups       [][]int    // Data shard indices of each local group
+	groupOf      []int      // Local group of every data and local parity shard
+	generator    [][]byte   // Coefficients of every shard as a combination of the data shards
+	global       *RSEncoder // Calculates the global parities
+}
+
+// RepairPlan describes which shards are read to reconstruct the lost shards
+type RepairPlan struct {
+	Lost  []int // Indices of the lost shards
+	Read  []int // Indices of the shards read for the repair
+	Local bool  // True if every lost shard is repaired from its local group
+}
+
+// NewLRC creates a new locally repairable code
+func NewLRC(field *gf.GF, dataShards, localGroups, globalShards int, localParity LocalParityType, opts ...Option) *LRC {
+	if localGroups <= 0 || localGroups > dataShards {
+		panic("Number of local groups must be between 1 and the number of data shards")
+	}
+	if dataShards+localGroups+globalShards > MaxTotalShards {
+		panic("Too many shards")
+	}
+
+	c := &LRC{
+		field:        field,
+		dataShards:   dataShards,
+		localGroups:  localGroups,
+		globalShards: globalShards,
+		totalShards:  dataShards + localGroups + globalShards,
+		global:       NewRSEncoder(field, dataShards, globalShards, opts...),
+	}
+	c.generateGroups()
+	c.generateGenerator(localParity)
+	return c
+}
+
+// TotalShards returns the number of data, local parity and global parity shards
+func (c *LRC) TotalShards() int {
+	return c.totalShards
+}
+
+// generateGroups splits the data shards into local groups of (almost) equal size
+func (c *LRC) generateGroups() {
+	c.groups = make([][]int, c.localGroups)
+	c.groupOf = make([]int, c.dataShards+c.localGroups)
+	start := 0
+	for g := 0; g < c.localGroups; g++ {
+		// The first dataShards % localGroups groups get one extra shard
+		size := c.dataShards / c.localGroups
+		if g < c.dataShards%c.localGroups {
+			size++
+		}
+		for d := start; d < start+size; d++ {
+			c.groups[g] = append(c.groups[g], d)
+			c.groupOf[d] = g
+		}
+		c.groupOf[c.dataShards+g] = g
+		start += size
+	}
+}
+
+// generateGenerator builds the generator matrix: one row per shard, expressing it in the data shards
+func (c *LRC) generateGenerator(localParity LocalParityType) {
+	c.generator = make([][]byte, c.totalShards)
+
+	// Data shards are the identity
+	for d := 0; d < c.dataShards; d++ {
+		c.generator[d] = make([]byte, c.dataShards)
+		c.generator[d][d] = 1
+	}
+
+	// Local parities only depend on the data shards of their group
+	for g, group := range c.groups {
+		row := make([]byte, c.dataShards)
+		if localParity == LocalRS {
+			// Single parity of an RS code over the group, evaluated at the point after the group's data points
+			points := make([]byte, len(group)+1)
+			for j := range points {
+				points[j] = byte(j + 1)
+			}
+			coefficients := lagrangeRow(c.field, points[:len(group)], points[len(group)])
+			for j, d := range group {
+				row[d] = coefficients[j]
+			}
+		} else {
+			for _, d := range group {
+				row[d] = 1
+			}
+		}
+		c.generator[c.dataShards+g] = row
+	}
+
+	// Global parities are the parities of the existing encoder
+	for i := 0; i < c.globalShards; i++ {
+		c.generator[c.dataShards+c.localGroups+i] = c.global.parityMatrix[i]
+	}
+}
+
+// Encode calculates the local and global parity shards.
+// shards must hold TotalShards entries, the first dataShards are the data,
+// the parity entries are allocated if nil and overwritten.
+func (c *LRC) Encode(shards [][]byte) {
+	if len(shards) != c.totalShards {
+		panic("Number of shards must equal the total number of shards")
+	}
+	shardSize := len(shards[0])
+	for d := 0; d < c.dataShards; d++ {
+		if len(shards[d]) != shardSize {
+			panic("All data shards must have the same length")
+		}
+	}
+
+	for i := c.dataShards; i < c.totalShards; i++ {
+		shards[i] = c.combine(shards, i, shardSize)
+	}
+}
+
+// combine calculates shard index from the data shards using its generator row
+func (c *LRC) combine(shards [][]byte, index, shardSize int) []byte {
+	shard := make([]byte, shardSize)
+	for d, coefficient := range c.generator[index] {
+		mulAddSlice(c.field, shard, shards[d], coefficient)
+	}
+	return shard
+}
+
+// PlanRepair decides which shards to read to reconstruct the lost shards.
+// Data and local parity shards are repaired from their local group when no other shard of
+// the group (or any global parity) is lost; otherwise dataShards independent shards are
+// read and the data is decoded globally.
+func (c *LRC) PlanRepair(lost []int) (*RepairPlan, error) {
+	isLost := make([]bool, c.totalShards)
+	for _, index := range lost {
+		if index < 0 || index >= c.totalShards {
+			return nil, fmt.Errorf("shard index %d out of range", index)
+		}
+		isLost[index] = true
+	}
+
+	plan := &RepairPlan{Lost: append([]int(nil), lost...)}
+	sort.Ints(plan.Lost)
+	if len(plan.Lost) == 0 {
+		plan.Local = true
+		return plan, nil
+	}
+
+	if read, ok := c.planLocal(isLost); ok {
+		plan.Read = read
+		plan.Local = true
+		return plan, nil
+	}
+
+	// Global decoding: prefer data shards, then local parities, then global parities
+	candidates := make([]int, 0, c.totalShards)
+	for i := 0; i < c.totalShards; i++ {
+		if !isLost[i] {
+			candidates = append(candidates, i)
+		}
+	}
+	plan.Read = selectIndependentRows(c.field, c.generator, candidates, c.dataShards)
+	if len(plan.Read) < c.dataShards {
+		return nil, fmt.Errorf("not enough independent shards to reconstruct data: have %d, need %d", len(plan.Read), c.dataShards)
+	}
+	return plan, nil
+}
+
+// planLocal returns the shards to read if every lost shard can be repaired within its local group
+func (c *LRC) planLocal(isLost []bool) ([]int, bool) {
+	lostPerGroup := make([]int, c.localGroups)
+	for i := 0; i < c.dataShards+c.localGroups; i++ {
+		if isLost[i] {
+			lostPerGroup[c.groupOf[i]]++
+		}
+	}
+	for i := c.dataShards + c.localGroups; i < c.totalShards; i++ {
+		if isLost[i] {
+			// Global parities depend on all data shards
+			return nil, false
+		}
+	}
+
+	var read []int
+	for g, group := range c.groups {
+		if lostPerGroup[g] == 0 {
+			continue
+		}
+		if lostPerGroup[g] > 1 {
+			return nil, false
+		}
+		// Read every surviving member of the group; a lost local parity only needs the data
+		for _, d := range group {
+			if !isLost[d] {
+				read = append(read, d)
+			}
+		}
+		if !isLost[c.dataShards+g] {
+			read = append(read, c.dataShards+g)
+		}
+	}
+	return read, true
+}
+
+// Reconstruct repairs the lost (nil) shards in place following PlanRepair and returns the plan used
+func (c *LRC) Reconstruct(shards [][]byte) (*RepairPlan, error) {
+	if len(shards) != c.totalShards {
+		return nil, fmt.Errorf("expected %d shards, got %d", c.totalShards, len(shards))
+	}
+
+	var lost []int
+	shardSize := -1
+	for i, shard := range shards {
+		if shard == nil {
+			lost = append(lost, i)
+		} else if shardSize < 0 {
+			shardSize = len(shard)
+		} else if len(shard) != shardSize {
+			return nil, fmt.Errorf("shard %d has %d bytes, expected %d", i, len(shard), shardSize)
+		}
+	}
+
+	plan, err := c.PlanRepair(lost)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.Local {
+		for _, index := range plan.Lost {
+			c.repairLocal(shards, index, shardSize)
+		}
+		return plan, nil
+	}
+
+	if err := c.repairGlobal(shards, plan, shardSize); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// repairLocal recovers one lost data or local parity shard from the rest of its group
+func (c *LRC) repairLocal(shards [][]byte, index, shardSize int) {
+	g := c.groupOf[index]
+	localIndex := c.dataShards + g
+	if index == localIndex {
+		shards[index] = c.combine(shards, index, shardSize)
+		return
+	}
+
+	// local = sum(coefficient_d * data_d), so the lost data shard is
+	// (local - sum of the other terms) / coefficient of the lost shard
+	row := c.generator[localIndex]
+	shard := make([]byte, shardSize)
+	copy(shard, shards[localIndex])
+	for _, d := range c.groups[g] {
+		if d != index {
+			mulAddSlice(c.field, shard, shards[d], row[d])
+		}
+	}
+	scale := c.field.Inv(row[index])
+	for i := range shard {
+		shard[i] = c.field.Mul(shard[i], scale)
+	}
+	shards[index] = shard
+}
+
+// repairGlobal decodes every data shard from the shards in the plan and recalculates the lost parities
+func (c *LRC) repairGlobal(shards [][]byte, plan *RepairPlan, shardSize int) error {
+	matrix := make([][]byte, len(plan.Read))
+	for i, index := range plan.Read {
+		matrix[i] = c.generator[index]
+	}
+	inverse, err := invertMatrix(c.field, matrix)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range plan.Lost {
+		if index >= c.dataShards {
+			continue
+		}
+		shard := make([]byte, shardSize)
+		for j, read := range plan.Read {
+			mulAddSlice(c.field, shard, shards[read], inverse[index][j])
+		}
+		shards[index] = shard
+	}
+
+	// All data shards are now available
+	for _, index := range plan.Lost {
+		if index >= c.dataShards {
+			shards[index] = c.combine(shards, index, shardSize)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package rs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLRCReconstruct(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		dataShards, localGroups, globalShards int
+		localParity                           LocalParityType
+		patterns                              int     // Erasure patterns of up to globalShards+1 shards
+		undecodable                           [][]int // The patterns the global parities of RSEncoder cannot decode
+	}{
+		{"6+2+2 xor", 6, 2, 2, LocalXOR, 175, nil},
+		{"6+2+2 rs", 6, 2, 2, LocalRS, 175, nil},
+		{"6+3+2 rs", 6, 3, 2, LocalRS, 231, [][]int{{0, 1, 10}}},
+		{"12+2+2 xor", 12, 2, 2, LocalXOR, 696, [][]int{{7, 8, 14}}},
+		{"12+2+2 rs", 12, 2, 2, LocalRS, 696, [][]int{{0, 2, 14}, {1, 2, 15}, {3, 4, 15}, {3, 5, 14}}},
+		{"12+3+3 xor", 12, 3, 3, LocalXOR, 4047, nil},
+		{"12+3+3 rs", 12, 3, 3, LocalRS, 4047, [][]int{{0, 1, 2, 3}, {0, 3, 5, 6}, {1, 2, 16, 17}, {5, 7, 16, 17}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code := NewLRC(newTestField(), test.dataShards, test.localGroups, test.globalShards, test.localParity)
+			shards := randomStripe(int64(test.dataShards), test.dataShards, code.TotalShards(), 64)
+			code.Encode(shards)
+
+			patterns := erasurePatterns(code.TotalShards(), test.globalShards+1)
+			if len(patterns) != test.patterns {
+				t.Fatalf("%d erasure patterns, want %d", len(patterns), test.patterns)
+			}
+			var undecodable [][]int
+			for _, lost := range patterns {
+				erased := erase(shards, lost)
+				if _, err := code.Reconstruct(erased); err != nil {
+					undecodable = append(undecodable, lost)
+					continue
+				}
+				if i := firstDifference(erased, shards); i >= 0 {
+					t.Fatalf("lost %v: shard %d is reconstructed wrong", lost, i)
+				}
+			}
+			if !reflect.DeepEqual(undecodable, test.undecodable) {
+				t.Errorf("undecodable patterns %v, want %v", undecodable, test.undecodable)
+			}
+		})
+	}
+}
+
+func TestLRCPlanRepair(t *testing.T) {
+	// 12 data shards in 3 groups of 4, local parities 12 to 14, global parities 15 to 17
+	code := NewLRC(newTestField(), 12, 3, 3, LocalXOR)
+	tests := []struct {
+		lost  []int
+		local bool
+		read  []int
+	}{
+		{[]int{5}, true, []int{4, 6, 7, 13}},
+		{[]int{13}, true, []int{4, 5, 6, 7}},
+		{[]int{0, 11}, true, []int{1, 2, 3, 12, 8, 9, 10, 14}},
+		{[]int{0, 1}, false, nil},
+		{[]int{16}, false, nil},
+	}
+
+	for _, test := range tests {
+		plan, err := code.PlanRepair(test.lost)
+		if err != nil {
+			t.Fatalf("lost %v: %v", test.lost, err)
+		}
+		if plan.Local != test.local {
+			t.Errorf("lost %v: local repair %v, want %v", test.lost, plan.Local, test.local)
+		}
+		if test.local && !reflect.DeepEqual(plan.Read, test.read) {
+			t.Errorf("lost %v: reads %v, want %v", test.lost, plan.Read, test.read)
+		}
+		if !test.local && len(plan.Read) != 12 {
+			t.Errorf("lost %v: reads %d shards, want 12", test.lost, len(plan.Read))
+		}
+	}
+
+	if _, err := code.PlanRepair([]int{18}); err == nil {
+		t.Error("PlanRepair accepted shard index 18 of 18 shards")
+	}
+}
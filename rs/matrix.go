@@ -0,0 +1,110 @@
+package rs
+
+import (
+	"errors"
+	"rs-encoder/gf"
+)
+
+// errSingularMatrix is returned when the selected shards do not determine the data
+var errSingularMatrix = errors.New("matrix is singular")
+
+// invertMatrix calculates the inverse of a square matrix over the field using Gauss-Jordan elimination
+func invertMatrix(field *gf.GF, matrix [][]byte) ([][]byte, error) {
+	n := len(matrix)
+
+	// Build the augmented matrix [matrix | identity]
+	work := make([][]byte, n)
+	for i := range matrix {
+		work[i] = make([]byte, 2*n)
+		copy(work[i], matrix[i])
+		work[i][n+i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		// Find a pivot row with a non-zero value in this column
+		pivot := -1
+		for row := col; row < n; row++ {
+			if work[row][col] != 0 {
+				pivot = row
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, errSingularMatrix
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+
+		// Scale the pivot row so the pivot becomes 1
+		scale := field.Inv(work[col][col])
+		for c := range work[col] {
+			work[col][c] = field.Mul(work[col][c], scale)
+		}
+
+		// Eliminate this column from every other row
+		for row := 0; row < n; row++ {
+			if row != col && work[row][col] != 0 {
+				mulAddSlice(field, work[row], work[col], work[row][col])
+			}
+		}
+	}
+
+	inverse := make([][]byte, n)
+	for i := range work {
+		inverse[i] = work[i][n:]
+	}
+	return inverse, nil
+}
+
+// selectIndependentRows picks, in order of preference, candidate rows of the generator matrix
+// until count linearly independent rows are found. It returns the selected candidates,
+// which may be fewer than count if the candidates do not span the data.
+func selectIndependentRows(field *gf.GF, generator [][]byte, candidates []int, count int) []int {
+	selected := make([]int, 0, count)
+	// Rows of the reduced basis and the column of their leading non-zero value
+	basis := make([][]byte, 0, count)
+	leads := make([]int, 0, count)
+
+	for _, candidate := range candidates {
+		if len(selected) == count {
+			break
+		}
+
+		// Reduce the candidate against the current basis
+		row := make([]byte, len(generator[candidate]))
+		copy(row, generator[candidate])
+		for b, lead := range leads {
+			if row[lead] != 0 {
+				mulAddSlice(field, row, basis[b], row[lead])
+			}
+		}
+
+		lead := -1
+		for c, value := range row {
+			if value != 0 {
+				lead = c
+				break
+			}
+		}
+		if lead < 0 {
+			// Linearly dependent on the selected rows
+			continue
+		}
+
+		// Normalise the new basis row and keep the basis reduced
+		scale := field.Inv(row[lead])
+		for c := range row {
+			row[c] = field.Mul(row[c], scale)
+		}
+		for b := range basis {
+			if basis[b][lead] != 0 {
+				mulAddSlice(field, basis[b], row, basis[b][lead])
+			}
+		}
+
+		basis = append(basis, row)
+		leads = append(leads, lead)
+		selected = append(selected, candidate)
+	}
+
+	return selected
+}
@@ -0,0 +1,359 @@
+package rs
+
+import (
+	"fmt"
+	"rs-encoder/gf"
+)
+
+// RepairStats reports the cost of repairing one lost shard
+type RepairStats struct {
+	Helpers          int   // Number of shards contacted
+	BytesRead        int64 // Bytes read from disk by the helpers
+	BytesTransferred int64 // Bytes sent over the network to the repairing node
+}
+
+// RSRepairStats returns the cost of repairing one shard of shardSize bytes with VandermondeDecoder,
+// which reads and transfers dataShards whole shards
+func RSRepairStats(dataShards int, shardSize int64) RepairStats {
+	return RepairStats{
+		Helpers:          dataShards,
+		BytesRead:        int64(dataShards) * shardSize,
+		BytesTransferred: int64(dataShards) * shardSize,
+	}
+}
+
+// ProductMatrixMSR minimum-storage regenerating code using the product-matrix construction
+// of Rashmi, Shah and Kumar, with d = 2k-2 helpers.
+// Every node stores alpha = k-1 symbols per stripe and a stripe holds k(k-1) data symbols, so the
+// storage overhead is the same as an RS code with k data shards. A lost node is repaired from any
+// d helpers, each sending a single symbol per stripe (1/alpha of its shard).
+// The code is not systematic: the data is only available by decoding from any k nodes.
+type ProductMatrixMSR struct {
+	field      *gf.GF
+	dataNodes  int      // k, number of nodes needed to decode
+	totalNodes int      // n, number of nodes
+	alpha      int      // Symbols stored per node and stripe, k-1
+	helpers    int      // d, number of helpers for a repair, 2k-2
+	psi        [][]byte // Encoding matrix, one Vandermonde row of length d per node
+	lambda     []byte   // Multiplier of the second message matrix per node, x^alpha
+}
+
+// NewProductMatrixMSR creates a product-matrix MSR code with dataNodes = k and totalNodes = n >= 2k-1
+func NewProductMatrixMSR(field *gf.GF, dataNodes, totalNodes int) *ProductMatrixMSR {
+	if dataNodes < 2 {
+		panic("Product-matrix MSR code needs at least two data nodes")
+	}
+	if totalNodes < 2*dataNodes-1 {
+		panic("Product-matrix MSR code needs at least 2k-1 nodes")
+	}
+
+	c := &ProductMatrixMSR{
+		field:      field,
+		dataNodes:  dataNodes,
+		totalNodes: totalNodes,
+		alpha:      dataNodes - 1,
+		helpers:    2*dataNodes - 2,
+	}
+	c.generateEncodingMatrix()
+	return c
+}
+
+// generateEncodingMatrix chooses evaluation points whose lambda = x^alpha values are distinct
+// and builds the Vandermonde encoding matrix
+func (c *ProductMatrixMSR) generateEncodingMatrix() {
+	c.psi = make([][]byte, 0, c.totalNodes)
+	c.lambda = make([]byte, 0, c.totalNodes)
+	used := make(map[byte]bool)
+
+	for x := 1; x <= MaxTotalShards && len(c.psi) < c.totalNodes; x++ {
+		lambda := c.field.Pow(byte(x), c.alpha)
+		if used[lambda] {
+			continue
+		}
+		used[lambda] = true
+
+		row := make([]byte, c.helpers)
+		for j := range row {
+			row[j] = c.field.Pow(byte(x), j)
+		}
+		c.psi = append(c.psi, row)
+		c.lambda = append(c.lambda, lambda)
+	}
+
+	if len(c.psi) < c.totalNodes {
+		panic("Too many nodes for the field")
+	}
+}
+
+// TotalNodes returns the number of nodes n
+func (c *ProductMatrixMSR) TotalNodes() int {
+	return c.totalNodes
+}
+
+// Helpers returns the number of helpers d contacted by Repair
+func (c *ProductMatrixMSR) Helpers() int {
+	return c.helpers
+}
+
+// StripeSize returns the number of data symbols per stripe, k(k-1)
+func (c *ProductMatrixMSR) StripeSize() int {
+	return c.dataNodes * c.alpha
+}
+
+// ShardSize returns the size of every node's shard for an object of size bytes
+func (c *ProductMatrixMSR) ShardSize(size int) int {
+	return c.chunkSize(size) * c.alpha
+}
+
+// chunkSize returns the number of stripes needed for an object of size bytes.
+// The stripes are stored column-wise: every symbol of the construction is a chunk of this many bytes.
+func (c *ProductMatrixMSR) chunkSize(size int) int {
+	stripe := c.StripeSize()
+	return (size + stripe - 1) / stripe
+}
+
+// Encode splits the data into stripes and returns the shard of every node
+func (c *ProductMatrixMSR) Encode(data []byte) [][]byte {
+	chunkSize := c.chunkSize(len(data))
+	padded := make([]byte, chunkSize*c.StripeSize())
+	copy(padded, data)
+
+	// Fill the symmetric message matrices S1 and S2, stacked as the d x alpha message matrix
+	message := make([][][]byte, c.helpers)
+	for r := range message {
+		message[r] = make([][]byte, c.alpha)
+	}
+	next := 0
+	for s := 0; s < 2; s++ {
+		for i := 0; i < c.alpha; i++ {
+			for j := i; j < c.alpha; j++ {
+				chunk := padded[next*chunkSize : (next+1)*chunkSize]
+				message[s*c.alpha+i][j] = chunk
+				message[s*c.alpha+j][i] = chunk
+				next++
+			}
+		}
+	}
+
+	// Node i stores psi_i^T * M
+	shards := make([][]byte, c.totalNodes)
+	for i := range shards {
+		shards[i] = make([]byte, c.alpha*chunkSize)
+		for a := 0; a < c.alpha; a++ {
+			symbol := shards[i][a*chunkSize : (a+1)*chunkSize]
+			for r := 0; r < c.helpers; r++ {
+				mulAddSlice(c.field, symbol, message[r][a], c.psi[i][r])
+			}
+		}
+	}
+	return shards
+}
+
+// Decode recovers the first size bytes of the object from any k shards (missing shards are nil)
+func (c *ProductMatrixMSR) Decode(shards [][]byte, size int) ([]byte, error) {
+	if len(shards) != c.totalNodes {
+		return nil, fmt.Errorf("expected %d shards, got %d", c.totalNodes, len(shards))
+	}
+	chunkSize := c.chunkSize(size)
+
+	// Use the first k available nodes
+	var nodes []int
+	for i, shard := range shards {
+		if shard == nil || len(nodes) == c.dataNodes {
+			continue
+		}
+		if len(shard) != c.alpha*chunkSize {
+			return nil, fmt.Errorf("shard %d has %d bytes, expected %d", i, len(shard), c.alpha*chunkSize)
+		}
+		nodes = append(nodes, i)
+	}
+	if len(nodes) < c.dataNodes {
+		return nil, fmt.Errorf("not enough shards to reconstruct data: have %d, need %d", len(nodes), c.dataNodes)
+	}
+
+	// X = C * Phi^T, where C holds the contents of the selected nodes
+	x := make([][][]byte, c.dataNodes)
+	for i, node := range nodes {
+		x[i] = make([][]byte, c.dataNodes)
+		for j, other := range nodes {
+			x[i][j] = make([]byte, chunkSize)
+			for a := 0; a < c.alpha; a++ {
+				mulAddSlice(c.field, x[i][j], c.symbol(shards[node], a, chunkSize), c.psi[other][a])
+			}
+		}
+	}
+
+	// X = P + Lambda * Q with P = Phi S1 Phi^T and Q = Phi S2 Phi^T symmetric,
+	// so the off-diagonal entries of P and Q follow from X_ij and X_ji
+	p := make([][][]byte, c.dataNodes)
+	q := make([][][]byte, c.dataNodes)
+	for i := range nodes {
+		p[i] = make([][]byte, c.dataNodes)
+		q[i] = make([][]byte, c.dataNodes)
+	}
+	for i := range nodes {
+		for j := i + 1; j < c.dataNodes; j++ {
+			li, lj := c.lambda[nodes[i]], c.lambda[nodes[j]]
+			// Q_ij = (X_ij + X_ji) / (lambda_i + lambda_j), P_ij = X_ij + lambda_i * Q_ij
+			qij := make([]byte, chunkSize)
+			mulAddSlice(c.field, qij, x[i][j], c.field.Inv(c.field.Add(li, lj)))
+			mulAddSlice(c.field, qij, x[j][i], c.field.Inv(c.field.Add(li, lj)))
+			pij := make([]byte, chunkSize)
+			copy(pij, x[i][j])
+			mulAddSlice(c.field, pij, qij, li)
+			p[i][j], p[j][i] = pij, pij
+			q[i][j], q[j][i] = qij, qij
+		}
+	}
+
+	s1, err := c.solveMessage(nodes, p, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	s2, err := c.solveMessage(nodes, q, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the data back from the upper triangles of S1 and S2
+	data := make([]byte, 0, chunkSize*c.StripeSize())
+	for _, s := range [][][][]byte{s1, s2} {
+		for i := 0; i < c.alpha; i++ {
+			for j := i; j < c.alpha; j++ {
+				data = append(data, s[i][j]...)
+			}
+		}
+	}
+	return data[:size], nil
+}
+
+// solveMessage recovers a symmetric message matrix S from the off-diagonal entries of Phi S Phi^T
+func (c *ProductMatrixMSR) solveMessage(nodes []int, product [][][]byte, chunkSize int) ([][][]byte, error) {
+	// For every node i, phi_j^T (S phi_i) = product_ij for the alpha other nodes j gives S phi_i
+	columns := make([][][]byte, c.alpha)
+	for i := 0; i < c.alpha; i++ {
+		matrix := make([][]byte, 0, c.alpha)
+		values := make([][]byte, 0, c.alpha)
+		for j := range nodes {
+			if j != i {
+				matrix = append(matrix, c.psi[nodes[j]][:c.alpha])
+				values = append(values, product[i][j])
+			}
+		}
+		inverse, err := invertMatrix(c.field, matrix)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = c.multiply(inverse, values, chunkSize)
+	}
+
+	// S * F = [S phi_0 ... S phi_alpha-1] with F = [phi_0 ... phi_alpha-1], so S = columns * F^-1
+	f := make([][]byte, c.alpha)
+	for i := 0; i < c.alpha; i++ {
+		f[i] = c.psi[nodes[i]][:c.alpha]
+	}
+	inverse, err := invertMatrix(c.field, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// f holds the rows phi_i^T, so F^-1 is the transpose of its inverse
+	s := make([][][]byte, c.alpha)
+	for r := 0; r < c.alpha; r++ {
+		s[r] = make([][]byte, c.alpha)
+		for col := 0; col < c.alpha; col++ {
+			s[r][col] = make([]byte, chunkSize)
+			for i := 0; i < c.alpha; i++ {
+				mulAddSlice(c.field, s[r][col], columns[i][r], inverse[col][i])
+			}
+		}
+	}
+	return s, nil
+}
+
+// HelperData returns what a helper node sends for the repair of node failed: one symbol per stripe,
+// the combination psi_helper^T * M * phi_failed of its stored symbols
+func (c *ProductMatrixMSR) HelperData(shard []byte, failed int) []byte {
+	chunkSize := len(shard) / c.alpha
+	out := make([]byte, chunkSize)
+	for a := 0; a < c.alpha; a++ {
+		mulAddSlice(c.field, out, c.symbol(shard, a, chunkSize), c.psi[failed][a])
+	}
+	return out
+}
+
+// RepairFromHelpers rebuilds the shard of node failed from the HelperData of d helper nodes
+func (c *ProductMatrixMSR) RepairFromHelpers(failed int, helpers []int, helperData [][]byte) ([]byte, error) {
+	if len(helpers) != c.helpers || len(helperData) != c.helpers {
+		return nil, fmt.Errorf("repair needs exactly %d helpers, got %d", c.helpers, len(helpers))
+	}
+	chunkSize := len(helperData[0])
+
+	// Psi_helpers * M * phi_failed = helperData gives S1 phi_failed and S2 phi_failed
+	matrix := make([][]byte, c.helpers)
+	for i, helper := range helpers {
+		if helper == failed || helper < 0 || helper >= c.totalNodes {
+			return nil, fmt.Errorf("invalid helper node %d", helper)
+		}
+		matrix[i] = c.psi[helper]
+	}
+	inverse, err := invertMatrix(c.field, matrix)
+	if err != nil {
+		return nil, fmt.Errorf("helper nodes are not distinct: %v", err)
+	}
+	w := c.multiply(inverse, helperData, chunkSize)
+
+	// By symmetry the failed node stores (S1 phi_f)^T + lambda_f * (S2 phi_f)^T
+	shard := make([]byte, c.alpha*chunkSize)
+	for a := 0; a < c.alpha; a++ {
+		symbol := shard[a*chunkSize : (a+1)*chunkSize]
+		copy(symbol, w[a])
+		mulAddSlice(c.field, symbol, w[c.alpha+a], c.lambda[failed])
+	}
+	return shard, nil
+}
+
+// Repair rebuilds the lost shard of node failed from the first d available other shards
+// and reports the repair cost
+func (c *ProductMatrixMSR) Repair(shards [][]byte, failed int) ([]byte, RepairStats, error) {
+	var stats RepairStats
+	if len(shards) != c.totalNodes {
+		return nil, stats, fmt.Errorf("expected %d shards, got %d", c.totalNodes, len(shards))
+	}
+
+	var helpers []int
+	var helperData [][]byte
+	for i, shard := range shards {
+		if i == failed || shard == nil || len(helpers) == c.helpers {
+			continue
+		}
+		helpers = append(helpers, i)
+		helperData = append(helperData, c.HelperData(shard, failed))
+		stats.BytesRead += int64(len(shard))
+		stats.BytesTransferred += int64(len(shard) / c.alpha)
+	}
+	if len(helpers) < c.helpers {
+		return nil, stats, fmt.Errorf("not enough helpers: have %d, need %d", len(helpers), c.helpers)
+	}
+	stats.Helpers = len(helpers)
+
+	shard, err := c.RepairFromHelpers(failed, helpers, helperData)
+	return shard, stats, err
+}
+
+// symbol returns stored symbol a of a shard
+func (c *ProductMatrixMSR) symbol(shard []byte, a, chunkSize int) []byte {
+	return shard[a*chunkSize : (a+1)*chunkSize]
+}
+
+// multiply calculates matrix * vector where every vector entry is a chunk
+func (c *ProductMatrixMSR) multiply(matrix [][]byte, vector [][]byte, chunkSize int) [][]byte {
+	result := make([][]byte, len(matrix))
+	for i, row := range matrix {
+		result[i] = make([]byte, chunkSize)
+		for j, coefficient := range row {
+			mulAddSlice(c.field, result[i], vector[j], coefficient)
+		}
+	}
+	return result
+}
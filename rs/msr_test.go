@@ -0,0 +1,116 @@
+package rs
+
+import (
+	"bytes"
+	"testing"
+)
+
+// msrCodes are the (k, n) parameters of the MSR tests
+var msrCodes = []struct {
+	dataNodes, totalNodes int
+}{
+	{2, 3},
+	{3, 5},
+	{3, 6},
+	{4, 8},
+	{5, 9},
+}
+
+func TestMSRDecode(t *testing.T) {
+	for _, code := range msrCodes {
+		c := NewProductMatrixMSR(newTestField(), code.dataNodes, code.totalNodes)
+		// A size that does not fill the last stripe
+		data := randomBytes(int64(code.totalNodes), 10*c.StripeSize()+3)
+		shards := c.Encode(data)
+		if len(shards) != code.totalNodes {
+			t.Fatalf("k=%d, n=%d: %d shards, want %d", code.dataNodes, code.totalNodes, len(shards), code.totalNodes)
+		}
+		for i, shard := range shards {
+			if len(shard) != c.ShardSize(len(data)) {
+				t.Fatalf("k=%d, n=%d: shard %d has %d bytes, want %d", code.dataNodes, code.totalNodes, i, len(shard), c.ShardSize(len(data)))
+			}
+		}
+
+		for _, lost := range erasurePatterns(code.totalNodes, code.totalNodes-code.dataNodes) {
+			decoded, err := c.Decode(erase(shards, lost), len(data))
+			if err != nil {
+				t.Fatalf("k=%d, n=%d, lost %v: %v", code.dataNodes, code.totalNodes, lost, err)
+			}
+			if !bytes.Equal(decoded, data) {
+				t.Fatalf("k=%d, n=%d, lost %v: decoded data differs", code.dataNodes, code.totalNodes, lost)
+			}
+		}
+
+		tooMany := make([]int, code.totalNodes-code.dataNodes+1)
+		for i := range tooMany {
+			tooMany[i] = i
+		}
+		if _, err := c.Decode(erase(shards, tooMany), len(data)); err == nil {
+			t.Errorf("k=%d, n=%d: Decode accepted %d of %d shards", code.dataNodes, code.totalNodes, code.dataNodes-1, code.totalNodes)
+		}
+	}
+}
+
+func TestMSRRepair(t *testing.T) {
+	for _, code := range msrCodes {
+		c := NewProductMatrixMSR(newTestField(), code.dataNodes, code.totalNodes)
+		data := randomBytes(int64(code.dataNodes), 16*c.StripeSize())
+		shards := c.Encode(data)
+		shardSize := int64(len(shards[0]))
+		alpha := int64(code.dataNodes - 1)
+
+		for failed := range shards {
+			repaired, stats, err := c.Repair(erase(shards, []int{failed}), failed)
+			if err != nil {
+				t.Fatalf("k=%d, n=%d, node %d: %v", code.dataNodes, code.totalNodes, failed, err)
+			}
+			if !bytes.Equal(repaired, shards[failed]) {
+				t.Fatalf("k=%d, n=%d, node %d: repaired shard differs", code.dataNodes, code.totalNodes, failed)
+			}
+
+			// d = 2k-2 helpers each send 1/(k-1) of their shard: 2 shards in all, k for RS
+			want := RepairStats{
+				Helpers:          c.Helpers(),
+				BytesRead:        int64(c.Helpers()) * shardSize,
+				BytesTransferred: int64(c.Helpers()) * shardSize / alpha,
+			}
+			if stats != want {
+				t.Errorf("k=%d, n=%d, node %d: stats %+v, want %+v", code.dataNodes, code.totalNodes, failed, stats, want)
+			}
+			if rs := RSRepairStats(code.dataNodes, shardSize); code.dataNodes > 2 && stats.BytesTransferred >= rs.BytesTransferred {
+				t.Errorf("k=%d, n=%d: transfers %d bytes, RS %d", code.dataNodes, code.totalNodes, stats.BytesTransferred, rs.BytesTransferred)
+			}
+		}
+	}
+}
+
+func TestMSRRepairFromAnyHelpers(t *testing.T) {
+	c := NewProductMatrixMSR(newTestField(), 3, 6)
+	shards := c.Encode(randomBytes(3, 100))
+
+	// Node 0 from every set of d = 4 of the 5 other nodes
+	for _, lost := range erasurePatterns(5, 1) {
+		var helpers []int
+		var helperData [][]byte
+		for i := 1; i < 6; i++ {
+			if i != lost[0]+1 {
+				helpers = append(helpers, i)
+				helperData = append(helperData, c.HelperData(shards[i], 0))
+			}
+		}
+		repaired, err := c.RepairFromHelpers(0, helpers, helperData)
+		if err != nil {
+			t.Fatalf("helpers %v: %v", helpers, err)
+		}
+		if !bytes.Equal(repaired, shards[0]) {
+			t.Errorf("helpers %v: repaired shard differs", helpers)
+		}
+	}
+
+	if _, _, err := c.Repair(erase(shards, []int{0, 1, 2}), 0); err == nil {
+		t.Error("Repair accepted 3 helpers, 4 are needed")
+	}
+	if _, err := c.RepairFromHelpers(0, []int{0, 1, 2, 3}, make([][]byte, 4)); err == nil {
+		t.Error("RepairFromHelpers accepted the failed node as a helper")
+	}
+}
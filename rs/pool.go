@@ -0,0 +1,68 @@
+package rs
+
+import "sync"
+
+// ShardPool recycles shard buffers, keeping a sync.Pool per shard size, so that
+// encoding many stripes of the same size does not allocate in steady state.
+// Buffers are handled through pointers so that returning them does not allocate either.
+type ShardPool struct {
+	mu    sync.RWMutex
+	pools map[int]*sync.Pool
+}
+
+// NewShardPool creates an empty shard buffer pool
+func NewShardPool() *ShardPool {
+	return &ShardPool{pools: make(map[int]*sync.Pool)}
+}
+
+// pool returns the pool of the buffers of size bytes, creating it on first use
+func (p *ShardPool) pool(size int) *sync.Pool {
+	p.mu.RLock()
+	pool, ok := p.pools[size]
+	p.mu.RUnlock()
+	if ok {
+		return pool
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pool, ok = p.pools[size]; !ok {
+		pool = &sync.Pool{New: func() interface{} {
+			buf := make([]byte, size)
+			return &buf
+		}}
+		p.pools[size] = pool
+	}
+	return pool
+}
+
+// Get returns a shard buffer of size bytes, its content is undefined
+func (p *ShardPool) Get(size int) *[]byte {
+	if size < 0 {
+		panic("Shard size must not be negative")
+	}
+	return p.pool(size).Get().(*[]byte)
+}
+
+// Put returns a buffer obtained from Get to the pool
+func (p *ShardPool) Put(buf *[]byte) {
+	p.pool(len(*buf)).Put(buf)
+}
+
+// GetStripe fills stripe with buffers of size bytes, the slice itself is owned by the caller
+func (p *ShardPool) GetStripe(stripe []*[]byte, size int) {
+	pool := p.pool(size)
+	for i := range stripe {
+		stripe[i] = pool.Get().(*[]byte)
+	}
+}
+
+// PutStripe returns the buffers of a stripe obtained from GetStripe and clears it
+func (p *ShardPool) PutStripe(stripe []*[]byte) {
+	for i, buf := range stripe {
+		if buf != nil {
+			p.Put(buf)
+			stripe[i] = nil
+		}
+	}
+}
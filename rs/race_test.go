@@ -0,0 +1,7 @@
+//go:build race
+
+package rs
+
+// raceEnabled is true when the tests run with the race detector, which makes sync.Pool
+// drop items at random
+const raceEnabled = true
@@ -0,0 +1,224 @@
+package rs
+
+import (
+	"rs-encoder/gf"
+)
+
+// EncodeShards calculates the parity shards of a stripe of equal length shards.
+// Every byte position (column) across the shards is encoded as one codeword, so
+// shards[n][c] is the same value Encode would return at index n for column c.
+// shards must hold totalShards entries: the first dataShards are the data, the
+// parity entries are allocated if nil and overwritten.
+func (enc *RSEncoder) EncodeShards(shards [][]byte) {
+	if len(shards) != enc.totalShards {
+		panic("Number of shards must equal the total number of shards")
+	}
+
+	shardSize := len(shards[0])
+	for i := 0; i < enc.dataShards; i++ {
+		if len(shards[i]) != shardSize {
+			panic("All data shards must have the same length")
+		}
+	}
+
+	// Each parity shard is a linear combination of the data shards
+	for i := 0; i < enc.parityShards; i++ {
+		parity := shards[enc.dataShards+i]
+		if len(parity) != shardSize {
+			parity = make([]byte, shardSize)
+			shards[enc.dataShards+i] = parity
+		} else {
+			for c := range parity {
+				parity[c] = 0
+			}
+		}
+
+		for j := 0; j < enc.dataShards; j++ {
+			mulAddSlice(enc.field, parity, shards[j], enc.parityMatrix[i][j])
+		}
+	}
+}
+
+// generateParityMatrix precomputes the Lagrange basis values of the data points at every parity point,
+// so that parity[i] = sum(parityMatrix[i][j] * data[j])
+func (enc *RSEncoder) generateParityMatrix() {
+	enc.parityMatrix = make([][]byte, enc.parityShards)
+	for i := 0; i < enc.parityShards; i++ {
+		enc.parityMatrix[i] = lagrangeRow(enc.field, enc.alphaPoints[:enc.dataShards], enc.alphaPoints[enc.dataShards+i])
+	}
+}
+
+// ReconstructShards recovers the missing shards of a stripe in place.
+// shards must hold totalShards entries, missing shards are nil; at least dataShards
+// shards must be present and all present shards must have the same length.
+func (dec *VandermondeDecoder) ReconstructShards(shards [][]byte) {
+	dec.reconstructShards(shards, dec.totalShards)
+}
+
+// reconstructShards recovers the missing shards among the first limit shards of a stripe
+func (dec *VandermondeDecoder) reconstructShards(shards [][]byte, limit int) {
+	if len(shards) != dec.totalShards {
+		panic("Number of shards must equal the total number of shards")
+	}
+
+	// Use the first dataShards available shards
+	indices := make([]int, 0, dec.dataShards)
+	shardSize := -1
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if shardSize < 0 {
+			shardSize = len(shard)
+		} else if len(shard) != shardSize {
+			panic("All shards must have the same length")
+		}
+		if len(indices) < dec.dataShards {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) < dec.dataShards {
+		panic("Not enough shards to reconstruct data")
+	}
+
+	// Evaluate the interpolation polynomial at the point of every missing shard
+	for i, shard := range shards[:limit] {
+		if shard != nil {
+			continue
+		}
+		shard = make([]byte, shardSize)
+		row := dec.interpolationRow(indices, dec.alphaPoints[i])
+		for j, index := range indices {
+			mulAddSlice(dec.field, shard, shards[index], row[j])
+		}
+		shards[i] = shard
+	}
+}
+
+// ReconstructInto recovers the missing shards of a stripe into caller-owned buffers, without
+// allocating. Every entry of shards must be a buffer of the same length; present reports
+// which of them hold valid shards, the others are overwritten with the recovered shards.
+// At least dataShards shards must be present.
+func (dec *VandermondeDecoder) ReconstructInto(shards [][]byte, present []bool) {
+	if len(shards) != dec.totalShards || len(present) != dec.totalShards {
+		panic("Number of shards must equal the total number of shards")
+	}
+	shardSize := len(shards[0])
+	for _, shard := range shards {
+		if len(shard) != shardSize {
+			panic("All shards must have the same length")
+		}
+	}
+
+	// Use the first dataShards available shards
+	var indices [MaxTotalShards]int
+	var points [MaxTotalShards]byte
+	n := 0
+	for i, ok := range present {
+		if ok && n < dec.dataShards {
+			indices[n] = i
+			points[n] = dec.alphaPoints[i]
+			n++
+		}
+	}
+	if n < dec.dataShards {
+		panic("Not enough shards to reconstruct data")
+	}
+
+	var row [MaxTotalShards]byte
+	for i, shard := range shards {
+		if present[i] {
+			continue
+		}
+		for c := range shard {
+			shard[c] = 0
+		}
+		lagrangeRowInto(dec.field, row[:n], points[:n], dec.alphaPoints[i])
+		for j, index := range indices[:n] {
+			mulAddSlice(dec.field, shard, shards[index], row[j])
+		}
+	}
+}
+
+// interpolationRow calculates the coefficients that evaluate, at point x, the polynomial
+// passing through the shards at the given indices
+func (dec *VandermondeDecoder) interpolationRow(indices []int, x byte) []byte {
+	points := make([]byte, len(indices))
+	for j, index := range indices {
+		points[j] = dec.alphaPoints[index]
+	}
+	return lagrangeRow(dec.field, points, x)
+}
+
+// lagrangeRow calculates the Lagrange basis values L_j(x) for the given interpolation points
+func lagrangeRow(field *gf.GF, points []byte, x byte) []byte {
+	row := make([]byte, len(points))
+	lagrangeRowInto(field, row, points, x)
+	return row
+}
+
+// lagrangeRowInto calculates the Lagrange basis values like lagrangeRow into row
+func lagrangeRowInto(field *gf.GF, row, points []byte, x byte) {
+	for j := range points {
+		basis := byte(1)
+		for k := range points {
+			if j != k {
+				// (x - x_k) / (x_j - x_k)
+				factor := field.Div(field.Sub(x, points[k]), field.Sub(points[j], points[k]))
+				basis = field.Mul(basis, factor)
+			}
+		}
+		row[j] = basis
+	}
+}
+
+// mulAddSlice calculates dst[c] += coefficient * src[c] for every column c
+func mulAddSlice(field *gf.GF, dst, src []byte, coefficient byte) {
+	if coefficient == 0 {
+		return
+	}
+	field.MulAddSlice(dst, src, coefficient)
+}
+
+// Split divides data into dataShards equal length data shards, padding the last one with zeros,
+// and returns them followed by empty parity shards ready for EncodeShards
+func (enc *RSEncoder) Split(data []byte) [][]byte {
+	return SplitShards(data, enc.dataShards, enc.totalShards)
+}
+
+// SplitShards divides data into dataShards equal length data shards like Split,
+// followed by totalShards-dataShards zeroed parity shards
+func SplitShards(data []byte, dataShards, totalShards int) [][]byte {
+	shardSize := (len(data) + dataShards - 1) / dataShards
+
+	// Copy into one padded buffer so the caller's data is never modified
+	padded := make([]byte, shardSize*totalShards)
+	copy(padded, data)
+
+	shards := make([][]byte, totalShards)
+	for i := range shards {
+		shards[i] = padded[i*shardSize : (i+1)*shardSize : (i+1)*shardSize]
+	}
+	return shards
+}
+
+// Join concatenates the data shards and returns the first size bytes, removing the padding added by Split.
+// All data shards must be present, call ReconstructShards first if some are missing.
+func (dec *VandermondeDecoder) Join(shards [][]byte, size int) []byte {
+	return JoinShards(shards, dec.dataShards, size)
+}
+
+// JoinShards concatenates the first dataShards shards like Join
+func JoinShards(shards [][]byte, dataShards, size int) []byte {
+	data := make([]byte, 0, size)
+	for i := 0; i < dataShards && len(data) < size; i++ {
+		if shards[i] == nil {
+			panic("Data shards must be reconstructed before joining")
+		}
+		data = append(data, shards[i]...)
+	}
+	if len(data) < size {
+		panic("Not enough data in shards")
+	}
+	return data[:size]
+}
@@ -0,0 +1,253 @@
+package rs
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// StreamEncoder encodes a stream that does not fit in memory with a Codec. The stream is
+// cut into stripes of DataShards*shardSize bytes; every stripe is split over the data
+// shards like SplitShards and appended to the shard streams together with its parity.
+// The last stripe is shorter: its shards hold ceil(rest/DataShards) bytes, the data shards
+// padded with zeros. Only one stripe is held in memory at a time.
+//
+// The stream is encoded as is; util.EncodeCompressedStream compresses it first.
+type StreamEncoder struct {
+	codec     Codec
+	shardSize int
+}
+
+// NewStreamEncoder creates a stream encoder writing stripes of shardSize bytes per shard
+func NewStreamEncoder(codec Codec, shardSize int) *StreamEncoder {
+	if shardSize <= 0 {
+		panic("Shard size must be positive")
+	}
+	return &StreamEncoder{codec: codec, shardSize: shardSize}
+}
+
+// ShardSize returns the number of bytes of every shard of a full stripe
+func (s *StreamEncoder) ShardSize() int {
+	return s.shardSize
+}
+
+// StreamShardSize returns the length of every shard stream of a stream of size bytes
+func (s *StreamEncoder) StreamShardSize(size int64) int64 {
+	stripeSize := int64(s.codec.DataShards() * s.shardSize)
+	full := size / stripeSize
+	return full*int64(s.shardSize) + int64(s.stripeShardSize(size-full*stripeSize))
+}
+
+// stripeShardSize returns the shard length of a stripe holding n bytes of the stream
+func (s *StreamEncoder) stripeShardSize(n int64) int {
+	dataShards := int64(s.codec.DataShards())
+	return int((n + dataShards - 1) / dataShards)
+}
+
+// Encode reads r until EOF and writes the data shard streams to data and the parity
+// shard streams to parity
+func (s *StreamEncoder) Encode(r io.Reader, data, parity []io.Writer) error {
+	return s.EncodeContext(context.Background(), r, data, parity, nil)
+}
+
+// EncodeContext is Encode checking ctx before every stripe and reporting the input bytes
+// encoded to progress, which may be nil. When ctx is done, ctx.Err() is returned and every
+// shard stream holds the same whole number of stripes, as reported by the last progress call.
+func (s *StreamEncoder) EncodeContext(ctx context.Context, r io.Reader, data, parity []io.Writer, progress ProgressFunc) error {
+	dataShards, parityShards := s.codec.DataShards(), s.codec.ParityShards()
+	if len(data) != dataShards {
+		return fmt.Errorf("expected %d data writers, got %d", dataShards, len(data))
+	}
+	if len(parity) != parityShards {
+		return fmt.Errorf("expected %d parity writers, got %d", parityShards, len(parity))
+	}
+	out := append(append([]io.Writer(nil), data...), parity...)
+
+	buf := make([]byte, dataShards*s.shardSize)
+	parityBufs := make([][]byte, parityShards)
+	for i := range parityBufs {
+		parityBufs[i] = make([]byte, s.shardSize)
+	}
+	stripe := make([][]byte, dataShards+parityShards)
+	var p Progress
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read input: %v", err)
+		}
+		last := err == io.ErrUnexpectedEOF
+
+		// Split the stripe like SplitShards, padding the last data shard with zeros
+		shardSize := s.stripeShardSize(int64(n))
+		for i := n; i < dataShards*shardSize; i++ {
+			buf[i] = 0
+		}
+		for i := 0; i < dataShards; i++ {
+			stripe[i] = buf[i*shardSize : (i+1)*shardSize]
+		}
+		for i, parityBuf := range parityBufs {
+			stripe[dataShards+i] = parityBuf[:shardSize]
+		}
+		if err := s.codec.Encode(stripe); err != nil {
+			return err
+		}
+
+		for i, shard := range stripe {
+			if _, err := out[i].Write(shard); err != nil {
+				return fmt.Errorf("failed to write shard %d: %v", i, err)
+			}
+		}
+
+		p.Stripes++
+		p.Bytes += int64(n)
+		if progress != nil {
+			progress(p)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Reconstruct reads the shard streams in (nil for missing streams, at least DataShards
+// must be present) stripe by stripe and writes the shard streams requested in out
+// (nil for the streams not needed), missing ones being reconstructed
+func (s *StreamEncoder) Reconstruct(in []io.Reader, out []io.Writer) error {
+	return s.ReconstructContext(context.Background(), in, out, nil)
+}
+
+// ReconstructContext is Reconstruct checking ctx before every stripe and reporting the data
+// bytes (including the padding of the last stripe) reconstructed to progress, which may be nil.
+// When ctx is done, ctx.Err() is returned and every requested shard stream holds the same
+// whole number of stripes, as reported by the last progress call.
+func (s *StreamEncoder) ReconstructContext(ctx context.Context, in []io.Reader, out []io.Writer, progress ProgressFunc) error {
+	totalShards := s.codec.DataShards() + s.codec.ParityShards()
+	if len(in) != totalShards {
+		return fmt.Errorf("expected %d readers, got %d", totalShards, len(in))
+	}
+	if len(out) != totalShards {
+		return fmt.Errorf("expected %d writers, got %d", totalShards, len(out))
+	}
+
+	bufs := make([][]byte, totalShards)
+	present := 0
+	for i, reader := range in {
+		if reader != nil {
+			bufs[i] = make([]byte, s.shardSize)
+			present++
+		}
+	}
+	if present < s.codec.DataShards() {
+		return fmt.Errorf("not enough shards: have %d, need %d", present, s.codec.DataShards())
+	}
+
+	stripe := make([][]byte, totalShards)
+	var p Progress
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Every present stream must deliver a stripe of the same length
+		shardSize := -1
+		for i, reader := range in {
+			stripe[i] = nil
+			if reader == nil {
+				continue
+			}
+			n, err := io.ReadFull(reader, bufs[i])
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return fmt.Errorf("failed to read shard %d: %v", i, err)
+			}
+			if shardSize < 0 {
+				shardSize = n
+			} else if n != shardSize {
+				return fmt.Errorf("shard %d is truncated: read %d bytes, expected %d", i, n, shardSize)
+			}
+			stripe[i] = bufs[i][:n]
+		}
+		if shardSize <= 0 {
+			return nil
+		}
+
+		if err := s.codec.Reconstruct(stripe); err != nil {
+			return err
+		}
+		for i, w := range out {
+			if w == nil {
+				continue
+			}
+			if _, err := w.Write(stripe[i]); err != nil {
+				return fmt.Errorf("failed to write shard %d: %v", i, err)
+			}
+		}
+
+		p.Stripes++
+		p.Bytes += int64(s.codec.DataShards() * shardSize)
+		if progress != nil {
+			progress(p)
+		}
+		if shardSize < s.shardSize {
+			return nil
+		}
+	}
+}
+
+// Join reads the data shard streams of a stream of size bytes and writes the stream to dst.
+// All data shard streams must be present, call Reconstruct first if some are missing.
+func (s *StreamEncoder) Join(dst io.Writer, data []io.Reader, size int64) error {
+	return s.JoinContext(context.Background(), dst, data, size, nil)
+}
+
+// JoinContext is Join checking ctx before every stripe and reporting the bytes written to
+// progress, which may be nil. When ctx is done, ctx.Err() is returned and dst holds the
+// stream up to the bytes reported by the last progress call.
+func (s *StreamEncoder) JoinContext(ctx context.Context, dst io.Writer, data []io.Reader, size int64, progress ProgressFunc) error {
+	dataShards := s.codec.DataShards()
+	if len(data) != dataShards {
+		return fmt.Errorf("expected %d data readers, got %d", dataShards, len(data))
+	}
+	for i, reader := range data {
+		if reader == nil {
+			return fmt.Errorf("data shard %d is missing", i)
+		}
+	}
+	if size < 0 {
+		return fmt.Errorf("invalid size %d", size)
+	}
+
+	buf := make([]byte, dataShards*s.shardSize)
+	var p Progress
+	for size > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := int64(len(buf))
+		if size < n {
+			n = size
+		}
+		shardSize := s.stripeShardSize(n)
+		for i, reader := range data {
+			if _, err := io.ReadFull(reader, buf[i*shardSize:(i+1)*shardSize]); err != nil {
+				return fmt.Errorf("failed to read data shard %d: %v", i, err)
+			}
+		}
+		if _, err := dst.Write(buf[:n]); err != nil {
+			return fmt.Errorf("failed to write output: %v", err)
+		}
+		size -= n
+
+		p.Stripes++
+		p.Bytes += n
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package rs
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testStripeShardSize is the shard size of a full stripe in the stream tests
+const testStripeShardSize = 64
+
+// newTestStreamEncoder returns a stream encoder over the Vandermonde codec of the tests
+func newTestStreamEncoder(t *testing.T) *StreamEncoder {
+	t.Helper()
+	codec, err := NewCodec("vandermonde", newTestField(), testDataShards, testParityShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewStreamEncoder(codec, testStripeShardSize)
+}
+
+// encodeStream encodes data and returns the shard streams
+func encodeStream(t *testing.T, s *StreamEncoder, data []byte) [][]byte {
+	t.Helper()
+	bufs := make([]bytes.Buffer, testDataShards+testParityShards)
+	writers := make([]io.Writer, len(bufs))
+	for i := range bufs {
+		writers[i] = &bufs[i]
+	}
+	if err := s.Encode(bytes.NewReader(data), writers[:testDataShards], writers[testDataShards:]); err != nil {
+		t.Fatal(err)
+	}
+	// Empty streams are not nil, nil streams are missing ones
+	streams := make([][]byte, len(bufs))
+	for i := range bufs {
+		streams[i] = append([]byte{}, bufs[i].Bytes()...)
+	}
+	return streams
+}
+
+// streamReaders returns readers of the shard streams, nil for the nil ones
+func streamReaders(streams [][]byte) []io.Reader {
+	readers := make([]io.Reader, len(streams))
+	for i, stream := range streams {
+		if stream != nil {
+			readers[i] = bytes.NewReader(stream)
+		}
+	}
+	return readers
+}
+
+// Input lengths around the stripe size of the stream tests
+var streamSizes = []int{
+	0,
+	1,
+	testDataShards * testStripeShardSize,   // Exactly one stripe
+	testDataShards*testStripeShardSize + 1, // One stripe and one byte
+	3*testDataShards*testStripeShardSize + 17, // Several stripes and a short one
+}
+
+func TestStreamEncodeJoin(t *testing.T) {
+	s := newTestStreamEncoder(t)
+	stripeSize := testDataShards * testStripeShardSize
+	for _, size := range streamSizes {
+		data := randomBytes(int64(size), size)
+		streams := encodeStream(t, s, data)
+		for i, stream := range streams {
+			if int64(len(stream)) != s.StreamShardSize(int64(size)) {
+				t.Fatalf("%d bytes: shard stream %d has %d bytes, want %d", size, i, len(stream), s.StreamShardSize(int64(size)))
+			}
+		}
+
+		// Every stripe is encoded like the shards of an object of the stripe
+		offset := 0
+		for start := 0; start < size; start += stripeSize {
+			end := min(start+stripeSize, size)
+			want := encodeObject(newTestField(), data[start:end], testDataShards, testParityShards)
+			shardSize := len(want[0])
+			for i := range want {
+				if !bytes.Equal(streams[i][offset:offset+shardSize], want[i]) {
+					t.Fatalf("%d bytes: stripe at %d, shard %d differs from an encoding of the stripe", size, start, i)
+				}
+			}
+			offset += shardSize
+		}
+
+		var out bytes.Buffer
+		if err := s.Join(&out, streamReaders(streams[:testDataShards]), int64(size)); err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("%d bytes: joined stream differs", size)
+		}
+	}
+}
+
+func TestStreamReconstruct(t *testing.T) {
+	s := newTestStreamEncoder(t)
+	for _, size := range streamSizes {
+		data := randomBytes(int64(size), size)
+		streams := encodeStream(t, s, data)
+
+		for _, lost := range erasurePatterns(testDataShards+testParityShards, testParityShards) {
+			// Every pattern of ParityShards lost streams, and the smaller ones for short streams
+			if len(lost) != testParityShards && size > 1 {
+				continue
+			}
+			bufs := make([]bytes.Buffer, len(streams))
+			writers := make([]io.Writer, len(streams))
+			for i := range bufs {
+				writers[i] = &bufs[i]
+			}
+			if err := s.Reconstruct(streamReaders(erase(streams, lost)), writers); err != nil {
+				t.Fatalf("%d bytes, shards %v lost: %v", size, lost, err)
+			}
+			for i := range streams {
+				if !bytes.Equal(bufs[i].Bytes(), streams[i]) {
+					t.Fatalf("%d bytes, shards %v lost: shard stream %d differs", size, lost, i)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamReconstructOnlyRequested(t *testing.T) {
+	s := newTestStreamEncoder(t)
+	size := 2*testDataShards*testStripeShardSize + 5
+	streams := encodeStream(t, s, randomBytes(1, size))
+
+	// Rebuild the two lost data shards only
+	lost := []int{2, 7}
+	var rebuilt [2]bytes.Buffer
+	out := make([]io.Writer, len(streams))
+	out[2], out[7] = &rebuilt[0], &rebuilt[1]
+	if err := s.Reconstruct(streamReaders(erase(streams, lost)), out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rebuilt[0].Bytes(), streams[2]) || !bytes.Equal(rebuilt[1].Bytes(), streams[7]) {
+		t.Error("rebuilt shard streams differ")
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	s := newTestStreamEncoder(t)
+	// The last stripe has shards of 5 bytes
+	size := 2*testDataShards*testStripeShardSize + 50
+	streams := encodeStream(t, s, randomBytes(2, size))
+	writers := make([]io.Writer, len(streams))
+	for i := range writers {
+		writers[i] = io.Discard
+	}
+
+	// One lost shard stream too many
+	lost := []int{0, 1, 2, 3, 4}
+	if err := s.Reconstruct(streamReaders(erase(streams, lost)), writers); err == nil || !strings.Contains(err.Error(), "not enough shards: have 9, need 10") {
+		t.Errorf("%d lost shard streams: got error %v", len(lost), err)
+	}
+
+	// A shard stream missing the end of its last stripe
+	for _, index := range []int{0, testDataShards} {
+		truncated := append([][]byte(nil), streams...)
+		truncated[index] = streams[index][:len(streams[index])-1]
+		if err := s.Reconstruct(streamReaders(truncated), writers); err == nil || !strings.Contains(err.Error(), "is truncated") {
+			t.Errorf("shard %d truncated: got error %v, want a truncated shard", index, err)
+		}
+	}
+	// A shard stream missing its whole last stripe
+	truncated := append([][]byte(nil), streams...)
+	truncated[5] = streams[5][:2*testStripeShardSize]
+	if err := s.Reconstruct(streamReaders(truncated), writers); err == nil || !strings.Contains(err.Error(), "shard 5 is truncated: read 0 bytes, expected 5") {
+		t.Errorf("last stripe of shard 5 missing: got error %v", err)
+	}
+	truncated[5] = streams[5][:len(streams[5])-1]
+	if err := s.Join(io.Discard, streamReaders(truncated[:testDataShards]), int64(size)); err == nil || !strings.Contains(err.Error(), "failed to read data shard 5") {
+		t.Errorf("join with shard 5 truncated: got error %v", err)
+	}
+
+	if err := s.Join(io.Discard, streamReaders(erase(streams, []int{3})[:testDataShards]), int64(size)); err == nil || !strings.Contains(err.Error(), "data shard 3 is missing") {
+		t.Errorf("join without shard 3: got error %v", err)
+	}
+	if err := s.Encode(bytes.NewReader(nil), writers[:testDataShards-1], writers[testDataShards:]); err == nil {
+		t.Error("Encode accepted too few data writers")
+	}
+}
@@ -0,0 +1,247 @@
+package rs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"rs-encoder/gf"
+)
+
+// transcodeBlockSize is the number of shard columns processed per step
+const transcodeBlockSize = 64 * 1024
+
+// Transcoder converts the shards of an object from one (dataShards, parityShards) layout
+// to another without decoding the whole object first. The data of an object is laid out
+// contiguously over the data shards: data shard d holds bytes [d*shardSize, (d+1)*shardSize).
+// The old and new layout may use different fields (primitive polynomials) and codecs.
+type Transcoder struct {
+	oldCodec Codec // Recovers missing old data shards
+	newCodec Codec // Calculates the new parity shards and verifies the new codewords
+
+	// Interpolation of missing old data shards column by column, nil unless the old
+	// codec is the Vandermonde or Lagrange code
+	oldDecoder *VandermondeDecoder
+}
+
+// NewTranscoder creates a transcoder from the old layout to the new layout, both encoded with
+// the Vandermonde code (or the Lagrange code, which has the same codewords)
+func NewTranscoder(oldField *gf.GF, oldDataShards, oldParityShards int, newField *gf.GF, newDataShards, newParityShards int, opts ...Option) *Transcoder {
+	oldCodec, _ := newVandermondeCodec(oldField, oldDataShards, oldParityShards, opts...)
+	newCodec, _ := newVandermondeCodec(newField, newDataShards, newParityShards, opts...)
+	return NewCodecTranscoder(oldCodec, newCodec)
+}
+
+// NewCodecTranscoder creates a transcoder between the layouts of two codecs, e.g. created
+// with NewCodec: the old codec decodes missing old data shards and the new codec encodes the
+// new layout, so shards can be moved to another codec as well as another layout.
+func NewCodecTranscoder(oldCodec, newCodec Codec) *Transcoder {
+	t := &Transcoder{oldCodec: oldCodec, newCodec: newCodec}
+	switch c := oldCodec.(type) {
+	case *vandermondeCodec:
+		t.oldDecoder = c.decoder
+	case *lagrangeCodec:
+		t.oldDecoder = NewVandermondeDecoder(c.field, c.DataShards(), c.DataShards()+c.ParityShards())
+	}
+	return t
+}
+
+// NewShardSize returns the shard size of the new layout for an object of size bytes
+func (t *Transcoder) NewShardSize(size int64) int64 {
+	dataShards := int64(t.newCodec.DataShards())
+	return (size + dataShards - 1) / dataShards
+}
+
+// Transcode reads the old layout and writes the new layout of the first size bytes of the object.
+// in holds one reader per old shard index (nil for missing shards), each shard being shardSize bytes;
+// at least dataShards old shards must be present. out holds one writer per new shard index.
+// The shards are processed in blocks of columns, and every new block is verified by decoding it
+// from the last dataShards new shards before it is written.
+func (t *Transcoder) Transcode(in []io.ReaderAt, shardSize, size int64, out []io.Writer) error {
+	oldDataShards := t.oldCodec.DataShards()
+	if oldTotalShards := oldDataShards + t.oldCodec.ParityShards(); len(in) != oldTotalShards {
+		return fmt.Errorf("expected %d old shards, got %d", oldTotalShards, len(in))
+	}
+	newDataShards := t.newCodec.DataShards()
+	newTotalShards := newDataShards + t.newCodec.ParityShards()
+	if len(out) != newTotalShards {
+		return fmt.Errorf("expected %d new shards, got %d", newTotalShards, len(out))
+	}
+	if size < 0 || size > int64(oldDataShards)*shardSize {
+		return fmt.Errorf("object size %d does not fit in %d shards of %d bytes", size, oldDataShards, shardSize)
+	}
+
+	// Use the first dataShards available old shards to recover missing data shards
+	indices := make([]int, 0, oldDataShards)
+	for i, reader := range in {
+		if reader != nil && len(indices) < oldDataShards {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) < oldDataShards {
+		return fmt.Errorf("not enough shards to reconstruct data: have %d, need %d", len(indices), oldDataShards)
+	}
+
+	reader := &transcodeReader{
+		codec:     t.oldCodec,
+		decoder:   t.oldDecoder,
+		in:        in,
+		indices:   indices,
+		rows:      make(map[int][]byte),
+		shardSize: shardSize,
+		size:      size,
+	}
+
+	newShardSize := t.NewShardSize(size)
+
+	for c0 := int64(0); c0 < newShardSize; c0 += transcodeBlockSize {
+		n := newShardSize - c0
+		if n > transcodeBlockSize {
+			n = transcodeBlockSize
+		}
+
+		// Gather the columns of the new data shards from the old layout
+		stripe := make([][]byte, newTotalShards)
+		for d := 0; d < newDataShards; d++ {
+			stripe[d] = make([]byte, n)
+			if err := reader.readData(int64(d)*newShardSize+c0, stripe[d]); err != nil {
+				return err
+			}
+		}
+		if err := t.newCodec.Encode(stripe); err != nil {
+			return err
+		}
+
+		if err := t.verify(stripe); err != nil {
+			return fmt.Errorf("verification failed at column %d: %v", c0, err)
+		}
+
+		for i, shard := range stripe {
+			if _, err := out[i].Write(shard); err != nil {
+				return fmt.Errorf("failed to write shard %d: %v", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// verify decodes the data of a new stripe from its last dataShards shards and compares it with the input
+func (t *Transcoder) verify(stripe [][]byte) error {
+	newDataShards := t.newCodec.DataShards()
+	if t.newCodec.ParityShards() == 0 {
+		return nil
+	}
+
+	check := make([][]byte, len(stripe))
+	copy(check[len(stripe)-newDataShards:], stripe[len(stripe)-newDataShards:])
+	if err := t.newCodec.Decode(check); err != nil {
+		return err
+	}
+
+	for d := 0; d < newDataShards; d++ {
+		if !bytes.Equal(check[d], stripe[d]) {
+			return fmt.Errorf("data shard %d does not match the decoded value", d)
+		}
+	}
+	return nil
+}
+
+// transcodeReader reads byte ranges of the object data from the old layout,
+// decoding the columns of missing data shards on the fly
+type transcodeReader struct {
+	codec     Codec
+	decoder   *VandermondeDecoder // Interpolates single missing data shards, nil to decode with codec
+	in        []io.ReaderAt
+	indices   []int          // Old shards used to recover missing data shards
+	rows      map[int][]byte // Interpolation coefficients per missing data shard
+	shardSize int64
+	size      int64
+}
+
+// readData fills buf with the object data starting at offset, bytes beyond the object size are zero
+func (r *transcodeReader) readData(offset int64, buf []byte) error {
+	for pos := 0; pos < len(buf); {
+		o := offset + int64(pos)
+		if o >= r.size {
+			// Padding of the last new data shard
+			for i := pos; i < len(buf); i++ {
+				buf[i] = 0
+			}
+			return nil
+		}
+
+		// Read up to the end of the old data shard, the buffer or the object
+		d := int(o / r.shardSize)
+		col := o % r.shardSize
+		n := int64(len(buf) - pos)
+		if n > r.shardSize-col {
+			n = r.shardSize - col
+		}
+		if n > r.size-o {
+			n = r.size - o
+		}
+
+		if err := r.readShard(d, col, buf[pos:pos+int(n)]); err != nil {
+			return err
+		}
+		pos += int(n)
+	}
+	return nil
+}
+
+// readShard reads columns of old data shard d starting at col, decoding them if the shard is missing
+func (r *transcodeReader) readShard(d int, col int64, piece []byte) error {
+	if r.in[d] != nil {
+		return readFullAt(r.in[d], d, col, piece)
+	}
+	if r.decoder == nil {
+		return r.decodeShard(d, col, piece)
+	}
+
+	row, ok := r.rows[d]
+	if !ok {
+		row = r.decoder.interpolationRow(r.indices, r.decoder.alphaPoints[d])
+		r.rows[d] = row
+	}
+
+	for i := range piece {
+		piece[i] = 0
+	}
+	column := make([]byte, len(piece))
+	for j, index := range r.indices {
+		if err := readFullAt(r.in[index], index, col, column); err != nil {
+			return err
+		}
+		mulAddSlice(r.decoder.field, piece, column, row[j])
+	}
+	return nil
+}
+
+// decodeShard decodes columns of missing old data shard d starting at col with the old codec,
+// from the same columns of the available shards
+func (r *transcodeReader) decodeShard(d int, col int64, piece []byte) error {
+	stripe := make([][]byte, len(r.in))
+	for _, index := range r.indices {
+		stripe[index] = make([]byte, len(piece))
+		if err := readFullAt(r.in[index], index, col, stripe[index]); err != nil {
+			return err
+		}
+	}
+	if err := r.codec.Decode(stripe); err != nil {
+		return fmt.Errorf("failed to decode data shard %d: %v", d, err)
+	}
+	copy(piece, stripe[d])
+	return nil
+}
+
+// readFullAt reads exactly len(buf) bytes of shard index at offset
+func readFullAt(reader io.ReaderAt, index int, offset int64, buf []byte) error {
+	n, err := reader.ReadAt(buf, offset)
+	if n == len(buf) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("failed to read shard %d: %v", index, err)
+}
@@ -0,0 +1,187 @@
+package rs
+
+import (
+	"bytes"
+	"io"
+	"rs-encoder/gf"
+	"testing"
+)
+
+// encodeObject splits data over dataShards data shards and encodes them with RSEncoder
+func encodeObject(field *gf.GF, data []byte, dataShards, parityShards int) [][]byte {
+	shards := SplitShards(data, dataShards, dataShards+parityShards)
+	NewRSEncoder(field, dataShards, parityShards).EncodeShards(shards)
+	return shards
+}
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		name                           string
+		oldDataShards, oldParityShards int
+		oldPoly                        byte
+		newDataShards, newParityShards int
+		newPoly                        byte
+		size                           int
+		lost                           [][]int // Old shards lost, nil for every pattern of up to oldParityShards
+	}{
+		{"more parity", 4, 2, 0x1d, 4, 4, 0x1d, 1000, nil},
+		{"fewer data shards", 6, 3, 0x1d, 4, 2, 0x1d, 999, nil},
+		{"more data shards", 4, 2, 0x1d, 8, 3, 0x1d, 1001, nil},
+		{"other field", 5, 2, 0x1d, 5, 2, 0x2b, 777, nil},
+		{"no new parity", 3, 2, 0x1d, 2, 0, 0x1d, 100, nil},
+		{"empty object", 4, 2, 0x1d, 6, 3, 0x1d, 0, [][]int{nil}},
+		// Several blocks of transcodeBlockSize columns
+		{"large object", 10, 4, 0x1d, 6, 3, 0x1d, 6*transcodeBlockSize + 12345, [][]int{nil, {0}, {3, 9}, {0, 1, 2, 3}, {10, 11, 12, 13}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldField, newField := gf.NewGF(test.oldPoly), gf.NewGF(test.newPoly)
+			data := randomBytes(int64(test.size), test.size)
+			oldShards := encodeObject(oldField, data, test.oldDataShards, test.oldParityShards)
+			shardSize := int64(len(oldShards[0]))
+
+			// The new layout must be the one encoding the data directly gives
+			want := encodeObject(newField, data, test.newDataShards, test.newParityShards)
+			transcoder := NewTranscoder(oldField, test.oldDataShards, test.oldParityShards, newField, test.newDataShards, test.newParityShards)
+			if got := transcoder.NewShardSize(int64(test.size)); got != int64(len(want[0])) {
+				t.Fatalf("NewShardSize = %d, want %d", got, len(want[0]))
+			}
+
+			lost := test.lost
+			if lost == nil {
+				lost = erasurePatterns(test.oldDataShards+test.oldParityShards, test.oldParityShards)
+			}
+			for _, pattern := range lost {
+				in := make([]io.ReaderAt, len(oldShards))
+				for i, shard := range erase(oldShards, pattern) {
+					if shard != nil {
+						in[i] = bytes.NewReader(shard)
+					}
+				}
+				out := make([]io.Writer, len(want))
+				got := make([]*bytes.Buffer, len(want))
+				for i := range out {
+					got[i] = new(bytes.Buffer)
+					out[i] = got[i]
+				}
+
+				if err := transcoder.Transcode(in, shardSize, int64(test.size), out); err != nil {
+					t.Fatalf("lost %v: %v", pattern, err)
+				}
+				for i := range want {
+					if !bytes.Equal(got[i].Bytes(), want[i]) {
+						t.Fatalf("lost %v: new shard %d differs", pattern, i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTranscodeErrors(t *testing.T) {
+	field := newTestField()
+	shards := encodeObject(field, randomBytes(1, 100), 4, 2)
+	transcoder := NewTranscoder(field, 4, 2, field, 5, 2)
+	readers := func(lost ...int) []io.ReaderAt {
+		in := make([]io.ReaderAt, len(shards))
+		for i, shard := range erase(shards, lost) {
+			if shard != nil {
+				in[i] = bytes.NewReader(shard)
+			}
+		}
+		return in
+	}
+	out := make([]io.Writer, 7)
+	for i := range out {
+		out[i] = io.Discard
+	}
+
+	tests := []struct {
+		name      string
+		in        []io.ReaderAt
+		shardSize int64
+		size      int64
+		out       []io.Writer
+	}{
+		{"too many lost shards", readers(0, 1, 2), 25, 100, out},
+		{"object larger than the shards", readers(), 25, 101, out},
+		{"truncated shard", readers(), 26, 104, out},
+		{"wrong number of old shards", readers()[:5], 25, 100, out},
+		{"wrong number of new shards", readers(), 25, 100, out[:6]},
+	}
+	for _, test := range tests {
+		if err := transcoder.Transcode(test.in, test.shardSize, test.size, test.out); err == nil {
+			t.Errorf("%s: Transcode succeeded", test.name)
+		}
+	}
+}
+
+// Transcoding to another codec gives the shards encoding the data with that codec directly
+func TestTranscodeCodecs(t *testing.T) {
+	tests := []struct {
+		oldCodec, newCodec string
+		lost               [][]int // Old shards lost, nil for every pattern of 4
+	}{
+		{CodecVandermonde, CodecLagrange, nil},
+		{CodecLagrange, CodecVandermonde, nil},
+		{CodecLagrange, CodecLagrange, nil},
+		// Horner shards are decoded by the Horner codec, which is not MDS
+		{CodecHorner, CodecVandermonde, [][]int{nil, {0}, {5}, {0, 5}, {6, 7}, {4, 5, 6, 7}}},
+		{CodecVandermonde, CodecHorner, nil},
+	}
+
+	field := newTestField()
+	data := randomBytes(7, transcodeBlockSize+1234)
+	for _, test := range tests {
+		t.Run(test.oldCodec+" to "+test.newCodec, func(t *testing.T) {
+			oldCodec, err := NewCodec(test.oldCodec, field, 4, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			newCodec, err := NewCodec(test.newCodec, field, 6, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldShards := SplitShards(data, 4, 8)
+			if err := oldCodec.Encode(oldShards); err != nil {
+				t.Fatal(err)
+			}
+			want := SplitShards(data, 6, 9)
+			if err := newCodec.Encode(want); err != nil {
+				t.Fatal(err)
+			}
+
+			transcoder := NewCodecTranscoder(oldCodec, newCodec)
+			lost := test.lost
+			if lost == nil {
+				for _, pattern := range erasurePatterns(8, 4) {
+					if len(pattern) == 4 {
+						lost = append(lost, pattern)
+					}
+				}
+			}
+			for _, pattern := range lost {
+				in := make([]io.ReaderAt, len(oldShards))
+				for i, shard := range erase(oldShards, pattern) {
+					if shard != nil {
+						in[i] = bytes.NewReader(shard)
+					}
+				}
+				got := make([]bytes.Buffer, len(want))
+				out := make([]io.Writer, len(want))
+				for i := range out {
+					out[i] = &got[i]
+				}
+				if err := transcoder.Transcode(in, int64(len(oldShards[0])), int64(len(data)), out); err != nil {
+					t.Fatalf("lost %v: %v", pattern, err)
+				}
+				for i := range want {
+					if !bytes.Equal(got[i].Bytes(), want[i]) {
+						t.Fatalf("lost %v: new shard %d differs", pattern, i)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package sss
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CombineDetect recovers the secret from shares that may include forged or corrupted ones,
+// using the redundancy of the shares beyond the threshold. The shares of a secret form a
+// Reed-Solomon codeword per secret byte, so with m shares and threshold t up to (m-t)/2
+// inconsistent shares per byte are corrected by Berlekamp-Welch decoding. The indices of
+// the shares that disagree with the recovered secret are returned, sorted.
+//
+// With exactly t shares nothing can be checked; with fewer than t+2 shares an
+// inconsistency is detected but the cheating share cannot be identified. Unlike Combine,
+// the decoding branches on the share values and is not constant time.
+func CombineDetect(shares []Share) ([]byte, []int, error) {
+	if err := checkShares(shares); err != nil {
+		return nil, nil, err
+	}
+	threshold := shares[0].Threshold
+	maxErrors := (len(shares) - threshold) / 2
+
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		xs[i] = byte(share.Index)
+	}
+	ys := make([]byte, len(shares))
+	secret := make([]byte, len(shares[0].Value))
+	inconsistent := make(map[int]bool)
+	for b := range secret {
+		for i, share := range shares {
+			ys[i] = share.Value[b]
+		}
+		poly, ok := berlekampWelch(xs, ys, threshold, maxErrors)
+		if !ok {
+			return nil, nil, fmt.Errorf("shares are inconsistent at byte %d and the cheating shares cannot be identified: "+
+				"%d shares with threshold %d identify at most %d", b, len(shares), threshold, maxErrors)
+		}
+		secret[b] = poly[0]
+		for i, share := range shares {
+			if evaluate(poly, xs[i]) != ys[i] {
+				inconsistent[share.Index] = true
+			}
+		}
+	}
+
+	indices := make([]int, 0, len(inconsistent))
+	for index := range inconsistent {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	return secret, indices, nil
+}
+
+// berlekampWelch finds the polynomial P of degree below threshold that agrees with all but at
+// most maxErrors of the points (xs[i], ys[i]). It solves Q(x_i) = y_i * E(x_i) for a monic error
+// locator E of degree maxErrors and Q of degree maxErrors+threshold-1, then divides P = Q / E.
+func berlekampWelch(xs, ys []byte, threshold, maxErrors int) ([]byte, bool) {
+	qTerms := maxErrors + threshold
+	unknowns := qTerms + maxErrors
+
+	// Row i: sum q_j x_i^j + y_i * sum_{k<e} e_k x_i^k = y_i * x_i^e (subtraction is addition)
+	matrix := make([][]byte, len(xs))
+	rhs := make([]byte, len(xs))
+	for i, x := range xs {
+		row := make([]byte, unknowns)
+		power := byte(1)
+		for j := 0; j < qTerms; j++ {
+			row[j] = power
+			if j < maxErrors {
+				row[qTerms+j] = field.Mul(ys[i], power)
+			}
+			if j == maxErrors {
+				rhs[i] = field.Mul(ys[i], power)
+			}
+			power = field.Mul(power, x)
+		}
+		matrix[i] = row
+	}
+
+	solution, ok := solve(matrix, rhs, unknowns)
+	if !ok {
+		return nil, false
+	}
+	q := solution[:qTerms]
+	locator := append(append([]byte(nil), solution[qTerms:]...), 1)
+
+	// Divide Q by the monic E, the remainder must vanish
+	remainder := append([]byte(nil), q...)
+	quotient := make([]byte, threshold)
+	for i := qTerms - 1; i >= maxErrors; i-- {
+		coefficient := remainder[i]
+		quotient[i-maxErrors] = coefficient
+		for j, e := range locator {
+			remainder[i-maxErrors+j] ^= field.Mul(coefficient, e)
+		}
+	}
+	for _, r := range remainder[:maxErrors] {
+		if r != 0 {
+			return nil, false
+		}
+	}
+	return quotient, true
+}
+
+// solve finds a solution of matrix * x = rhs over GF(2^8) by Gauss-Jordan elimination, free
+// variables being set to 0. It reports false if the system is inconsistent. The arguments
+// are modified.
+func solve(matrix [][]byte, rhs []byte, unknowns int) ([]byte, bool) {
+	var pivots []int
+	r := 0
+	for c := 0; c < unknowns && r < len(matrix); c++ {
+		pivot := -1
+		for i := r; i < len(matrix); i++ {
+			if matrix[i][c] != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		matrix[r], matrix[pivot] = matrix[pivot], matrix[r]
+		rhs[r], rhs[pivot] = rhs[pivot], rhs[r]
+
+		inv := field.Inv(matrix[r][c])
+		for j := range matrix[r] {
+			matrix[r][j] = field.Mul(matrix[r][j], inv)
+		}
+		rhs[r] = field.Mul(rhs[r], inv)
+
+		for i := range matrix {
+			if i == r || matrix[i][c] == 0 {
+				continue
+			}
+			factor := matrix[i][c]
+			for j := range matrix[i] {
+				matrix[i][j] ^= field.Mul(factor, matrix[r][j])
+			}
+			rhs[i] ^= field.Mul(factor, rhs[r])
+		}
+		pivots = append(pivots, c)
+		r++
+	}
+
+	for i := r; i < len(matrix); i++ {
+		if rhs[i] != 0 {
+			return nil, false
+		}
+	}
+	solution := make([]byte, unknowns)
+	for i, c := range pivots {
+		solution[c] = rhs[i]
+	}
+	return solution, true
+}
+
+// evaluate calculates the value of the polynomial with the given coefficients (lowest
+// degree first) at x with Horner's method
+func evaluate(poly []byte, x byte) byte {
+	result := byte(0)
+	for i := len(poly) - 1; i >= 0; i-- {
+		result = field.Add(field.Mul(result, x), poly[i])
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package sss
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// corrupt returns a copy of shares with every byte of the shares at positions changed
+func corrupt(shares []Share, positions ...int) []Share {
+	corrupted := append([]Share(nil), shares...)
+	for _, p := range positions {
+		value := append([]byte(nil), shares[p].Value...)
+		for b := range value {
+			value[b] ^= byte(0x5a + p)
+		}
+		corrupted[p].Value = value
+	}
+	return corrupted
+}
+
+func TestCombineDetect(t *testing.T) {
+	secret := testSecret(5, 24)
+	shares, err := Split(secret, 7, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		shares    []Share
+		corrupted []int // Positions of the shares to corrupt
+		want      []int // Indices reported as inconsistent
+	}{
+		{"all consistent", shares, nil, []int{}},
+		{"one corrupted share", shares, []int{3}, []int{4}},
+		// 7 shares with threshold 3 correct (7-3)/2 = 2 inconsistent shares
+		{"two corrupted shares", shares, []int{0, 6}, []int{1, 7}},
+		// 5 shares correct one
+		{"one of five corrupted", shares[2:], []int{1}, []int{4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, inconsistent, err := CombineDetect(corrupt(test.shares, test.corrupted...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Error("wrong secret")
+			}
+			if !reflect.DeepEqual(inconsistent, test.want) {
+				t.Errorf("inconsistent shares %v, want %v", inconsistent, test.want)
+			}
+		})
+	}
+}
+
+func TestCombineDetectCannotIdentify(t *testing.T) {
+	shares, err := Split(testSecret(6, 16), 7, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		shares    []Share
+		corrupted []int
+	}{
+		{"three of seven corrupted", shares, []int{1, 2, 5}},
+		// One share beyond the threshold detects but does not identify a cheater
+		{"one of four corrupted", shares[:4], []int{2}},
+	}
+	for _, test := range tests {
+		// A wrong secret must never be returned
+		if got, _, err := CombineDetect(corrupt(test.shares, test.corrupted...)); err == nil || !strings.Contains(err.Error(), "cannot be identified") {
+			t.Errorf("%s: got secret %x and error %v, want shares that cannot be identified", test.name, got, err)
+		}
+	}
+
+	// With exactly the threshold nothing can be checked
+	if _, inconsistent, err := CombineDetect(corrupt(shares[:3], 1)); err != nil || len(inconsistent) != 0 {
+		t.Errorf("threshold shares: got %v, %v, want no inconsistency found", inconsistent, err)
+	}
+}
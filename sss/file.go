@@ -0,0 +1,178 @@
+package sss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ShareMagic identifies a share file
+const ShareMagic = "RSSS"
+
+// ShareFormatVersion is the version of the share header written by this package.
+// Version 1 headers, written before shares had an epoch, are still read as epoch 0.
+const ShareFormatVersion = 2
+
+// ShareHeaderSize is the size in bytes of an encoded share header
+const ShareHeaderSize = 40
+
+// shareHeaderSizeV1 is the size in bytes of a version 1 share header
+const shareHeaderSizeV1 = 36
+
+// ErrChecksum is returned when a share value or header does not match its checksum
+var ErrChecksum = errors.New("checksum mismatch")
+
+// castagnoli is the CRC32C table used for share checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ShareFileName returns the name of the file of share index of the secret file name
+func ShareFileName(name string, index int) string {
+	return fmt.Sprintf("%s.share%03d", name, index)
+}
+
+// MarshalBinary encodes the share into a header followed by the value. The header is big-endian:
+//
+//	magic [4] | version u8 | threshold u8 | index u8 | reserved u8 | value length u32 |
+//	secret id [16] | epoch u32 | value CRC32C u32 | header CRC32C u32
+func (s Share) MarshalBinary() ([]byte, error) {
+	if s.Threshold < MinThreshold || s.Threshold > MaxShares || s.Index < 1 || s.Index > MaxShares {
+		return nil, fmt.Errorf("threshold must be between %d and %d, index between 1 and %d", MinThreshold, MaxShares, MaxShares)
+	}
+	if uint64(len(s.Value)) > 0xFFFFFFFF {
+		return nil, fmt.Errorf("share value too long: %d bytes", len(s.Value))
+	}
+
+	buf := make([]byte, ShareHeaderSize, ShareHeaderSize+len(s.Value))
+	copy(buf[0:4], ShareMagic)
+	buf[4] = ShareFormatVersion
+	buf[5] = byte(s.Threshold)
+	buf[6] = byte(s.Index)
+	binary.BigEndian.PutUint32(buf[8:12], uint32(len(s.Value)))
+	copy(buf[12:28], s.ID[:])
+	binary.BigEndian.PutUint32(buf[28:32], s.Epoch)
+	binary.BigEndian.PutUint32(buf[32:36], crc32.Checksum(s.Value, castagnoli))
+	binary.BigEndian.PutUint32(buf[36:40], crc32.Checksum(buf[:36], castagnoli))
+	return append(buf, s.Value...), nil
+}
+
+// UnmarshalBinary decodes a share encoded by MarshalBinary, or a version 1 share, returning
+// ErrChecksum if the header or the value is corrupted
+func (s *Share) UnmarshalBinary(data []byte) error {
+	if len(data) < shareHeaderSizeV1 {
+		return fmt.Errorf("share header too short: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[0:4], []byte(ShareMagic)) {
+		return fmt.Errorf("not a share file: bad magic %q", data[0:4])
+	}
+
+	// The checksums end the header, after the epoch in version 2
+	size := ShareHeaderSize
+	switch data[4] {
+	case 1:
+		size = shareHeaderSizeV1
+	case ShareFormatVersion:
+		if len(data) < ShareHeaderSize {
+			return fmt.Errorf("share header too short: %d bytes", len(data))
+		}
+	default:
+		return fmt.Errorf("unsupported share format version %d", data[4])
+	}
+	if binary.BigEndian.Uint32(data[size-4:size]) != crc32.Checksum(data[:size-4], castagnoli) {
+		return fmt.Errorf("share header: %w", ErrChecksum)
+	}
+
+	length := binary.BigEndian.Uint32(data[8:12])
+	if uint64(len(data)-size) != uint64(length) {
+		return fmt.Errorf("share value has %d bytes, header says %d", len(data)-size, length)
+	}
+	value := data[size:]
+	if binary.BigEndian.Uint32(data[size-8:size-4]) != crc32.Checksum(value, castagnoli) {
+		return fmt.Errorf("share %d value: %w", data[6], ErrChecksum)
+	}
+
+	s.Threshold = int(data[5])
+	s.Index = int(data[6])
+	copy(s.ID[:], data[12:28])
+	s.Epoch = 0
+	if size == ShareHeaderSize {
+		s.Epoch = binary.BigEndian.Uint32(data[28:32])
+	}
+	s.Value = append([]byte(nil), value...)
+	if s.Threshold < MinThreshold || s.Index < 1 {
+		return fmt.Errorf("invalid threshold %d or index %d", s.Threshold, s.Index)
+	}
+	return nil
+}
+
+// WriteShare writes the encoded share to w
+func WriteShare(w io.Writer, share Share) error {
+	buf, err := share.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("failed to write share: %v", err)
+	}
+	return nil
+}
+
+// ReadShare reads an encoded share from r until EOF
+func ReadShare(r io.Reader) (Share, error) {
+	var share Share
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return share, fmt.Errorf("failed to read share: %v", err)
+	}
+	err = share.UnmarshalBinary(data)
+	return share, err
+}
+
+// WriteShareFiles writes every share to dir/ShareFileName(name, index), readable by the owner only
+func WriteShareFiles(dir, name string, shares []Share) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	for _, share := range shares {
+		buf, err := share.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, ShareFileName(name, share.Index))
+		if err := os.WriteFile(path, buf, 0600); err != nil {
+			return fmt.Errorf("failed to write share %d: %v", share.Index, err)
+		}
+	}
+	return nil
+}
+
+// ReadShareFile reads the share stored in a file
+func ReadShareFile(path string) (Share, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Share{}, err
+	}
+	var share Share
+	if err := share.UnmarshalBinary(data); err != nil {
+		return share, fmt.Errorf("%s: %w", path, err)
+	}
+	return share, nil
+}
+
+// FindShareFiles returns the share files of a directory, sorted by name
+func FindShareFiles(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.share[0-9][0-9][0-9]"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no share files found in %s", dir)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
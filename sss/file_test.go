@@ -0,0 +1,152 @@
+package sss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testShare returns a share with fixed fields
+func testShare() Share {
+	return Share{
+		ID:        SecretID{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		Threshold: 3,
+		Index:     2,
+		Epoch:     7,
+		Value:     []byte("share value"),
+	}
+}
+
+// encodeShare encodes a share field by field in the given format version
+func encodeShare(share Share, version byte) []byte {
+	buf := []byte(ShareMagic)
+	buf = append(buf, version, byte(share.Threshold), byte(share.Index), 0)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(share.Value)))
+	buf = append(buf, share.ID[:]...)
+	if version >= 2 {
+		buf = binary.BigEndian.AppendUint32(buf, share.Epoch)
+	}
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(share.Value, castagnoli))
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf, castagnoli))
+	return append(buf, share.Value...)
+}
+
+func TestShareFormat(t *testing.T) {
+	share := testShare()
+	data, err := share.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := encodeShare(share, 2); !bytes.Equal(data, want) {
+		t.Fatalf("encoded share\n%x, want\n%x", data, want)
+	}
+	if len(data) != ShareHeaderSize+len(share.Value) {
+		t.Errorf("encoded share has %d bytes, want %d", len(data), ShareHeaderSize+len(share.Value))
+	}
+
+	var decoded Share
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, share) {
+		t.Errorf("decoded %+v, want %+v", decoded, share)
+	}
+}
+
+// Shares written before refreshes existed are read as epoch 0
+func TestShareFormatVersion1(t *testing.T) {
+	share := testShare()
+	data := encodeShare(share, 1)
+	if len(data) != shareHeaderSizeV1+len(share.Value) {
+		t.Fatalf("version 1 share has %d bytes", len(data))
+	}
+
+	var decoded Share
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	share.Epoch = 0
+	if !reflect.DeepEqual(decoded, share) {
+		t.Errorf("decoded %+v, want %+v", decoded, share)
+	}
+
+	// Rewritten in the current version
+	data, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[4] != ShareFormatVersion {
+		t.Errorf("rewritten as version %d, want %d", data[4], ShareFormatVersion)
+	}
+}
+
+func TestShareFormatRejections(t *testing.T) {
+	valid := encodeShare(testShare(), 2)
+	tests := []struct {
+		name   string
+		modify func(data []byte) []byte
+		err    string
+	}{
+		{"flipped value byte", func(data []byte) []byte { data[len(data)-1] ^= 1; return data }, "share 2 value: checksum mismatch"},
+		{"flipped header byte", func(data []byte) []byte { data[20] ^= 1; return data }, "share header: checksum mismatch"},
+		{"flipped epoch", func(data []byte) []byte { data[31] ^= 1; return data }, "share header: checksum mismatch"},
+		{"bad magic", func(data []byte) []byte { data[0] = 'X'; return data }, "not a share file"},
+		{"unknown version", func(data []byte) []byte { data[4] = 3; return data }, "unsupported share format version 3"},
+		{"truncated value", func(data []byte) []byte { return data[:len(data)-1] }, "share value has 10 bytes, header says 11"},
+		{"truncated header", func(data []byte) []byte { return data[:20] }, "share header too short: 20 bytes"},
+		// A version 2 header cut to the size of version 1
+		{"truncated version 2 header", func(data []byte) []byte { return data[:shareHeaderSizeV1] }, "share header too short: 36 bytes"},
+		{"threshold 1", func(data []byte) []byte {
+			share := testShare()
+			share.Threshold = 1
+			return encodeShare(share, 2)
+		}, "invalid threshold 1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var share Share
+			err := share.UnmarshalBinary(test.modify(append([]byte(nil), valid...)))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+			if strings.Contains(test.err, "checksum") && !errors.Is(err, ErrChecksum) {
+				t.Errorf("got error %v, want ErrChecksum", err)
+			}
+		})
+	}
+}
+
+func TestShareFiles(t *testing.T) {
+	shares, err := Split(testSecret(12, 16), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := WriteShareFiles(dir, "secret", shares); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := FindShareFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 || filepath.Base(paths[2]) != "secret.share003" {
+		t.Fatalf("found share files %v", paths)
+	}
+	for i, path := range paths {
+		share, err := ReadShareFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(share, shares[i]) {
+			t.Errorf("%s holds %+v, want %+v", path, share, shares[i])
+		}
+	}
+	if _, err := FindShareFiles(t.TempDir()); err == nil {
+		t.Error("FindShareFiles found shares in an empty directory")
+	}
+}
@@ -0,0 +1,230 @@
+package sss
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"rs-encoder/util"
+	"sort"
+)
+
+// manifestDomain separates the signatures of manifests from other uses of the dealer key
+const manifestDomain = "rs-encoder sss manifest v1"
+
+// Commitment is the SHA-256 commitment to a share
+type Commitment [sha256.Size]byte
+
+// MarshalText encodes the commitment in hexadecimal
+func (c Commitment) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(c[:])), nil
+}
+
+// UnmarshalText decodes a commitment in hexadecimal
+func (c *Commitment) UnmarshalText(text []byte) error {
+	return util.DecodeHex(c[:], text, "commitment")
+}
+
+// Commit calculates the commitment of a share: SHA-256 over its secret ID, threshold,
+// index, epoch and value
+func Commit(share Share) Commitment {
+	h := sha256.New()
+	h.Write(share.ID[:])
+	var buf [12]byte
+	binary.BigEndian.PutUint16(buf[0:2], uint16(share.Threshold))
+	binary.BigEndian.PutUint16(buf[2:4], uint16(share.Index))
+	binary.BigEndian.PutUint32(buf[4:8], share.Epoch)
+	binary.BigEndian.PutUint32(buf[8:12], uint32(len(share.Value)))
+	h.Write(buf[:])
+	h.Write(share.Value)
+
+	var c Commitment
+	h.Sum(c[:0])
+	return c
+}
+
+// Manifest describes a split secret and commits to every share. It is signed by the
+// dealer, so a shareholder cannot forge a share that passes CheckShare.
+type Manifest struct {
+	ID          SecretID          `json:"secret_id"`
+	Threshold   int               `json:"threshold"`
+	Epoch       uint32            `json:"epoch"`
+	Length      int               `json:"length"`      // Secret size in bytes
+	Commitments []Commitment      `json:"commitments"` // Commitment of share i at i-1, zero if share i was dropped
+	PublicKey   ed25519.PublicKey `json:"public_key"`
+	Signature   []byte            `json:"signature"`
+}
+
+// ManifestFileName returns the name of the manifest file of the secret file name
+func ManifestFileName(name string) string {
+	return name + ".manifest.json"
+}
+
+// SplitVerifiable splits secret like Split and returns the manifest of the shares signed with
+// the dealer key
+func SplitVerifiable(secret []byte, n, t int, key ed25519.PrivateKey) ([]Share, *Manifest, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, nil, fmt.Errorf("invalid dealer key: %d bytes", len(key))
+	}
+	shares, err := Split(secret, n, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := NewManifest(shares, n, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shares, m, nil
+}
+
+// NewManifest creates the manifest of shares of one secret and epoch, out of n shares in
+// total, signed with the dealer key. The commitments of the missing shares are left zero,
+// so that no share matches them, e.g. for the shares dropped by a refresh.
+func NewManifest(shares []Share, n int, key ed25519.PrivateKey) (*Manifest, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid dealer key: %d bytes", len(key))
+	}
+	if err := checkShares(shares); err != nil {
+		return nil, err
+	}
+	first := shares[0]
+	if n < len(shares) || n > MaxShares {
+		return nil, fmt.Errorf("invalid number of shares %d for %d shares", n, len(shares))
+	}
+
+	m := &Manifest{
+		ID:          first.ID,
+		Threshold:   first.Threshold,
+		Epoch:       first.Epoch,
+		Length:      len(first.Value),
+		Commitments: make([]Commitment, n),
+		PublicKey:   key.Public().(ed25519.PublicKey),
+	}
+	for _, share := range shares {
+		if share.Index > n {
+			return nil, fmt.Errorf("share index %d is outside the %d shares of the manifest", share.Index, n)
+		}
+		m.Commitments[share.Index-1] = Commit(share)
+	}
+	m.Signature = ed25519.Sign(key, m.signedMessage())
+	return m, nil
+}
+
+// signedMessage returns the encoding of the manifest covered by the signature
+func (m *Manifest) signedMessage() []byte {
+	msg := []byte(manifestDomain)
+	msg = append(msg, m.ID[:]...)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(m.Threshold))
+	msg = binary.BigEndian.AppendUint32(msg, m.Epoch)
+	msg = binary.BigEndian.AppendUint32(msg, uint32(m.Length))
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(m.Commitments)))
+	for _, c := range m.Commitments {
+		msg = append(msg, c[:]...)
+	}
+	return append(msg, m.PublicKey...)
+}
+
+// Verify checks the signature of the manifest. publicKey is the dealer key the caller trusts;
+// if it is nil the key recorded in the manifest is used, which only protects against
+// corruption, not against a forged manifest.
+func (m *Manifest) Verify(publicKey ed25519.PublicKey) error {
+	if len(m.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid manifest public key: %d bytes", len(m.PublicKey))
+	}
+	if publicKey != nil && !publicKey.Equal(m.PublicKey) {
+		return fmt.Errorf("manifest is signed by a different dealer key")
+	}
+	if m.Threshold < MinThreshold || m.Threshold > len(m.Commitments) || len(m.Commitments) > MaxShares {
+		return fmt.Errorf("invalid manifest: threshold %d of %d shares", m.Threshold, len(m.Commitments))
+	}
+	if !ed25519.Verify(m.PublicKey, m.signedMessage(), m.Signature) {
+		return fmt.Errorf("invalid manifest signature")
+	}
+	return nil
+}
+
+// CheckShare checks that a share belongs to the secret of the manifest and matches its commitment
+func (m *Manifest) CheckShare(share Share) error {
+	if share.ID != m.ID {
+		return fmt.Errorf("share %d belongs to secret %s, not %s", share.Index, share.ID, m.ID)
+	}
+	if share.Index < 1 || share.Index > len(m.Commitments) {
+		return fmt.Errorf("share index %d is outside the %d shares of the manifest", share.Index, len(m.Commitments))
+	}
+	if share.Epoch != m.Epoch {
+		return fmt.Errorf("share %d is from epoch %d, the manifest from epoch %d", share.Index, share.Epoch, m.Epoch)
+	}
+	if share.Threshold != m.Threshold || len(share.Value) != m.Length {
+		return fmt.Errorf("share %d has threshold %d and %d bytes, expected %d and %d",
+			share.Index, share.Threshold, len(share.Value), m.Threshold, m.Length)
+	}
+	if Commit(share) != m.Commitments[share.Index-1] {
+		return fmt.Errorf("share %d does not match its commitment", share.Index)
+	}
+	return nil
+}
+
+// CombineVerified recovers the secret from shares checked against a signed manifest. Shares
+// failing their commitment are excluded, the others are combined with CombineDetect. The
+// indices of all excluded or inconsistent shares are returned, sorted.
+func CombineVerified(shares []Share, m *Manifest, publicKey ed25519.PublicKey) ([]byte, []int, error) {
+	if err := m.Verify(publicKey); err != nil {
+		return nil, nil, err
+	}
+
+	var valid []Share
+	var rejected []int
+	for _, share := range shares {
+		if err := m.CheckShare(share); err != nil {
+			rejected = append(rejected, share.Index)
+			continue
+		}
+		valid = append(valid, share)
+	}
+	if len(valid) < m.Threshold {
+		return nil, rejected, fmt.Errorf("not enough valid shares: have %d, need %d, shares %v failed their commitments",
+			len(valid), m.Threshold, rejected)
+	}
+
+	secret, inconsistent, err := CombineDetect(valid)
+	if err != nil {
+		return nil, rejected, err
+	}
+	rejected = append(rejected, inconsistent...)
+	sort.Ints(rejected)
+	return secret, rejected, nil
+}
+
+// EncodeManifest encodes the manifest as the JSON of a manifest file
+func EncodeManifest(m *Manifest) ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteManifest writes the manifest to a JSON file
+func WriteManifest(path string, m *Manifest) error {
+	data, err := EncodeManifest(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadManifest reads a manifest from a JSON file, the signature is not verified
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+	return &m, nil
+}
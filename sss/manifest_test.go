@@ -0,0 +1,143 @@
+package sss
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testDealerKey returns a dealer key derived from seed
+func testDealerKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func TestCombineVerified(t *testing.T) {
+	key := testDealerKey(1)
+	secret := testSecret(7, 32)
+	shares, manifest, err := SplitVerifiable(secret, 5, 3, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	got, rejected, err := CombineVerified(shares, manifest, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, secret) || len(rejected) != 0 {
+		t.Errorf("got %x with rejected shares %v, want the secret and none", got, rejected)
+	}
+
+	// A tampered share fails its commitment and is left out
+	got, rejected, err = CombineVerified(corrupt(shares, 1), manifest, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, secret) || !reflect.DeepEqual(rejected, []int{2}) {
+		t.Errorf("got %x with rejected shares %v, want the secret and [2]", got, rejected)
+	}
+
+	// Even with exactly the threshold, where CombineDetect could not tell
+	if _, rejected, err = CombineVerified(corrupt(shares[:3], 0), manifest, publicKey); err == nil || !reflect.DeepEqual(rejected, []int{1}) {
+		t.Errorf("got rejected shares %v and error %v, want [1] and not enough valid shares", rejected, err)
+	}
+
+	// A share relabelled with another index does not match the commitment of that index
+	relabelled := append([]Share(nil), shares...)
+	relabelled[0].Index, relabelled[1].Index = 2, 1
+	if _, rejected, err = CombineVerified(relabelled, manifest, publicKey); err != nil || !reflect.DeepEqual(rejected, []int{1, 2}) {
+		t.Errorf("got rejected shares %v and error %v, want [1 2]", rejected, err)
+	}
+}
+
+func TestManifestVerifyRejectsTampering(t *testing.T) {
+	key := testDealerKey(1)
+	shares, manifest, err := SplitVerifiable(testSecret(8, 16), 4, 2, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := key.Public().(ed25519.PublicKey)
+	if err := manifest.Verify(publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *Manifest)
+		err    string
+	}{
+		{"threshold", func(m *Manifest) { m.Threshold = 3 }, "invalid manifest signature"},
+		{"epoch", func(m *Manifest) { m.Epoch = 1 }, "invalid manifest signature"},
+		{"length", func(m *Manifest) { m.Length = 15 }, "invalid manifest signature"},
+		{"secret ID", func(m *Manifest) { m.ID[0] ^= 1 }, "invalid manifest signature"},
+		{"commitment", func(m *Manifest) { m.Commitments[2][0] ^= 1 }, "invalid manifest signature"},
+		{"dropped commitment", func(m *Manifest) { m.Commitments = m.Commitments[:3] }, "invalid manifest signature"},
+		{"signature", func(m *Manifest) { m.Signature[0] ^= 1 }, "invalid manifest signature"},
+		{"threshold of 1", func(m *Manifest) { m.Threshold = 1 }, "invalid manifest: threshold 1 of 4 shares"},
+		{
+			// Re-signed by another dealer: consistent, but not the trusted key
+			"other dealer",
+			func(m *Manifest) {
+				other, err := NewManifest(shares, 4, testDealerKey(2))
+				if err != nil {
+					t.Fatal(err)
+				}
+				*m = *other
+			},
+			"signed by a different dealer key",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tampered := *manifest
+			tampered.Commitments = append([]Commitment(nil), manifest.Commitments...)
+			tampered.Signature = append([]byte(nil), manifest.Signature...)
+			test.modify(&tampered)
+			if err := tampered.Verify(publicKey); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+			if _, _, err := CombineVerified(shares, &tampered, publicKey); err == nil {
+				t.Error("CombineVerified accepted the tampered manifest")
+			}
+		})
+	}
+
+	// Without a trusted key a manifest re-signed by anyone passes: only corruption is detected
+	forged, err := NewManifest(shares, 4, testDealerKey(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := forged.Verify(nil); err != nil {
+		t.Errorf("re-signed manifest without a trusted key: %v", err)
+	}
+}
+
+func TestManifestFileRoundTrip(t *testing.T) {
+	key := testDealerKey(3)
+	shares, manifest, err := SplitVerifiable(testSecret(9, 16), 3, 2, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), ManifestFileName("secret"))
+	if err := WriteManifest(path, manifest); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, manifest) {
+		t.Errorf("read manifest %+v, want %+v", read, manifest)
+	}
+	if err := read.Verify(key.Public().(ed25519.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+	for _, share := range shares {
+		if err := read.CheckShare(share); err != nil {
+			t.Errorf("share %d: %v", share.Index, err)
+		}
+	}
+}
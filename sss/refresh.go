@@ -0,0 +1,155 @@
+package sss
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+)
+
+// RefreshUpdate is the contribution of one shareholder to a proactive refresh: the values,
+// at the index of every participating shareholder, of a random polynomial of degree
+// threshold-1 whose constant term is zero. Adding the updates of all participants to a
+// share gives a share of the same secret on a new random polynomial, so the shares from
+// before the refresh, leaked or not, are of no use with the refreshed ones.
+//
+// The update for each shareholder must reach it over a private channel: together with
+// the old share it reveals the new one.
+type RefreshUpdate struct {
+	ID     SecretID
+	Epoch  uint32         // Epoch of the shares being refreshed
+	From   int            // Index of the shareholder that made the update
+	Deltas map[int][]byte // Value to add to the share of each participating index
+}
+
+// NewRefreshUpdate makes the contribution of the holder of share to a refresh among the
+// shareholders of indices, which must include the holder. The coefficients of the
+// polynomial are drawn from crypto/rand.
+func NewRefreshUpdate(share Share, indices []int) (*RefreshUpdate, error) {
+	if err := checkParticipants(share, indices); err != nil {
+		return nil, err
+	}
+
+	t := share.Threshold
+	coefficients := make([]byte, (t-1)*len(share.Value))
+	if _, err := rand.Read(coefficients); err != nil {
+		return nil, fmt.Errorf("failed to generate coefficients: %v", err)
+	}
+	defer clear(coefficients)
+
+	zero := make([]byte, len(share.Value))
+	update := &RefreshUpdate{ID: share.ID, Epoch: share.Epoch, From: share.Index, Deltas: make(map[int][]byte)}
+	for _, index := range indices {
+		update.Deltas[index] = evaluateShare(zero, coefficients, t, byte(index))
+	}
+	return update, nil
+}
+
+// ApplyRefresh adds the updates of all participants of a refresh to share and returns the
+// share of the next epoch. Every participant must have contributed exactly one update for
+// the same set of participants, or the refreshed shares would not combine.
+func ApplyRefresh(share Share, updates []*RefreshUpdate) (Share, error) {
+	indices := make([]int, 0, len(updates))
+	for _, update := range updates {
+		indices = append(indices, update.From)
+	}
+	if err := checkParticipants(share, indices); err != nil {
+		return Share{}, err
+	}
+
+	value := append([]byte(nil), share.Value...)
+	for _, update := range updates {
+		if update.ID != share.ID || update.Epoch != share.Epoch {
+			return Share{}, fmt.Errorf("update from share %d is for secret %s epoch %d, not %s epoch %d",
+				update.From, update.ID, update.Epoch, share.ID, share.Epoch)
+		}
+		if len(update.Deltas) != len(indices) {
+			return Share{}, fmt.Errorf("update from share %d covers %d shares, expected %d",
+				update.From, len(update.Deltas), len(indices))
+		}
+		for _, index := range indices {
+			if _, ok := update.Deltas[index]; !ok {
+				return Share{}, fmt.Errorf("update from share %d does not cover share %d", update.From, index)
+			}
+		}
+		delta := update.Deltas[share.Index]
+		if len(delta) != len(value) {
+			return Share{}, fmt.Errorf("update from share %d has %d bytes, expected %d", update.From, len(delta), len(value))
+		}
+		for b, d := range delta {
+			value[b] = field.Add(value[b], d)
+		}
+	}
+
+	share.Epoch++
+	share.Value = value
+	return share, nil
+}
+
+// Refresh runs a refresh among the holders of shares locally: every holder makes an update
+// and applies the updates of all holders. It returns the shares of the next epoch, in the
+// order of shares. Shares not taking part can no longer be combined with the refreshed ones.
+func Refresh(shares []Share) ([]Share, error) {
+	if err := checkShares(shares); err != nil {
+		return nil, err
+	}
+
+	indices := make([]int, len(shares))
+	for i, share := range shares {
+		indices[i] = share.Index
+	}
+
+	updates := make([]*RefreshUpdate, len(shares))
+	for i, share := range shares {
+		update, err := NewRefreshUpdate(share, indices)
+		if err != nil {
+			return nil, err
+		}
+		updates[i] = update
+	}
+
+	refreshed := make([]Share, len(shares))
+	for i, share := range shares {
+		next, err := ApplyRefresh(share, updates)
+		if err != nil {
+			return nil, err
+		}
+		refreshed[i] = next
+	}
+	for _, update := range updates {
+		for _, delta := range update.Deltas {
+			clear(delta)
+		}
+	}
+	return refreshed, nil
+}
+
+// checkParticipants checks that the indices of a refresh are distinct, include the index of
+// share, and are enough to recover the secret afterwards
+func checkParticipants(share Share, indices []int) error {
+	if share.Threshold < MinThreshold || share.Threshold > MaxShares || share.Index < 1 || share.Index > MaxShares {
+		return fmt.Errorf("invalid threshold %d or index %d", share.Threshold, share.Index)
+	}
+	if len(indices) < share.Threshold {
+		return fmt.Errorf("not enough shares in the refresh: have %d, need %d", len(indices), share.Threshold)
+	}
+	if share.Epoch == ^uint32(0) {
+		return fmt.Errorf("share %d is at the last epoch %d", share.Index, share.Epoch)
+	}
+
+	sorted := append([]int(nil), indices...)
+	sort.Ints(sorted)
+	found := false
+	for i, index := range sorted {
+		if index < 1 || index > MaxShares {
+			return fmt.Errorf("invalid share index %d", index)
+		}
+		if i > 0 && sorted[i-1] == index {
+			return fmt.Errorf("duplicate share %d", index)
+		}
+		found = found || index == share.Index
+	}
+	if !found {
+		return fmt.Errorf("share %d does not take part in the refresh", share.Index)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package sss
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRefresh(t *testing.T) {
+	secret := testSecret(10, 32)
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := Refresh(shares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, share := range refreshed {
+		if share.Epoch != 1 || share.Index != shares[i].Index || share.ID != shares[i].ID {
+			t.Fatalf("refreshed share %d is %+v", i, share)
+		}
+		if bytes.Equal(share.Value, shares[i].Value) {
+			t.Errorf("share %d is unchanged", share.Index)
+		}
+	}
+
+	// Any threshold of the new shares still recover the secret
+	for _, positions := range subsets(5, 3) {
+		got, err := Combine(pick(refreshed, positions))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Fatalf("refreshed shares %v: wrong secret", positions)
+		}
+	}
+
+	// Old and new shares do not combine, even with the epochs rewritten
+	mixed := []Share{shares[0], shares[1], refreshed[2]}
+	if _, err := Combine(mixed); err == nil || !strings.Contains(err.Error(), "shares from before and after a refresh cannot be combined") {
+		t.Errorf("mixed epochs: got error %v", err)
+	}
+	mixed[2].Epoch = 0
+	if got, err := Combine(mixed); err != nil || bytes.Equal(got, secret) {
+		t.Errorf("mixed values: got %x, %v, want a wrong secret", got, err)
+	}
+
+	// A second refresh of a subset moves on again; the share left out is stale
+	again, err := Refresh(refreshed[1:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].Epoch != 2 {
+		t.Errorf("second refresh at epoch %d, want 2", again[0].Epoch)
+	}
+	if got, err := Combine(again); err != nil || !bytes.Equal(got, secret) {
+		t.Errorf("second refresh: got %x, %v", got, err)
+	}
+	if _, err := Combine([]Share{again[0], again[1], refreshed[4]}); err == nil {
+		t.Error("combined a share left out of the refresh")
+	}
+}
+
+// The distributed protocol: every holder makes an update and applies the updates it receives
+func TestRefreshUpdates(t *testing.T) {
+	secret := testSecret(11, 16)
+	shares, err := Split(secret, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices := []int{1, 3, 4}
+	participants := []Share{shares[0], shares[2], shares[3]}
+
+	updates := make([]*RefreshUpdate, len(participants))
+	for i, share := range participants {
+		if updates[i], err = NewRefreshUpdate(share, indices); err != nil {
+			t.Fatal(err)
+		}
+		// The update polynomial has a zero constant term, so the deltas alone recover nothing
+		deltas := []Share{
+			{ID: share.ID, Threshold: 2, Index: 1, Value: updates[i].Deltas[1]},
+			{ID: share.ID, Threshold: 2, Index: 3, Value: updates[i].Deltas[3]},
+		}
+		if zero, err := Combine(deltas); err != nil || !bytes.Equal(zero, make([]byte, 16)) {
+			t.Errorf("update of share %d does not hide zero: %x, %v", share.Index, zero, err)
+		}
+	}
+
+	next := make([]Share, len(participants))
+	for i, share := range participants {
+		if next[i], err = ApplyRefresh(share, updates); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := Combine(next[1:]); err != nil || !bytes.Equal(got, secret) {
+		t.Errorf("got %x, %v, want the secret", got, err)
+	}
+
+	tests := []struct {
+		name    string
+		share   Share
+		updates []*RefreshUpdate
+		err     string
+	}{
+		{"missing update", participants[0], updates[:2], "update from share 1 covers 3 shares, expected 2"},
+		{"share not taking part", shares[1], updates, "share 2 does not take part in the refresh"},
+		{"update of another epoch", next[0], updates, "is for secret"},
+		{"too few participants", participants[0], updates[:1], "not enough shares in the refresh: have 1, need 2"},
+	}
+	for _, test := range tests {
+		if _, err := ApplyRefresh(test.share, test.updates); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.err)
+		}
+	}
+	if _, err := NewRefreshUpdate(shares[0], []int{1, 1, 2}); err == nil || !strings.Contains(err.Error(), "duplicate share 1") {
+		t.Errorf("duplicate participant: got error %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package sss implements Shamir's secret sharing over GF(2^8).
+//
+// Every byte of the secret is the constant term of a random polynomial of degree
+// threshold-1; share i holds the values of the polynomials at x = i. Any threshold
+// shares recover the secret by Lagrange interpolation at x = 0, fewer reveal nothing.
+package sss
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"rs-encoder/gf"
+	"rs-encoder/util"
+)
+
+// MaxShares is the largest number of shares: the non-zero elements of GF(2^8)
+const MaxShares = 255
+
+// MinThreshold is the smallest threshold: with a threshold of 1 every share holds the secret
+// in the clear
+const MinThreshold = 2
+
+// PrimitivePoly is the primitive polynomial of the field the shares are calculated in
+const PrimitivePoly = 0x1d
+
+// field is GF(2^8) with PrimitivePoly. Its operations run in constant time, so that the
+// secret and the coefficients do not leak through timing.
+var field = gf.NewConstantTimeGF(PrimitivePoly)
+
+// SecretID identifies the secret a share belongs to, so that shares of different
+// secrets are not combined
+type SecretID [16]byte
+
+// String returns the secret ID in hexadecimal
+func (id SecretID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// MarshalText encodes the secret ID in hexadecimal
+func (id SecretID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText decodes a secret ID in hexadecimal
+func (id *SecretID) UnmarshalText(text []byte) error {
+	return util.DecodeHex(id[:], text, "secret ID")
+}
+
+// Share is one share of a secret
+type Share struct {
+	ID        SecretID // Random ID shared by all shares of a secret
+	Threshold int      // Number of shares needed to recover the secret
+	Index     int      // Evaluation point of the share, 1 to MaxShares
+	Epoch     uint32   // Number of refreshes since the split, shares of different epochs do not combine
+	Value     []byte   // The polynomials evaluated at Index, one byte per secret byte
+}
+
+// Split divides secret into n shares, any t of which recover it. The polynomial
+// coefficients are drawn from crypto/rand.
+func Split(secret []byte, n, t int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret is empty")
+	}
+	if t < MinThreshold || t > n || n > MaxShares {
+		return nil, fmt.Errorf("invalid threshold %d of %d shares: need %d <= threshold <= shares <= %d", t, n, MinThreshold, MaxShares)
+	}
+
+	var id SecretID
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate secret ID: %v", err)
+	}
+	// coefficients[d*len(secret)+b] is the coefficient of x^(d+1) for secret byte b
+	coefficients := make([]byte, (t-1)*len(secret))
+	if _, err := rand.Read(coefficients); err != nil {
+		return nil, fmt.Errorf("failed to generate coefficients: %v", err)
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		value := evaluateShare(secret, coefficients, t, byte(i+1))
+		shares[i] = Share{ID: id, Threshold: t, Index: i + 1, Value: value}
+	}
+
+	clear(coefficients)
+	return shares, nil
+}
+
+// evaluateShare evaluates at x the polynomials of degree t-1 with the constant terms
+// constants and the other coefficients laid out as in Split
+func evaluateShare(constants, coefficients []byte, t int, x byte) []byte {
+	value := make([]byte, len(constants))
+	for b, c := range constants {
+		// Horner's method from the highest coefficient down to the constant term
+		y := byte(0)
+		for d := t - 2; d >= 0; d-- {
+			y = field.Add(field.Mul(y, x), coefficients[d*len(constants)+b])
+		}
+		value[b] = field.Add(field.Mul(y, x), c)
+	}
+	return value
+}
+
+// Combine recovers the secret from at least Threshold shares of the same secret.
+// Only the first Threshold shares are used.
+func Combine(shares []Share) ([]byte, error) {
+	if err := checkShares(shares); err != nil {
+		return nil, err
+	}
+	first := shares[0]
+
+	// Lagrange interpolation at x = 0: secret = sum(y_j * L_j(0))
+	used := shares[:first.Threshold]
+	secret := make([]byte, len(first.Value))
+	for j, share := range used {
+		basis := lagrangeBasisAtZero(used, j)
+		for b, y := range share.Value {
+			secret[b] = field.Add(secret[b], field.Mul(y, basis))
+		}
+	}
+	return secret, nil
+}
+
+// checkShares checks that there are at least Threshold distinct shares of the same secret and epoch
+func checkShares(shares []Share) error {
+	if len(shares) == 0 {
+		return fmt.Errorf("no shares")
+	}
+	first := shares[0]
+	if first.Threshold < MinThreshold || first.Threshold > MaxShares {
+		return fmt.Errorf("invalid threshold %d", first.Threshold)
+	}
+
+	seen := make(map[int]bool)
+	for _, share := range shares {
+		if share.ID != first.ID {
+			return fmt.Errorf("share %d belongs to secret %s, not %s", share.Index, share.ID, first.ID)
+		}
+		if share.Epoch != first.Epoch {
+			return fmt.Errorf("share %d is from epoch %d, not %d: shares from before and after a refresh cannot be combined",
+				share.Index, share.Epoch, first.Epoch)
+		}
+		if share.Threshold != first.Threshold || len(share.Value) != len(first.Value) {
+			return fmt.Errorf("share %d has threshold %d and %d bytes, expected %d and %d",
+				share.Index, share.Threshold, len(share.Value), first.Threshold, len(first.Value))
+		}
+		if share.Index < 1 || share.Index > MaxShares {
+			return fmt.Errorf("invalid share index %d", share.Index)
+		}
+		if seen[share.Index] {
+			return fmt.Errorf("duplicate share %d", share.Index)
+		}
+		seen[share.Index] = true
+	}
+	if len(shares) < first.Threshold {
+		return fmt.Errorf("not enough shares: have %d, need %d", len(shares), first.Threshold)
+	}
+	return nil
+}
+
+// lagrangeBasisAtZero calculates L_j(0) = prod (0 - x_k) / (x_j - x_k) over k != j
+func lagrangeBasisAtZero(shares []Share, j int) byte {
+	xj := byte(shares[j].Index)
+	basis := byte(1)
+	for k, share := range shares {
+		if k == j {
+			continue
+		}
+		xk := byte(share.Index)
+		basis = field.Mul(basis, field.Div(field.Sub(0, xk), field.Sub(xj, xk)))
+	}
+	return basis
+}
@@ -0,0 +1,168 @@
+package sss
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// testSecret returns a reproducible random secret of n bytes
+func testSecret(seed int64, n int) []byte {
+	secret := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(secret)
+	return secret
+}
+
+// subsets returns every subset of size k of the indices 0..n-1
+func subsets(n, k int) [][]int {
+	if k == 0 {
+		return [][]int{{}}
+	}
+	var result [][]int
+	for last := k - 1; last < n; last++ {
+		for _, subset := range subsets(last, k-1) {
+			result = append(result, append(subset, last))
+		}
+	}
+	return result
+}
+
+// pick returns the shares at the given positions
+func pick(shares []Share, positions []int) []Share {
+	picked := make([]Share, len(positions))
+	for i, p := range positions {
+		picked[i] = shares[p]
+	}
+	return picked
+}
+
+func TestSplitCombine(t *testing.T) {
+	tests := []struct{ n, t int }{{2, 2}, {3, 2}, {5, 3}, {6, 6}, {7, 4}}
+	secret := testSecret(1, 32)
+
+	for _, test := range tests {
+		shares, err := Split(secret, test.n, test.t)
+		if err != nil {
+			t.Fatalf("Split(%d, %d): %v", test.n, test.t, err)
+		}
+		if len(shares) != test.n {
+			t.Fatalf("Split(%d, %d) returned %d shares", test.n, test.t, len(shares))
+		}
+		for i, share := range shares {
+			if share.Index != i+1 || share.Threshold != test.t || share.ID != shares[0].ID || len(share.Value) != len(secret) {
+				t.Fatalf("Split(%d, %d): share %d is %+v", test.n, test.t, i, share)
+			}
+		}
+
+		// Any t shares or more, in any order, recover the secret
+		for k := test.t; k <= test.n; k++ {
+			for _, positions := range subsets(test.n, k) {
+				subset := pick(shares, positions)
+				rand.New(rand.NewSource(int64(k))).Shuffle(len(subset), func(i, j int) { subset[i], subset[j] = subset[j], subset[i] })
+				got, err := Combine(subset)
+				if err != nil {
+					t.Fatalf("%d of %d, shares %v: %v", test.t, test.n, positions, err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Fatalf("%d of %d, shares %v: wrong secret", test.t, test.n, positions)
+				}
+			}
+		}
+	}
+}
+
+func TestCombineNeedsThresholdShares(t *testing.T) {
+	shares, err := Split(testSecret(2, 16), 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, positions := range subsets(5, 2) {
+		if _, err := Combine(pick(shares, positions)); err == nil || !strings.Contains(err.Error(), "not enough shares: have 2, need 3") {
+			t.Errorf("shares %v: got error %v, want not enough shares", positions, err)
+		}
+	}
+	if _, err := Combine(nil); err == nil {
+		t.Error("Combine accepted no shares")
+	}
+}
+
+func TestCombineRejectsMismatchedShares(t *testing.T) {
+	shares, err := Split(testSecret(3, 16), 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Split(testSecret(4, 16), 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		shares []Share
+		err    string
+	}{
+		{"other secret", []Share{shares[0], other[1]}, "belongs to secret"},
+		{"duplicate share", []Share{shares[1], shares[1]}, "duplicate share 2"},
+		{"other threshold", []Share{shares[0], {ID: shares[0].ID, Threshold: 3, Index: 2, Value: shares[1].Value}}, "has threshold 3"},
+		{"shorter value", []Share{shares[0], {ID: shares[0].ID, Threshold: 2, Index: 2, Value: shares[1].Value[:8]}}, "and 8 bytes"},
+		{"index 0", []Share{shares[0], {ID: shares[0].ID, Threshold: 2, Index: 0, Value: shares[1].Value}}, "invalid share index 0"},
+	}
+	for _, test := range tests {
+		if _, err := Combine(test.shares); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestSplitRejectsInvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret []byte
+		n, t   int
+		err    string
+	}{
+		// Every share would hold the secret in the clear
+		{"threshold 1", []byte("secret"), 3, 1, "invalid threshold 1 of 3 shares"},
+		{"threshold 0", []byte("secret"), 3, 0, "invalid threshold 0 of 3 shares"},
+		{"threshold above the shares", []byte("secret"), 3, 4, "invalid threshold 4 of 3 shares"},
+		{"too many shares", []byte("secret"), MaxShares + 1, 2, "invalid threshold 2 of 256 shares"},
+		{"empty secret", nil, 3, 2, "secret is empty"},
+	}
+	for _, test := range tests {
+		if _, err := Split(test.secret, test.n, test.t); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.err)
+		}
+	}
+
+	// The largest split still recovers the secret
+	shares, err := Split([]byte("secret"), MaxShares, MaxShares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Combine(shares); err != nil || string(got) != "secret" {
+		t.Errorf("%d of %d shares: got %q, %v", MaxShares, MaxShares, got, err)
+	}
+}
+
+// Below the threshold the shares say nothing about the secret: for any t-1 shares, every
+// candidate secret is consistent with them
+func TestSharesBelowThresholdHideSecret(t *testing.T) {
+	shares, err := Split([]byte{0x42}, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every value of the third share completes the first two into a different secret
+	seen := make(map[byte]bool)
+	for y := 0; y < 256; y++ {
+		third := Share{ID: shares[0].ID, Threshold: 3, Index: 3, Value: []byte{byte(y)}}
+		got, err := Combine([]Share{shares[0], shares[1], third})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[got[0]] = true
+	}
+	if len(seen) != 256 {
+		t.Errorf("two shares rule out %d of 256 secrets", 256-len(seen))
+	}
+}
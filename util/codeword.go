@@ -0,0 +1,295 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"strconv"
+	"strings"
+)
+
+// CodewordFormatVersion is the version of the JSON codeword format written by WriteCodewordToJSON
+const CodewordFormatVersion = 1
+
+// Codeword is the versioned, self-describing JSON representation of a codeword or of
+// a subset of its shards. Every shard carries its explicit index.
+type Codeword struct {
+	FormatVersion int `json:"format_version"`
+	CodeParams
+	EvalPoints []string        `json:"eval_points,omitempty"` // Evaluation point of every shard index
+	Shards     []CodewordShard `json:"shards"`
+}
+
+// CodewordShard is one shard of a codeword
+type CodewordShard struct {
+	Index int    `json:"index"`
+	Value string `json:"value"`
+}
+
+// legacyCodeword holds the fields of the files written before the versioned format:
+// encoded.json ("message" and "encoded", with null for missing shards) and decoder
+// input ("message" with either "indices" or "start_index")
+type legacyCodeword struct {
+	CodeParams
+	FormatVersion int       `json:"format_version"`
+	Message       []string  `json:"message"`
+	Encoded       []*string `json:"encoded"`
+	Indices       []int     `json:"indices"`
+	StartIndex    int       `json:"start_index"`
+}
+
+// NewCodeword builds a codeword from shard values and their indices
+func NewCodeword(params CodeParams, evalPoints []byte, values []byte, indices []int) *Codeword {
+	codeword := &Codeword{
+		FormatVersion: CodewordFormatVersion,
+		CodeParams:    params,
+		EvalPoints:    HexStrings(evalPoints),
+		Shards:        make([]CodewordShard, len(values)),
+	}
+	for i, value := range values {
+		codeword.Shards[i] = CodewordShard{Index: indices[i], Value: fmt.Sprintf("0x%02x", value)}
+	}
+	return codeword
+}
+
+// ShardValues returns the shard values and their indices
+func (c *Codeword) ShardValues() ([]byte, []int, error) {
+	values := make([]byte, len(c.Shards))
+	indices := make([]int, len(c.Shards))
+	seen := make(map[int]bool)
+	for i, shard := range c.Shards {
+		value, err := parseHexByte(shard.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("shard %d: %v", shard.Index, err)
+		}
+		if shard.Index < 0 || seen[shard.Index] {
+			return nil, nil, fmt.Errorf("invalid or duplicate shard index %d", shard.Index)
+		}
+		seen[shard.Index] = true
+		values[i] = value
+		indices[i] = shard.Index
+	}
+	return values, indices, nil
+}
+
+// EvalPointValues returns the evaluation points recorded in the codeword, nil if none are recorded
+func (c *Codeword) EvalPointValues() ([]byte, error) {
+	if len(c.EvalPoints) == 0 {
+		return nil, nil
+	}
+	points := make([]byte, len(c.EvalPoints))
+	for i, point := range c.EvalPoints {
+		value, err := parseHexByte(point)
+		if err != nil {
+			return nil, fmt.Errorf("evaluation point %d: %v", i, err)
+		}
+		points[i] = value
+	}
+	return points, nil
+}
+
+// LoadCodeword reads a codeword from a JSON file. Besides the versioned format it accepts
+// the legacy formats: encoded.json with "message" and "encoded", where the encoded shards
+// start at index 0, missing shards are null and the message length is the number of data
+// shards, and decoder input with "message" holding the shards at "indices", or consecutive
+// shards from "start_index" when no indices are given.
+func LoadCodeword(filePath string) (*Codeword, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	var legacy legacyCodeword
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %v", err)
+	}
+
+	switch {
+	case legacy.FormatVersion > CodewordFormatVersion:
+		return nil, fmt.Errorf("unsupported codeword format version %d", legacy.FormatVersion)
+
+	case legacy.FormatVersion > 0:
+		var codeword Codeword
+		if err := json.Unmarshal(data, &codeword); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %v", err)
+		}
+		return &codeword, nil
+
+	case legacy.Encoded != nil:
+		// Legacy encoded.json: the full codeword with null for missing shards, the message is its data part
+		codeword := &Codeword{CodeParams: legacy.CodeParams}
+		if codeword.DataShards == 0 {
+			codeword.DataShards = len(legacy.Message)
+		}
+		if codeword.ParityShards == 0 && codeword.DataShards > 0 {
+			codeword.ParityShards = len(legacy.Encoded) - codeword.DataShards
+		}
+		for i, value := range legacy.Encoded {
+			if value != nil {
+				codeword.Shards = append(codeword.Shards, CodewordShard{Index: i, Value: *value})
+			}
+		}
+		return codeword, nil
+
+	case legacy.Message != nil:
+		// Legacy decoder input: the shards at the given indices, or consecutive shards from start_index.
+		// Parameters are left to the caller's defaults.
+		if legacy.Indices != nil && len(legacy.Indices) != len(legacy.Message) {
+			return nil, fmt.Errorf("got %d shards but %d indices", len(legacy.Message), len(legacy.Indices))
+		}
+		codeword := &Codeword{CodeParams: legacy.CodeParams}
+		for i, value := range legacy.Message {
+			index := legacy.StartIndex + i
+			if legacy.Indices != nil {
+				index = legacy.Indices[i]
+			}
+			codeword.Shards = append(codeword.Shards, CodewordShard{Index: index, Value: value})
+		}
+		return codeword, nil
+	}
+
+	return nil, fmt.Errorf("no shards found: expected \"shards\", \"encoded\" or \"message\"")
+}
+
+// WriteCodewordToJSON writes a codeword to a JSON file in the versioned format
+func WriteCodewordToJSON(filePath string, codeword *Codeword) error {
+	codeword.FormatVersion = CodewordFormatVersion
+	return WriteJSON(filePath, codeword)
+}
+
+// parseHexByte parses a byte written as "0x1f" or "1f"
+func parseHexByte(hexStr string) (byte, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(hexStr, "0x"), 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse hex value: %v", err)
+	}
+	return byte(value), nil
+}
+
+// DecodedData is the JSON result of decoding a codeword
+type DecodedData struct {
+	CodeParams
+	EncodedShards   []string        `json:"encoded_shards"`
+	ShardIndices    []int           `json:"shard_indices"`
+	DecodedData     []string        `json:"decoded_data"`
+	RecoveredShards []CodewordShard `json:"recovered_shards"` // Missing shards of the codeword, recalculated from the input
+}
+
+// DecodedCodeword is a codeword reconstructed from some of its shards
+type DecodedCodeword struct {
+	Values    []byte // Input shard values
+	Indices   []int  // Input shard indices
+	Shards    []byte // Every shard of the codeword
+	Recovered []int  // Indices of the shards missing from the input
+	Message   []byte // The data shards
+}
+
+// EncodeMessage encodes every message byte as one data shard and returns the whole codeword
+func EncodeMessage(message []byte, codec CodecID, primitivePoly byte, parityShards int, opts ...rs.Option) (*Codeword, error) {
+	if err := ValidateShardCounts(len(message), parityShards); err != nil {
+		return nil, err
+	}
+
+	coder, err := rs.NewCodec(codec.String(), gf.NewGF(primitivePoly), len(message), parityShards, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every shard is a single byte
+	shards := make([][]byte, len(message)+parityShards)
+	for i, value := range message {
+		shards[i] = []byte{value}
+	}
+	if err := coder.Encode(shards); err != nil {
+		return nil, err
+	}
+
+	encoded := make([]byte, len(shards))
+	indices := make([]int, len(shards))
+	for i, shard := range shards {
+		encoded[i] = shard[0]
+		indices[i] = i
+	}
+	params := NewCodeParams(codec, len(message), parityShards, primitivePoly)
+	return NewCodeword(params, rs.EvaluationPoints(len(shards)), encoded, indices), nil
+}
+
+// DecodeCodeword reconstructs a codeword with the given parameters from any pattern of at
+// least dataShards of its shards. Shards with indices beyond dataShards+parityShards, added
+// by extending the codeword, are accepted.
+func DecodeCodeword(codeword *Codeword, codec CodecID, primitivePoly byte, dataShards, parityShards int, opts ...rs.Option) (*DecodedCodeword, error) {
+	values, indices, err := codeword.ShardValues()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) < dataShards {
+		return nil, fmt.Errorf("not enough shards: have %d, need %d", len(values), dataShards)
+	}
+
+	totalShards := dataShards + parityShards
+	for _, index := range indices {
+		if index+1 > totalShards {
+			totalShards = index + 1
+		}
+	}
+	if totalShards > rs.MaxTotalShards {
+		return nil, fmt.Errorf("shard indices exceed the maximum of %d shards", rs.MaxTotalShards)
+	}
+
+	// Shards added by extending the codeword are parity shards of a longer code
+	coder, err := rs.NewCodec(codec.String(), gf.NewGF(primitivePoly), dataShards, totalShards-dataShards, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The recorded evaluation points must be the ones the codecs use
+	recordedPoints, err := codeword.EvalPointValues()
+	if err != nil {
+		return nil, err
+	}
+	codecPoints := rs.EvaluationPoints(totalShards)
+	for i, point := range recordedPoints {
+		if i < len(codecPoints) && point != codecPoints[i] {
+			return nil, fmt.Errorf("unsupported evaluation point 0x%02x for shard %d, expected 0x%02x", point, i, codecPoints[i])
+		}
+	}
+
+	// Reconstruct the whole codeword, every shard is a single byte
+	shards := make([][]byte, totalShards)
+	for i, index := range indices {
+		shards[index] = []byte{values[i]}
+	}
+	decoded := &DecodedCodeword{Values: values, Indices: indices, Shards: make([]byte, totalShards)}
+	for i, shard := range shards {
+		if shard == nil {
+			decoded.Recovered = append(decoded.Recovered, i)
+		}
+	}
+	if err := coder.Reconstruct(shards); err != nil {
+		return nil, err
+	}
+
+	for i, shard := range shards {
+		decoded.Shards[i] = shard[0]
+	}
+	// The encoding is systematic, so the message is the data part of the codeword
+	decoded.Message = decoded.Shards[:dataShards]
+	return decoded, nil
+}
+
+// DecodedData returns the JSON result of the decoding
+func (d *DecodedCodeword) DecodedData(params CodeParams) DecodedData {
+	recovered := make([]CodewordShard, len(d.Recovered))
+	for i, index := range d.Recovered {
+		recovered[i] = CodewordShard{Index: index, Value: fmt.Sprintf("0x%02x", d.Shards[index])}
+	}
+	return DecodedData{
+		CodeParams:      params,
+		EncodedShards:   HexStrings(d.Values),
+		ShardIndices:    d.Indices,
+		DecodedData:     HexStrings(d.Message),
+		RecoveredShards: recovered,
+	}
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// CompressionID identifies how an object is compressed before it is split into shards
+type CompressionID uint8
+
+const (
+	// CompressionNone stores the object as is
+	CompressionNone CompressionID = 0
+	// CompressionGzip compresses the object with gzip
+	CompressionGzip CompressionID = 1
+	// CompressionZlib compresses the object with zlib
+	CompressionZlib CompressionID = 2
+	// CompressionFlate compresses the object with raw DEFLATE
+	CompressionFlate CompressionID = 3
+)
+
+// DefaultMaxCompressionRatio is the compressed to original size ratio above which
+// compression is not worth it and the object is stored as is
+const DefaultMaxCompressionRatio = 0.9
+
+// compressionNames maps compression IDs to their names
+var compressionNames = map[CompressionID]string{
+	CompressionNone:  "none",
+	CompressionGzip:  "gzip",
+	CompressionZlib:  "zlib",
+	CompressionFlate: "flate",
+}
+
+// String returns the name of the compression
+func (c CompressionID) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("compression(%d)", uint8(c))
+}
+
+// ParseCompression returns the compression with the given name
+func ParseCompression(name string) (CompressionID, error) {
+	for id, compressionName := range compressionNames {
+		if compressionName == name {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown compression %q (expected none, gzip, zlib or flate)", name)
+}
+
+// Compress compresses data with the given compression at the best compression level
+func Compress(data []byte, compression CompressionID) ([]byte, error) {
+	if compression == CompressionNone {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	w, err := newCompressor(&buf, compression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// newCompressor returns a writer compressing to w at the best compression level
+func newCompressor(w io.Writer, compression CompressionID) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	case CompressionZlib:
+		return zlib.NewWriterLevel(w, zlib.BestCompression)
+	case CompressionFlate:
+		return flate.NewWriter(w, flate.BestCompression)
+	}
+	return nil, fmt.Errorf("unsupported compression %s", compression)
+}
+
+// newDecompressor returns a reader decompressing the stream read from r. The stream ends
+// by itself, what follows it in r is not read.
+func newDecompressor(r io.Reader, compression CompressionID) (io.Reader, error) {
+	switch compression {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %v", err)
+		}
+		// A single member, followed by the zero padding of the last data shard
+		gr.Multistream(false)
+		return gr, nil
+	case CompressionZlib:
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %v", err)
+		}
+		return zr, nil
+	case CompressionFlate:
+		return flate.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("unsupported compression %s", compression)
+}
+
+// Decompress decompresses data compressed by Compress into exactly size bytes. The
+// compressed streams end by themselves, so padding after them is ignored. Data that
+// would decompress to more than size bytes is rejected without being inflated further.
+func Decompress(data []byte, compression CompressionID, size int64) ([]byte, error) {
+	if compression == CompressionNone {
+		if int64(len(data)) < size {
+			return nil, fmt.Errorf("object has %d bytes, expected %d", len(data), size)
+		}
+		return data[:size], nil
+	}
+	r, err := newDecompressor(bytes.NewReader(data), compression)
+	if err != nil {
+		return nil, err
+	}
+
+	// size comes from the header: the buffer grows with the bytes actually inflated, and
+	// reading stops one byte past size
+	var out bytes.Buffer
+	if size < maxPayloadPrealloc {
+		out.Grow(int(size))
+	} else {
+		out.Grow(maxPayloadPrealloc)
+	}
+	if _, err := io.Copy(&out, io.LimitReader(r, size+1)); err != nil {
+		return nil, fmt.Errorf("failed to decompress: %v", err)
+	}
+	if int64(out.Len()) != size {
+		return nil, fmt.Errorf("object decompresses to %d bytes, expected %d", out.Len(), size)
+	}
+	return out.Bytes(), nil
+}
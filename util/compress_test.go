@@ -0,0 +1,172 @@
+package util
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// logLines returns n bytes of repetitive log lines, which compress well
+func logLines(n int) []byte {
+	line := "2024-05-01T12:00:00Z INFO request served path=/api/v1/objects status=200\n"
+	return []byte(strings.Repeat(line, n/len(line)+1)[:n])
+}
+
+// randomData returns n reproducible random bytes, which do not compress
+func randomData(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+var compressions = []CompressionID{CompressionNone, CompressionGzip, CompressionZlib, CompressionFlate}
+
+func TestCompressRoundTrip(t *testing.T) {
+	inputs := map[string][]byte{
+		"empty":  {},
+		"1 byte": {42},
+		"logs":   logLines(100000),
+		"random": randomData(1, 10000),
+	}
+	for _, compression := range compressions {
+		for name, data := range inputs {
+			compressed, err := Compress(data, compression)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", compression, name, err)
+			}
+			// The last data shard is padded with zeros after the compressed stream
+			padded := append(append([]byte(nil), compressed...), make([]byte, 100)...)
+			got, err := Decompress(padded, compression, int64(len(data)))
+			if err != nil {
+				t.Fatalf("%s, %s: %v", compression, name, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("%s, %s: round trip differs", compression, name)
+			}
+		}
+	}
+
+	compressed, err := Compress(logLines(100000), CompressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) > 100000/20 {
+		t.Errorf("logs compress to %d bytes, expected at most 5%%", len(compressed))
+	}
+}
+
+func TestDecompressRejectsWrongSizes(t *testing.T) {
+	data := logLines(10000)
+	for _, compression := range compressions[1:] {
+		compressed, err := Compress(data, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			name string
+			size int64
+			err  string
+		}{
+			{"object larger than recorded", int64(len(data)) - 1, "decompresses to 10000 bytes, expected 9999"},
+			{"object smaller than recorded", int64(len(data)) + 1, "decompresses to 10000 bytes, expected 10001"},
+			// A hostile header must not make Decompress allocate the recorded size
+			{"hostile size", 1 << 62, "expected 4611686018427387904"},
+		}
+		for _, test := range tests {
+			if _, err := Decompress(compressed, compression, test.size); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s, %s: got error %v, want one containing %q", compression, test.name, err, test.err)
+			}
+		}
+		if _, err := Decompress(randomData(2, 100), compression, 100); err == nil {
+			t.Errorf("%s: Decompress accepted random bytes", compression)
+		}
+	}
+	if _, err := Decompress([]byte{1, 2, 3}, CompressionNone, 1<<62); err == nil {
+		t.Error("Decompress accepted an uncompressed object larger than its data")
+	}
+}
+
+func TestEncodeCompressedObject(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		compression CompressionID
+		maxRatio    float64
+		want        CompressionID // Compression recorded in the header
+	}{
+		{"logs", logLines(50000), CompressionGzip, DefaultMaxCompressionRatio, CompressionGzip},
+		{"logs with zlib", logLines(50000), CompressionZlib, DefaultMaxCompressionRatio, CompressionZlib},
+		{"logs with flate", logLines(50000), CompressionFlate, DefaultMaxCompressionRatio, CompressionFlate},
+		// Random data grows when compressed, so it is stored as is
+		{"random", randomData(3, 50000), CompressionGzip, DefaultMaxCompressionRatio, CompressionNone},
+		// Logs compress to about 2%, above a 1% limit
+		{"ratio above the limit", logLines(50000), CompressionGzip, 0.001, CompressionNone},
+		{"not requested", logLines(50000), CompressionNone, 0, CompressionNone},
+		{"empty", []byte{}, CompressionGzip, DefaultMaxCompressionRatio, CompressionNone},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header, shards, err := EncodeCompressedObject(test.data, test.compression, test.maxRatio, CodecVandermonde, DefaultPrimitivePoly, 4, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header.Compression != test.want {
+				t.Fatalf("compression %s, want %s", header.Compression, test.want)
+			}
+			if header.ObjectSize != int64(len(test.data)) {
+				t.Errorf("object size %d, want %d", header.ObjectSize, len(test.data))
+			}
+
+			// Through the shard format: version 2 records the compression
+			header.Version = header.formatVersion()
+			wantVersion := uint8(ShardFormatVersion)
+			if test.want != CompressionNone {
+				wantVersion = ShardFormatVersionFlags
+			}
+			for i := range shards {
+				var buf bytes.Buffer
+				header.Index = i
+				if err := WriteShard(&buf, header, shards[i]); err != nil {
+					t.Fatal(err)
+				}
+				if buf.Bytes()[4] != wantVersion {
+					t.Fatalf("shard format version %d, want %d", buf.Bytes()[4], wantVersion)
+				}
+				read, payload, err := ReadShard(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if read.Compression != test.want || !bytes.Equal(payload, shards[i]) {
+					t.Fatalf("shard %d does not read back with compression %s", i, test.want)
+				}
+			}
+
+			// Any 4 shards decode, decompressing transparently
+			shards[0], shards[5] = nil, nil
+			data, err := DecodeObject(header, shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, test.data) {
+				t.Error("decoded object differs")
+			}
+		})
+	}
+}
+
+func TestCompressedDataSize(t *testing.T) {
+	header := ShardHeader{DataShards: 4, ShardLength: 100, ObjectSize: 10000}
+	if got := header.DataSize(); got != 10000 {
+		t.Errorf("uncompressed DataSize = %d, want the object size", got)
+	}
+	header.Compression = CompressionGzip
+	if got := header.DataSize(); got != 400 {
+		t.Errorf("compressed DataSize = %d, want all 400 bytes of the data shards", got)
+	}
+	header.Flags = FlagEncrypted
+	if got := header.DataSize(); got != 4*(100-ShardCipherOverhead) {
+		t.Errorf("compressed and encrypted DataSize = %d, want %d", got, 4*(100-ShardCipherOverhead))
+	}
+}
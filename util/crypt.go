@@ -0,0 +1,206 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ShardKeySize is the size in bytes of the AES-256 key of a ShardCipher
+const ShardKeySize = 32
+
+// ShardCipherOverhead is the number of bytes encryption adds to a shard payload:
+// the random nonce and the GCM tag
+const ShardCipherOverhead = 12 + 16
+
+// scrypt cost of the passphrase keys: 32 MiB of memory per object
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// passphraseSalt prefixes the object ID in the salt of passphrase keys
+const passphraseSalt = "rs-encoder shard key\x00"
+
+// ErrAuthentication is returned when an encrypted shard payload fails authentication: it
+// is corrupted, was encrypted with another key, or its header was altered, e.g. to pass
+// the shard off as another index or object
+var ErrAuthentication = errors.New("shard authentication failed")
+
+// ErrKeyRequired is returned when an encrypted shard is read without a cipher
+var ErrKeyRequired = errors.New("shard is encrypted, a key is needed")
+
+// ErrNotEncrypted is returned when a shard in the clear is read with a cipher
+var ErrNotEncrypted = errors.New("shard is not encrypted")
+
+// ShardCipher encrypts shard payloads at rest with AES-256-GCM. Every payload gets a random
+// nonce, and the shard header (object ID, shard index, shard counts, codec, compression
+// and object size) is authenticated as additional data, so a shard swapped between indices or objects
+// is rejected before decoding.
+type ShardCipher struct {
+	key        []byte // Key of every object, nil with a passphrase
+	passphrase []byte
+
+	mu    sync.Mutex
+	aeads map[ObjectID]cipher.AEAD
+}
+
+// NewShardCipher creates a ShardCipher with a ShardKeySize bytes key used for every object
+func NewShardCipher(key []byte) (*ShardCipher, error) {
+	if len(key) != ShardKeySize {
+		return nil, fmt.Errorf("invalid shard key: %d bytes, expected %d", len(key), ShardKeySize)
+	}
+	return &ShardCipher{key: append([]byte(nil), key...), aeads: make(map[ObjectID]cipher.AEAD)}, nil
+}
+
+// NewPassphraseCipher creates a ShardCipher whose key is derived from a passphrase with
+// scrypt, salted with the object ID so every object has its own key. The derivation is
+// deliberately slow and runs once per object.
+func NewPassphraseCipher(passphrase []byte) (*ShardCipher, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase is empty")
+	}
+	return &ShardCipher{passphrase: append([]byte(nil), passphrase...), aeads: make(map[ObjectID]cipher.AEAD)}, nil
+}
+
+// ReadKeyFile reads a shard key file: ShardKeySize raw bytes, or as many bytes in
+// hexadecimal followed by an optional newline
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == ShardKeySize {
+		return data, nil
+	}
+	text := bytes.TrimSpace(data)
+	if len(text) == hex.EncodedLen(ShardKeySize) {
+		key := make([]byte, ShardKeySize)
+		if _, err := hex.Decode(key, text); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: expected a %d bytes key, raw or in hexadecimal", path, ShardKeySize)
+}
+
+// Seal encrypts the payload of the shard described by header: the result is the nonce
+// followed by the ciphertext and tag
+func (c *ShardCipher) Seal(header ShardHeader, payload []byte) ([]byte, error) {
+	aead, err := c.aead(header.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err := rand.Read(sealed); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return aead.Seal(sealed, sealed, payload, header.associatedData()), nil
+}
+
+// Open decrypts a payload sealed by Seal, returning ErrAuthentication if the payload or the
+// authenticated header fields do not match
+func (c *ShardCipher) Open(header ShardHeader, sealed []byte) ([]byte, error) {
+	aead, err := c.aead(header.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("shard %d: %w", header.Index, ErrAuthentication)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, ciphertext, header.associatedData())
+	if err != nil {
+		return nil, fmt.Errorf("shard %d: %w", header.Index, ErrAuthentication)
+	}
+	return payload, nil
+}
+
+// aead returns the AES-256-GCM instance of an object, deriving its key on first use
+func (c *ShardCipher) aead(id ObjectID) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if aead, ok := c.aeads[id]; ok {
+		return aead, nil
+	}
+
+	key := c.key
+	if key == nil {
+		var err error
+		key, err = scrypt(c.passphrase, append([]byte(passphraseSalt), id[:]...), scryptN, scryptR, scryptP, ShardKeySize)
+		if err != nil {
+			return nil, err
+		}
+		defer clear(key)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c.aeads[id] = aead
+	return aead, nil
+}
+
+// associatedData encodes the header fields an encrypted payload is bound to. The length and
+// checksum describe the ciphertext and are left out.
+func (h ShardHeader) associatedData() []byte {
+	ad := []byte(ShardMagic)
+	ad = append(ad, byte(h.Codec), h.PrimitivePoly, byte(h.Flags), byte(h.Compression))
+	ad = binary.BigEndian.AppendUint16(ad, uint16(h.DataShards))
+	ad = binary.BigEndian.AppendUint16(ad, uint16(h.ParityShards))
+	ad = binary.BigEndian.AppendUint16(ad, uint16(h.Index))
+	ad = binary.BigEndian.AppendUint64(ad, uint64(h.ObjectSize))
+	return append(ad, h.ObjectID[:]...)
+}
+
+// ShardOption configures how shards are written and read
+type ShardOption func(*shardOptions)
+
+// shardOptions holds the settings of the ShardOptions
+type shardOptions struct {
+	cipher *ShardCipher
+	root   *MerkleHash // Merkle root the shard files read must prove inclusion in
+}
+
+// WithCipher encrypts the shards written and decrypts the shards read with c. Reading a
+// shard that is not encrypted fails, so plain shards cannot be passed off as encrypted
+// ones. A nil c leaves the shards in the clear.
+func WithCipher(c *ShardCipher) ShardOption {
+	return func(o *shardOptions) {
+		o.cipher = c
+	}
+}
+
+// newShardOptions applies opts to the default settings
+func newShardOptions(opts []ShardOption) *shardOptions {
+	o := &shardOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// open returns the plain payload of a shard read from storage
+func (o *shardOptions) open(header ShardHeader, payload []byte) ([]byte, error) {
+	encrypted := header.Flags&FlagEncrypted != 0
+	switch {
+	case encrypted && o.cipher == nil:
+		return nil, fmt.Errorf("shard %d: %w", header.Index, ErrKeyRequired)
+	case !encrypted && o.cipher != nil:
+		return nil, fmt.Errorf("shard %d: %w", header.Index, ErrNotEncrypted)
+	case encrypted:
+		return o.cipher.Open(header, payload)
+	}
+	return payload, nil
+}
@@ -0,0 +1,165 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// testCipher returns a ShardCipher with a fixed key
+func testCipher(t *testing.T) *ShardCipher {
+	t.Helper()
+	c, err := NewShardCipher(bytes.Repeat([]byte{0x5a}, ShardKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestShardCipherRoundTrip(t *testing.T) {
+	passphrase, err := NewPassphraseCipher([]byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphers := map[string]*ShardCipher{"key": testCipher(t), "passphrase": passphrase}
+
+	for name, c := range ciphers {
+		for _, size := range []int{0, 1, 15, 16, 17, 4096} {
+			payload := bytes.Repeat([]byte{byte(size)}, size)
+			header := testHeader()
+			header.ObjectSize = int64(4 * size)
+			var buf bytes.Buffer
+			if err := WriteShard(&buf, header, payload, WithCipher(c)); err != nil {
+				t.Fatalf("%s, %d bytes: %v", name, size, err)
+			}
+			if buf.Len() != ShardHeaderSize+size+ShardCipherOverhead {
+				t.Errorf("%s, %d bytes: shard has %d bytes, want %d", name, size, buf.Len(), ShardHeaderSize+size+ShardCipherOverhead)
+			}
+			if size >= 16 && bytes.Contains(buf.Bytes(), payload) {
+				t.Errorf("%s, %d bytes: the payload is stored in the clear", name, size)
+			}
+
+			header, got, err := ReadShard(bytes.NewReader(buf.Bytes()), WithCipher(c))
+			if err != nil {
+				t.Fatalf("%s, %d bytes: %v", name, size, err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Errorf("%s, %d bytes: decrypted payload differs", name, size)
+			}
+			if header.Flags&FlagEncrypted == 0 {
+				t.Errorf("%s, %d bytes: FlagEncrypted is not set", name, size)
+			}
+		}
+	}
+}
+
+func TestShardCipherNonceIsRandom(t *testing.T) {
+	c := testCipher(t)
+	payload := []byte("same payload")
+	first, err := c.Seal(testHeader(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Seal(testHeader(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("sealing twice gives the same ciphertext")
+	}
+}
+
+// The header fields are authenticated: rewriting them in the header, with valid checksums,
+// must not pass a shard off as another one
+func TestShardCipherAuthenticatesHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(h *ShardHeader)
+	}{
+		{"index", func(h *ShardHeader) { h.Index = 2 }},
+		{"object ID", func(h *ShardHeader) { h.ObjectID[0] ^= 1 }},
+		{"object size", func(h *ShardHeader) { h.ObjectSize++ }},
+		{"data shards", func(h *ShardHeader) { h.DataShards = 5 }},
+		{"parity shards", func(h *ShardHeader) { h.ParityShards = 3 }},
+		{"codec", func(h *ShardHeader) { h.Codec = CodecLagrange }},
+		{"polynomial", func(h *ShardHeader) { h.PrimitivePoly = 0x2b }},
+		{"compression", func(h *ShardHeader) { h.Compression = CompressionGzip }},
+	}
+
+	c := testCipher(t)
+	var buf bytes.Buffer
+	if err := WriteShard(&buf, testHeader(), []byte("shard payload"), WithCipher(c)); err != nil {
+		t.Fatal(err)
+	}
+	header, err := ParseShardHeader(buf.Bytes()[:ShardHeaderSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := buf.Bytes()[ShardHeaderSize:]
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			modified := header
+			test.modify(&modified)
+			modified.Version = modified.formatVersion()
+			data, err := modified.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = ReadShard(bytes.NewReader(append(data, payload...)), WithCipher(c))
+			if !errors.Is(err, ErrAuthentication) {
+				t.Errorf("got error %v, want ErrAuthentication", err)
+			}
+		})
+	}
+}
+
+func TestShardCipherRejections(t *testing.T) {
+	c := testCipher(t)
+	var plain, sealed bytes.Buffer
+	if err := WriteShard(&plain, testHeader(), []byte("shard payload")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteShard(&sealed, testHeader(), []byte("shard payload"), WithCipher(c)); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewShardCipher(bytes.Repeat([]byte{0xa5}, ShardKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		opts []ShardOption
+		want error
+	}{
+		{"encrypted shard without a key", sealed.Bytes(), nil, ErrKeyRequired},
+		{"plain shard with a key", plain.Bytes(), []ShardOption{WithCipher(c)}, ErrNotEncrypted},
+		{"wrong key", sealed.Bytes(), []ShardOption{WithCipher(other)}, ErrAuthentication},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := ReadShard(bytes.NewReader(test.data), test.opts...); !errors.Is(err, test.want) {
+				t.Errorf("got error %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	// A flipped ciphertext bit with a recomputed payload checksum only fails authentication
+	header, err := ParseShardHeader(sealed.Bytes()[:ShardHeaderSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte(nil), sealed.Bytes()[ShardHeaderSize:]...)
+	payload[len(payload)/2] ^= 1
+	header.Checksum = Checksum(payload)
+	data, err := header.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadShard(bytes.NewReader(append(data, payload...)), WithCipher(c)); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("tampered ciphertext: got error %v, want ErrAuthentication", err)
+	}
+}
@@ -58,6 +58,7 @@ func ReadMessageFromJSON(filePath string) ([]byte, error) {
 }
 
 // WriteEncodedToJSONWithOriginal writes the encoding result and original message to a JSON file
+// in the legacy format; WriteCodewordToJSON writes the versioned format
 func WriteEncodedToJSONWithOriginal(filePath string, encoded []byte, originalMessage []byte) error {
 	// Convert byte arrays to hex string arrays
 	encodedStrings := make([]string, len(encoded))
@@ -90,18 +91,3 @@ func WriteEncodedToJSONWithOriginal(filePath string, encoded []byte, originalMes
 
 	return nil
 }
-
-// WriteEncodedToJSON writes the encoding result to a JSON file, using the first 6 bytes of the encoding result as the original message
-func WriteEncodedToJSON(filePath string, encoded []byte) error {
-	// Assume the original message is the first 6 bytes of the encoded result
-	originalMessage := encoded[:min(6, len(encoded))]
-	return WriteEncodedToJSONWithOriginal(filePath, encoded, originalMessage)
-}
-
-// min returns the minimum of two ints
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"rs-encoder/rs"
+)
+
+// HexStrings converts bytes to "0x1f" strings, as written into the JSON files
+func HexStrings(values []byte) []string {
+	if values == nil {
+		return nil
+	}
+	hexStrings := make([]string, len(values))
+	for i, value := range values {
+		hexStrings[i] = fmt.Sprintf("0x%02x", value)
+	}
+	return hexStrings
+}
+
+// ParseHexStrings converts "0x1f" or "1f" strings to bytes
+func ParseHexStrings(hexStrings []string) ([]byte, error) {
+	values := make([]byte, len(hexStrings))
+	for i, hexStr := range hexStrings {
+		value, err := parseHexByte(hexStr)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %v", i, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// DecodeHex decodes exactly len(dst) bytes of hexadecimal text into dst, what names the
+// value in the errors
+func DecodeHex(dst, text []byte, what string) error {
+	if hex.DecodedLen(len(text)) != len(dst) {
+		return fmt.Errorf("invalid %s %q: expected %d hexadecimal bytes", what, text, len(dst))
+	}
+	if _, err := hex.Decode(dst, text); err != nil {
+		return fmt.Errorf("invalid %s %q: %v", what, text, err)
+	}
+	return nil
+}
+
+// PrintBytes prints bytes in decimal and hexadecimal
+func PrintBytes(w io.Writer, values []byte) {
+	fmt.Fprint(w, "[ ")
+	for i, value := range values {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprint(w, value)
+	}
+	fmt.Fprintln(w, " ]")
+
+	// Print hexadecimal format
+	fmt.Fprint(w, "Hexadecimal: [ ")
+	for i, value := range values {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprintf(w, "0x%02x", value)
+	}
+	fmt.Fprintln(w, " ]")
+}
+
+// WriteJSON writes v to a JSON file, indented
+func WriteJSON(filePath string, v interface{}) error {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON encoding failed: %v", err)
+	}
+	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+	return nil
+}
+
+// TraceOptions returns the rs options that print the diagnostics of the encoders and
+// decoders to w, one line each, or no options when trace is false
+func TraceOptions(trace bool, w io.Writer) []rs.Option {
+	if !trace {
+		return nil
+	}
+	return []rs.Option{rs.WithTrace(func(line string) {
+		fmt.Fprintln(w, line)
+	})}
+}
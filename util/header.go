@@ -0,0 +1,326 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+)
+
+// ShardMagic identifies a shard file
+const ShardMagic = "RSSH"
+
+// ShardFormatVersion is the version of the shard header written by this package for
+// shards without flags
+const ShardFormatVersion = 1
+
+// ShardFormatVersionFlags is the version of the shard header written for shards with flags or
+// a compressed object. Readers of version 1 reject these shards instead of taking e.g.
+// ciphertext for the payload.
+const ShardFormatVersionFlags = 2
+
+// ShardHeaderSize is the size in bytes of an encoded shard header
+const ShardHeaderSize = 56
+
+// CodecID identifies the construction used to calculate the parity shards
+type CodecID uint8
+
+const (
+	// CodecVandermonde is RSEncoder with consecutive integer evaluation points
+	CodecVandermonde CodecID = 1
+	// CodecLagrange is the Lagrange interpolation encoder, producing the same codewords as CodecVandermonde
+	CodecLagrange CodecID = 2
+	// CodecHorner evaluates the message as polynomial coefficients with Horner's method
+	CodecHorner CodecID = 3
+)
+
+// codecNames maps codec IDs to the names of their rs codecs
+var codecNames = map[CodecID]string{
+	CodecVandermonde: rs.CodecVandermonde,
+	CodecLagrange:    rs.CodecLagrange,
+	CodecHorner:      rs.CodecHorner,
+}
+
+// String returns the name of the codec
+func (c CodecID) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("codec(%d)", uint8(c))
+}
+
+// MDS reports whether any DataShards shards of a stripe encoded with the codec rebuild it.
+// The Horner codec is not MDS: some sets of shards leave the data undetermined, so it is
+// only offered for JSON messages, never for objects stored as shard files.
+func (c CodecID) MDS() bool {
+	return c == CodecVandermonde || c == CodecLagrange
+}
+
+// ParseCodec returns the codec with the given name
+func ParseCodec(name string) (CodecID, error) {
+	for id, codecName := range codecNames {
+		if codecName == name {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown codec %q (expected lagrange, vandermonde or horner)", name)
+}
+
+// ShardFlags records how the payload of a shard is stored
+type ShardFlags uint8
+
+const (
+	// FlagEncrypted marks a payload encrypted with a ShardCipher
+	FlagEncrypted ShardFlags = 1 << iota
+
+	// knownFlags are the flags understood by this package
+	knownFlags = FlagEncrypted
+)
+
+// ErrChecksum is returned when a shard payload or header does not match its checksum
+var ErrChecksum = errors.New("checksum mismatch")
+
+// castagnoli is the CRC32C table used for shard checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ObjectID identifies the object a shard belongs to
+type ObjectID [16]byte
+
+// String returns the object ID in hexadecimal
+func (id ObjectID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// ShardHeader describes a shard and the object it belongs to.
+// It is stored in front of the shard payload, big-endian:
+//
+//	magic [4] | version u8 | codec u8 | primitive polynomial u8 | flags u8 (version 2) |
+//	data shards u16 | parity shards u16 | shard index u16 | compression u8 (version 2) | reserved u8 |
+//	shard length u64 | object size u64 | object id [16] | payload CRC32C u32 | header CRC32C u32
+type ShardHeader struct {
+	Version       uint8
+	Codec         CodecID
+	PrimitivePoly byte
+	Flags         ShardFlags
+	Compression   CompressionID // Compression of the object before it was split
+	DataShards    int
+	ParityShards  int
+	Index         int
+	ShardLength   int64 // Length of the payload following the header
+	ObjectSize    int64 // Original object size, before compression and padding
+	ObjectID      ObjectID
+	Checksum      uint32 // CRC32C of the payload
+}
+
+// Checksum calculates the CRC32C of a shard payload
+func Checksum(payload []byte) uint32 {
+	return crc32.Checksum(payload, castagnoli)
+}
+
+// MarshalBinary encodes the header into ShardHeaderSize bytes
+func (h ShardHeader) MarshalBinary() ([]byte, error) {
+	if h.DataShards < 0 || h.DataShards > 0xFFFF || h.ParityShards < 0 || h.ParityShards > 0xFFFF || h.Index < 0 || h.Index > 0xFFFF {
+		return nil, fmt.Errorf("shard counts and index must fit in 16 bits")
+	}
+
+	buf := make([]byte, ShardHeaderSize)
+	copy(buf[0:4], ShardMagic)
+	buf[4] = h.Version
+	buf[5] = byte(h.Codec)
+	buf[6] = h.PrimitivePoly
+	buf[7] = byte(h.Flags)
+	binary.BigEndian.PutUint16(buf[8:10], uint16(h.DataShards))
+	binary.BigEndian.PutUint16(buf[10:12], uint16(h.ParityShards))
+	binary.BigEndian.PutUint16(buf[12:14], uint16(h.Index))
+	buf[14] = byte(h.Compression)
+	binary.BigEndian.PutUint64(buf[16:24], uint64(h.ShardLength))
+	binary.BigEndian.PutUint64(buf[24:32], uint64(h.ObjectSize))
+	copy(buf[32:48], h.ObjectID[:])
+	binary.BigEndian.PutUint32(buf[48:52], h.Checksum)
+	binary.BigEndian.PutUint32(buf[52:56], crc32.Checksum(buf[:52], castagnoli))
+	return buf, nil
+}
+
+// ParseShardHeader decodes a header from the first ShardHeaderSize bytes of data
+func ParseShardHeader(data []byte) (ShardHeader, error) {
+	var h ShardHeader
+	if len(data) < ShardHeaderSize {
+		return h, fmt.Errorf("shard header too short: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[0:4], []byte(ShardMagic)) {
+		return h, fmt.Errorf("not a shard file: bad magic %q", data[0:4])
+	}
+	if binary.BigEndian.Uint32(data[52:56]) != crc32.Checksum(data[:52], castagnoli) {
+		return h, fmt.Errorf("shard header: %w", ErrChecksum)
+	}
+
+	h.Version = data[4]
+	switch h.Version {
+	case ShardFormatVersion:
+		// The flags and compression bytes are reserved in version 1
+	case ShardFormatVersionFlags:
+		h.Flags = ShardFlags(data[7])
+		if h.Flags&^knownFlags != 0 {
+			return h, fmt.Errorf("unsupported shard flags 0x%02x", uint8(h.Flags))
+		}
+		h.Compression = CompressionID(data[14])
+		if _, ok := compressionNames[h.Compression]; !ok {
+			return h, fmt.Errorf("unsupported %s", h.Compression)
+		}
+	default:
+		return h, fmt.Errorf("unsupported shard format version %d", h.Version)
+	}
+	h.Codec = CodecID(data[5])
+	h.PrimitivePoly = data[6]
+	h.DataShards = int(binary.BigEndian.Uint16(data[8:10]))
+	h.ParityShards = int(binary.BigEndian.Uint16(data[10:12]))
+	h.Index = int(binary.BigEndian.Uint16(data[12:14]))
+	h.ShardLength = int64(binary.BigEndian.Uint64(data[16:24]))
+	h.ObjectSize = int64(binary.BigEndian.Uint64(data[24:32]))
+	copy(h.ObjectID[:], data[32:48])
+	h.Checksum = binary.BigEndian.Uint32(data[48:52])
+
+	if !gf.IsPrimitive(h.PrimitivePoly) {
+		return h, fmt.Errorf("polynomial x^8 + 0x%02x of the shard header is not primitive", h.PrimitivePoly)
+	}
+	if _, ok := codecNames[h.Codec]; !ok {
+		return h, fmt.Errorf("unsupported %s", h.Codec)
+	}
+	if err := ValidateShardCounts(h.DataShards, h.ParityShards); err != nil {
+		return h, fmt.Errorf("invalid shard header: %v", err)
+	}
+	if h.ShardLength < 0 || h.ObjectSize < 0 {
+		return h, fmt.Errorf("invalid shard length or object size")
+	}
+	if h.Index >= h.DataShards+h.ParityShards {
+		return h, fmt.Errorf("shard index %d is outside the %d+%d layout", h.Index, h.DataShards, h.ParityShards)
+	}
+
+	// The object size is used to size buffers, it must fit in the data shards. A compressed
+	// object is bounded by Decompress instead.
+	capacity := h.ShardLength
+	if h.Flags&FlagEncrypted != 0 {
+		capacity -= ShardCipherOverhead
+		if capacity < 0 {
+			return h, fmt.Errorf("encrypted shard length %d is below the %d bytes of nonce and tag", h.ShardLength, ShardCipherOverhead)
+		}
+	}
+	// ceil(ObjectSize/DataShards) > capacity, without overflowing
+	if h.Compression == CompressionNone && h.ObjectSize > 0 && (h.ObjectSize-1)/int64(h.DataShards) >= capacity {
+		return h, fmt.Errorf("object size %d does not fit in %d data shards of %d bytes", h.ObjectSize, h.DataShards, capacity)
+	}
+	return h, nil
+}
+
+// formatVersion returns the version of the header: version 1 unless flags or compression
+// need version 2
+func (h ShardHeader) formatVersion() uint8 {
+	if h.Flags == 0 && h.Compression == CompressionNone {
+		return ShardFormatVersion
+	}
+	return ShardFormatVersionFlags
+}
+
+// DataSize returns the number of bytes of the data shards that hold the object: its size,
+// or all of the data shards if the object is compressed, as the compressed size is not
+// recorded and the compressed stream ends by itself
+func (h ShardHeader) DataSize() int64 {
+	if h.Compression == CompressionNone {
+		return h.ObjectSize
+	}
+	shardLength := h.ShardLength
+	if h.Flags&FlagEncrypted != 0 {
+		shardLength -= ShardCipherOverhead
+	}
+	return shardLength * int64(h.DataShards)
+}
+
+// SameObject reports whether two headers describe shards of the same encoded object
+func (h ShardHeader) SameObject(other ShardHeader) bool {
+	return h.Codec == other.Codec && h.PrimitivePoly == other.PrimitivePoly && h.Flags == other.Flags && h.Compression == other.Compression &&
+		h.DataShards == other.DataShards && h.ParityShards == other.ParityShards &&
+		h.ShardLength == other.ShardLength && h.ObjectSize == other.ObjectSize &&
+		h.ObjectID == other.ObjectID
+}
+
+// WriteShard writes the header followed by the payload, filling in the version, length and checksum.
+// With WithCipher the payload is encrypted and FlagEncrypted is set.
+func WriteShard(w io.Writer, header ShardHeader, payload []byte, opts ...ShardOption) error {
+	o := newShardOptions(opts)
+	if o.cipher != nil {
+		header.Flags |= FlagEncrypted
+		sealed, err := o.cipher.Seal(header, payload)
+		if err != nil {
+			return err
+		}
+		payload = sealed
+	} else if header.Flags&FlagEncrypted != 0 {
+		return fmt.Errorf("shard %d is marked encrypted but no cipher is given", header.Index)
+	}
+
+	header.Version = header.formatVersion()
+	header.ShardLength = int64(len(payload))
+	header.Checksum = Checksum(payload)
+
+	buf, err := header.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("failed to write shard header: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf("failed to write shard payload: %v", err)
+	}
+	return nil
+}
+
+// maxPayloadPrealloc bounds the buffer allocated for a payload before any of it is read
+const maxPayloadPrealloc = 1 << 20
+
+// readPayload reads a payload of length bytes. The length comes from the header, which a
+// damaged or hostile shard can set to anything, so the buffer only grows with the bytes
+// actually read.
+func readPayload(r io.Reader, length int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if length < maxPayloadPrealloc {
+		buf.Grow(int(length))
+	} else {
+		buf.Grow(maxPayloadPrealloc)
+	}
+	if _, err := buf.ReadFrom(io.LimitReader(r, length)); err != nil {
+		return nil, fmt.Errorf("failed to read shard payload: %v", err)
+	}
+	if int64(buf.Len()) != length {
+		return nil, fmt.Errorf("failed to read shard payload: got %d of %d bytes", buf.Len(), length)
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadShard reads a header and its payload, returning ErrChecksum if the payload is corrupted.
+// An encrypted payload is decrypted with the cipher given by WithCipher, see ShardCipher.Open.
+func ReadShard(r io.Reader, opts ...ShardOption) (ShardHeader, []byte, error) {
+	buf := make([]byte, ShardHeaderSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return ShardHeader{}, nil, fmt.Errorf("failed to read shard header: %v", err)
+	}
+	header, err := ParseShardHeader(buf)
+	if err != nil {
+		return header, nil, err
+	}
+
+	payload, err := readPayload(r, header.ShardLength)
+	if err != nil {
+		return header, nil, err
+	}
+	if Checksum(payload) != header.Checksum {
+		return header, nil, fmt.Errorf("shard %d payload: %w", header.Index, ErrChecksum)
+	}
+	payload, err = newShardOptions(opts).open(header, payload)
+	return header, payload, err
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"strings"
+	"testing"
+)
+
+// testHeader returns the header of shard 1 of a 4+2 object
+func testHeader() ShardHeader {
+	return ShardHeader{
+		Version:       ShardFormatVersion,
+		Codec:         CodecVandermonde,
+		PrimitivePoly: DefaultPrimitivePoly,
+		DataShards:    4,
+		ParityShards:  2,
+		Index:         1,
+		ObjectSize:    49, // 4 shards of "shard payload", the last one padded
+		ObjectID:      ObjectID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}
+}
+
+func TestReadShardRoundTrip(t *testing.T) {
+	payload := []byte("shard payload")
+	var buf bytes.Buffer
+	if err := WriteShard(&buf, testHeader(), payload); err != nil {
+		t.Fatal(err)
+	}
+	header, got, err := ReadShard(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("payload is %q, want %q", got, payload)
+	}
+	if header.ShardLength != int64(len(payload)) || header.Index != 1 || header.ObjectID != testHeader().ObjectID {
+		t.Errorf("header %+v does not describe the written shard", header)
+	}
+}
+
+func TestReadShardRejectsBadHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(h *ShardHeader)
+		err    string
+	}{
+		{
+			// A header claiming an exabyte payload must not allocate it
+			name:   "length beyond the payload",
+			modify: func(h *ShardHeader) { h.ShardLength = 1 << 60 },
+			err:    "got 13 of 1152921504606846976 bytes",
+		},
+		{
+			name:   "truncated payload",
+			modify: func(h *ShardHeader) { h.ShardLength = 20 },
+			err:    "got 13 of 20 bytes",
+		},
+		{
+			// Would make the decoder allocate an exabyte for the object
+			name:   "object size beyond the data shards",
+			modify: func(h *ShardHeader) { h.ObjectSize = 1 << 62 },
+			err:    "object size 4611686018427387904 does not fit in 4 data shards of 13 bytes",
+		},
+		{
+			name:   "object size one byte too large",
+			modify: func(h *ShardHeader) { h.ObjectSize = 4*13 + 1 },
+			err:    "does not fit in 4 data shards",
+		},
+		{
+			name: "encrypted shard shorter than its overhead",
+			modify: func(h *ShardHeader) {
+				h.Version, h.Flags, h.ObjectSize = ShardFormatVersionFlags, FlagEncrypted, 0
+			},
+			err: "below the 28 bytes of nonce and tag",
+		},
+		{
+			name:   "no data shards",
+			modify: func(h *ShardHeader) { h.DataShards, h.ObjectSize = 0, 0 },
+			err:    "number of data shards must be positive",
+		},
+		{
+			name:   "unknown codec",
+			modify: func(h *ShardHeader) { h.Codec = 9 },
+			err:    "unsupported codec(9)",
+		},
+		{
+			name:   "polynomial that is not primitive",
+			modify: func(h *ShardHeader) { h.PrimitivePoly = 0x1b },
+			err:    "x^8 + 0x1b of the shard header is not primitive",
+		},
+		{
+			name:   "zero polynomial",
+			modify: func(h *ShardHeader) { h.PrimitivePoly = 0 },
+			err:    "not primitive",
+		},
+	}
+
+	payload := []byte("shard payload")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := testHeader()
+			header.ShardLength = int64(len(payload))
+			header.Checksum = Checksum(payload)
+			test.modify(&header)
+			buf, err := header.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = ReadShard(bytes.NewReader(append(buf, payload...)))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestReadShardDetectsCorruption(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteShard(&buf, testHeader(), []byte("shard payload")); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	data[len(data)-1] ^= 1
+	if _, _, err := ReadShard(bytes.NewReader(data)); !errors.Is(err, ErrChecksum) {
+		t.Errorf("got error %v, want ErrChecksum", err)
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	for _, codec := range []CodecID{CodecVandermonde, CodecLagrange, CodecHorner} {
+		if id, err := ParseCodec(codec.String()); err != nil || id != codec {
+			t.Errorf("ParseCodec(%q) = %v, %v", codec.String(), id, err)
+		}
+		// Every codec ID names a registered rs codec
+		if _, err := rs.NewCodec(codec.String(), gf.NewGF(DefaultPrimitivePoly), 4, 2); err != nil {
+			t.Errorf("%s: %v", codec, err)
+		}
+	}
+	for _, name := range []string{"", "rs", "Lagrange", "codec(1)"} {
+		if _, err := ParseCodec(name); err == nil || !strings.Contains(err.Error(), "unknown codec") {
+			t.Errorf("ParseCodec(%q): got error %v, want an unknown codec", name, err)
+		}
+	}
+	if name := CodecID(9).String(); name != "codec(9)" {
+		t.Errorf("CodecID(9).String() = %q", name)
+	}
+}
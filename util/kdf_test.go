@@ -0,0 +1,87 @@
+package util
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// unhex decodes a test vector written in hexadecimal, spaces allowed
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Test vectors of RFC 7914 section 12
+func TestScryptRFC7914(t *testing.T) {
+	tests := []struct {
+		passphrase, salt string
+		N, r, p          int
+		want             string
+		long             bool // Needs 1 GiB of memory
+	}{
+		{"", "", 16, 1, 1, "" +
+			"77 d6 57 62 38 65 7b 20 3b 19 ca 42 c1 8a 04 97 f1 6b 48 44 e3 07 4a e8 df df fa 3f ed e2 14 42" +
+			"fc d0 06 9d ed 09 48 f8 32 6a 75 3a 0f c8 1f 17 e8 d3 e0 fb 2e 0d 36 28 cf 35 e2 0c 38 d1 89 06", false},
+		{"password", "NaCl", 1024, 8, 16, "" +
+			"fd ba be 1c 9d 34 72 00 78 56 e7 19 0d 01 e9 fe 7c 6a d7 cb c8 23 78 30 e7 73 76 63 4b 37 31 62" +
+			"2e af 30 d9 2e 22 a3 88 6f f1 09 27 9d 98 30 da c7 27 af b9 4a 83 ee 6d 83 60 cb df a2 cc 06 40", false},
+		{"pleaseletmein", "SodiumChloride", 16384, 8, 1, "" +
+			"70 23 bd cb 3a fd 73 48 46 1c 06 cd 81 fd 38 eb fd a8 fb ba 90 4f 8e 3e a9 b5 43 f6 54 5d a1 f2" +
+			"d5 43 29 55 61 3f 0f cf 62 d4 97 05 24 2a 9a f9 e6 1e 85 dc 0d 65 1e 40 df cf 01 7b 45 57 58 87", false},
+		{"pleaseletmein", "SodiumChloride", 1048576, 8, 1, "" +
+			"21 01 cb 9b 6a 51 1a ae ad db be 09 cf 70 f8 81 ec 56 8d 57 4a 2f fd 4d ab e5 ee 98 20 ad aa 47" +
+			"8e 56 fd 8f 4b a5 d0 9f fa 1c 6d 92 7c 40 f4 c3 37 30 40 49 e8 a9 52 fb cb f4 5c 6f a7 7a 41 a4", true},
+	}
+
+	for _, test := range tests {
+		if test.long && testing.Short() {
+			t.Logf("skipping N=%d in short mode", test.N)
+			continue
+		}
+		want := unhex(t, test.want)
+		got, err := scrypt([]byte(test.passphrase), []byte(test.salt), test.N, test.r, test.p, len(want))
+		if err != nil {
+			t.Fatalf("scrypt(%q, %q, N=%d): %v", test.passphrase, test.salt, test.N, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("scrypt(%q, %q, N=%d, r=%d, p=%d) = %x, want %x", test.passphrase, test.salt, test.N, test.r, test.p, got, want)
+		}
+	}
+}
+
+// Test vectors of RFC 7914 section 11
+func TestPBKDF2SHA256RFC7914(t *testing.T) {
+	tests := []struct {
+		passphrase, salt string
+		iterations       int
+		want             string
+	}{
+		{"passwd", "salt", 1, "" +
+			"55 ac 04 6e 56 e3 08 9f ec 16 91 c2 25 44 b6 05 f9 41 85 21 6d de 04 65 e6 8b 9d 57 c2 0d ac bc" +
+			"49 ca 9c cc f1 79 b6 45 99 16 64 b3 9d 77 ef 31 7c 71 b8 45 b1 e3 0b d5 09 11 20 41 d3 a1 97 83"},
+		{"Password", "NaCl", 80000, "" +
+			"4d dc d8 f6 0b 98 be 21 83 0c ee 5e f2 27 01 f9 64 1a 44 18 d0 4c 04 14 ae ff 08 87 6b 34 ab 56" +
+			"a1 d4 25 a1 22 58 33 54 9a db 84 1b 51 c9 b3 17 6a 27 2b de bb a1 d0 78 47 8f 62 b3 97 f3 3c 8d"},
+	}
+
+	for _, test := range tests {
+		want := unhex(t, test.want)
+		if got := pbkdf2SHA256([]byte(test.passphrase), []byte(test.salt), test.iterations, len(want)); !bytes.Equal(got, want) {
+			t.Errorf("PBKDF2(%q, %q, %d) = %x, want %x", test.passphrase, test.salt, test.iterations, got, want)
+		}
+	}
+}
+
+func TestScryptRejectsInvalidParameters(t *testing.T) {
+	for _, params := range [][3]int{{0, 1, 1}, {1, 1, 1}, {15, 1, 1}, {16, 0, 1}, {16, 1, 0}, {16, 1 << 20, 1 << 10}} {
+		if _, err := scrypt([]byte("p"), []byte("s"), params[0], params[1], params[2], 32); err == nil {
+			t.Errorf("scrypt accepted N=%d, r=%d, p=%d", params[0], params[1], params[2])
+		}
+	}
+}
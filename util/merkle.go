@@ -0,0 +1,287 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MerkleHash is a node of a shard Merkle tree
+type MerkleHash [sha256.Size]byte
+
+// String returns the hash in hexadecimal
+func (h MerkleHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// MarshalText encodes the hash in hexadecimal
+func (h MerkleHash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText decodes a hash in hexadecimal
+func (h *MerkleHash) UnmarshalText(text []byte) error {
+	return DecodeHex(h[:], text, "Merkle hash")
+}
+
+// ParseMerkleHash decodes a hash in hexadecimal
+func ParseMerkleHash(text string) (MerkleHash, error) {
+	var h MerkleHash
+	err := h.UnmarshalText([]byte(text))
+	return h, err
+}
+
+// MarshalText encodes the object ID in hexadecimal
+func (id ObjectID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText decodes an object ID in hexadecimal
+func (id *ObjectID) UnmarshalText(text []byte) error {
+	return DecodeHex(id[:], text, "object ID")
+}
+
+// ErrProof is returned when a shard has no inclusion proof or its proof does not lead to
+// the Merkle root of the object
+var ErrProof = errors.New("inclusion proof does not verify")
+
+// ShardLeaf returns the leaf hash of a shard: SHA-256 over a 0x00 prefix, the header fields
+// describing the shard (object ID, index, shard counts, codec, compression and object size)
+// and the payload, so a payload proves membership at its own index of its own object only.
+// The payload is the encoded shard, before any encryption.
+func ShardLeaf(header ShardHeader, payload []byte) MerkleHash {
+	// Encryption is applied after the tree is built, so the flags are left out
+	header.Flags = 0
+
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(header.associatedData())
+	h.Write(payload)
+
+	var leaf MerkleHash
+	h.Sum(leaf[:0])
+	return leaf
+}
+
+// merkleNode returns the hash of an inner node: SHA-256 over a 0x01 prefix and the children,
+// the prefix keeping inner nodes from passing for leaves
+func merkleNode(left, right MerkleHash) MerkleHash {
+	buf := make([]byte, 0, 1+2*len(left))
+	buf = append(buf, 0x01)
+	buf = append(buf, left[:]...)
+	buf = append(buf, right[:]...)
+	return sha256.Sum256(buf)
+}
+
+// MerkleTree is a SHA-256 Merkle tree over the shards of an object. A node without a
+// sibling at the end of a level is promoted to the next level unchanged.
+type MerkleTree struct {
+	levels [][]MerkleHash // levels[0] are the leaves, the last level is the root
+}
+
+// NewMerkleTree builds the tree over leaves, which must not be empty
+func NewMerkleTree(leaves []MerkleHash) *MerkleTree {
+	if len(leaves) == 0 {
+		panic("Merkle tree needs at least one leaf")
+	}
+	levels := [][]MerkleHash{append([]MerkleHash(nil), leaves...)}
+	for level := levels[0]; len(level) > 1; {
+		next := make([]MerkleHash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, merkleNode(level[i], level[i+1]))
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return &MerkleTree{levels: levels}
+}
+
+// NewShardTree builds the tree over the payloads of all shards of an object
+func NewShardTree(header ShardHeader, shards [][]byte) *MerkleTree {
+	leaves := make([]MerkleHash, len(shards))
+	for i, shard := range shards {
+		if shard == nil {
+			panic("All shards must be present to build the Merkle tree")
+		}
+		header.Index = i
+		leaves[i] = ShardLeaf(header, shard)
+	}
+	return NewMerkleTree(leaves)
+}
+
+// Root returns the root hash of the tree
+func (t *MerkleTree) Root() MerkleHash {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Leaves returns the number of leaves of the tree
+func (t *MerkleTree) Leaves() int {
+	return len(t.levels[0])
+}
+
+// Proof returns the inclusion proof of leaf index: the siblings on its path to the root,
+// bottom up
+func (t *MerkleTree) Proof(index int) []MerkleHash {
+	var path []MerkleHash
+	for _, level := range t.levels[:len(t.levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			path = append(path, level[sibling])
+		}
+		index /= 2
+	}
+	return path
+}
+
+// VerifyMerkleProof reports whether path proves that leaf is leaf index of a tree of leaves
+// leaves with the given root
+func VerifyMerkleProof(root, leaf MerkleHash, index, leaves int, path []MerkleHash) bool {
+	if index < 0 || index >= leaves {
+		return false
+	}
+	node := leaf
+	for width := leaves; width > 1; width = (width + 1) / 2 {
+		if sibling := index ^ 1; sibling < width {
+			if len(path) == 0 {
+				return false
+			}
+			if index%2 == 0 {
+				node = merkleNode(node, path[0])
+			} else {
+				node = merkleNode(path[0], node)
+			}
+			path = path[1:]
+		}
+		index /= 2
+	}
+	return len(path) == 0 && node == root
+}
+
+// ObjectManifest records the Merkle root of the shards of an object. Whoever trusts the root
+// can check any shard with its ShardProof, without the other shards.
+type ObjectManifest struct {
+	CodeParams
+	ObjectID   ObjectID   `json:"object_id"`
+	ObjectSize int64      `json:"object_size"`
+	MerkleRoot MerkleHash `json:"merkle_root"`
+}
+
+// ShardProof is the inclusion proof of one shard in the Merkle tree of its object
+type ShardProof struct {
+	ObjectID ObjectID     `json:"object_id"`
+	Index    int          `json:"index"`
+	Shards   int          `json:"shards"` // Number of leaves of the tree
+	Path     []MerkleHash `json:"path"`
+}
+
+// Verify checks that the proof leads from the shard payload to root
+func (p *ShardProof) Verify(root MerkleHash, header ShardHeader, payload []byte) error {
+	switch {
+	case p.ObjectID != header.ObjectID || p.Index != header.Index:
+		return fmt.Errorf("shard %d: proof is for shard %d of object %s: %w", header.Index, p.Index, p.ObjectID, ErrProof)
+	case p.Shards != header.DataShards+header.ParityShards:
+		return fmt.Errorf("shard %d: proof is for %d shards, not %d: %w", header.Index, p.Shards, header.DataShards+header.ParityShards, ErrProof)
+	case !VerifyMerkleProof(root, ShardLeaf(header, payload), p.Index, p.Shards, p.Path):
+		return fmt.Errorf("shard %d: %w", header.Index, ErrProof)
+	}
+	return nil
+}
+
+// ManifestFileName returns the name of the manifest file of an object, e.g. "name.manifest.json"
+func ManifestFileName(name string) string {
+	return name + ".manifest.json"
+}
+
+// proofFileSuffix is appended to the shard file name to name its proof file
+const proofFileSuffix = ".proof"
+
+// ProofFileName returns the name of the inclusion proof file of a shard, e.g. "name.003.proof"
+func ProofFileName(name string, index int) string {
+	return ShardFileName(name, index) + proofFileSuffix
+}
+
+// WithProofs only accepts the shard files read whose inclusion proof, in the proof file next
+// to them, leads to root. Shards without a proof file are rejected too.
+func WithProofs(root MerkleHash) ShardOption {
+	return func(o *shardOptions) {
+		o.root = &root
+	}
+}
+
+// verifyProof checks the inclusion proof of a shard read from storage, if a root was given
+func (o *shardOptions) verifyProof(path string, header ShardHeader, payload []byte) error {
+	if o.root == nil {
+		return nil
+	}
+	proof, err := ReadProofFile(path)
+	if err != nil {
+		return fmt.Errorf("shard %d: %w: %v", header.Index, ErrProof, err)
+	}
+	return proof.Verify(*o.root, header, payload)
+}
+
+// WriteProofFiles builds the Merkle tree of an object from all of its shards and writes the
+// manifest and the proof files of the given shard indices, or of every shard if none is
+// given, to dir. It returns the manifest.
+func WriteProofFiles(dir, name string, header ShardHeader, shards [][]byte, indices ...int) (*ObjectManifest, error) {
+	tree := NewShardTree(header, shards)
+	manifest := &ObjectManifest{
+		CodeParams: NewCodeParams(header.Codec, header.DataShards, header.ParityShards, header.PrimitivePoly),
+		ObjectID:   header.ObjectID,
+		ObjectSize: header.ObjectSize,
+		MerkleRoot: tree.Root(),
+	}
+	if err := WriteJSON(filepath.Join(dir, ManifestFileName(name)), manifest); err != nil {
+		return nil, err
+	}
+
+	if len(indices) == 0 {
+		for i := range shards {
+			indices = append(indices, i)
+		}
+	}
+	for _, index := range indices {
+		proof := ShardProof{ObjectID: header.ObjectID, Index: index, Shards: len(shards), Path: tree.Proof(index)}
+		if err := WriteJSON(filepath.Join(dir, ProofFileName(name, index)), proof); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// ReadObjectManifest reads the manifest of an object
+func ReadObjectManifest(path string) (*ObjectManifest, error) {
+	var manifest ObjectManifest
+	if err := readJSONFile(path, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// ReadProofFile reads the inclusion proof of a shard
+func ReadProofFile(path string) (*ShardProof, error) {
+	var proof ShardProof
+	if err := readJSONFile(path, &proof); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// readJSONFile reads a JSON file into v
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return nil
+}
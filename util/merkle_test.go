@@ -0,0 +1,208 @@
+package util
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testLeaves returns n distinct leaf hashes
+func testLeaves(n int) []MerkleHash {
+	leaves := make([]MerkleHash, n)
+	for i := range leaves {
+		leaves[i] = sha256.Sum256([]byte{byte(i)})
+	}
+	return leaves
+}
+
+func TestMerkleTreeShape(t *testing.T) {
+	l := testLeaves(5)
+	tests := []struct {
+		leaves int
+		root   MerkleHash
+	}{
+		{1, l[0]},
+		{2, merkleNode(l[0], l[1])},
+		// The last node of an odd level is promoted unchanged
+		{3, merkleNode(merkleNode(l[0], l[1]), l[2])},
+		{5, merkleNode(merkleNode(merkleNode(l[0], l[1]), merkleNode(l[2], l[3])), l[4])},
+	}
+	for _, test := range tests {
+		if root := NewMerkleTree(l[:test.leaves]).Root(); root != test.root {
+			t.Errorf("%d leaves: root %s, want %s", test.leaves, root, test.root)
+		}
+	}
+
+	// An inner node does not pass for a leaf
+	if merkleNode(l[0], l[1]) == sha256.Sum256(append(l[0][:], l[1][:]...)) {
+		t.Error("inner nodes are not domain separated")
+	}
+}
+
+func TestMerkleProofs(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 4, 5, 7, 8, 14} {
+		leaves := testLeaves(n)
+		tree := NewMerkleTree(leaves)
+		root := tree.Root()
+		for index, leaf := range leaves {
+			path := tree.Proof(index)
+			if !VerifyMerkleProof(root, leaf, index, n, path) {
+				t.Fatalf("%d leaves: the proof of leaf %d does not verify", n, index)
+			}
+
+			// Every sibling of the path matters
+			for i := range path {
+				tampered := append([]MerkleHash(nil), path...)
+				tampered[i][0] ^= 1
+				if VerifyMerkleProof(root, leaf, index, n, tampered) {
+					t.Errorf("%d leaves, leaf %d: verifies with sibling %d tampered", n, index, i)
+				}
+			}
+			// The proof is for this index only
+			for other := -1; other <= n; other++ {
+				if other != index && VerifyMerkleProof(root, leaf, other, n, path) {
+					t.Errorf("%d leaves: the proof of leaf %d verifies for index %d", n, index, other)
+				}
+			}
+			if len(path) > 0 && VerifyMerkleProof(root, leaf, index, n, path[:len(path)-1]) {
+				t.Errorf("%d leaves, leaf %d: verifies with a truncated path", n, index)
+			}
+			if VerifyMerkleProof(root, leaf, index, n, append(path, leaf)) {
+				t.Errorf("%d leaves, leaf %d: verifies with an extra sibling", n, index)
+			}
+			if n > 1 && VerifyMerkleProof(root, leaves[(index+1)%n], index, n, path) {
+				t.Errorf("%d leaves: leaf %d verifies at index %d", n, (index+1)%n, index)
+			}
+		}
+	}
+}
+
+func TestShardLeafBindsHeader(t *testing.T) {
+	header := testHeader()
+	payload := []byte("shard payload")
+	leaf := ShardLeaf(header, payload)
+
+	tests := []struct {
+		name   string
+		modify func(h *ShardHeader)
+	}{
+		{"index", func(h *ShardHeader) { h.Index = 2 }},
+		{"object ID", func(h *ShardHeader) { h.ObjectID[0] ^= 1 }},
+		{"object size", func(h *ShardHeader) { h.ObjectSize++ }},
+		{"parity shards", func(h *ShardHeader) { h.ParityShards = 3 }},
+		{"compression", func(h *ShardHeader) { h.Compression = CompressionGzip }},
+	}
+	for _, test := range tests {
+		modified := header
+		test.modify(&modified)
+		if ShardLeaf(modified, payload) == leaf {
+			t.Errorf("%s: the leaf does not change", test.name)
+		}
+	}
+	if ShardLeaf(header, []byte("shard paylaod")) == leaf {
+		t.Error("payload: the leaf does not change")
+	}
+	// The tree is built before encryption
+	header.Flags = FlagEncrypted
+	if ShardLeaf(header, payload) != leaf {
+		t.Error("the leaf depends on the encryption flag")
+	}
+}
+
+func TestReadShardFilesWithProofs(t *testing.T) {
+	header, shards, err := EncodeObject(logLines(1000), CodecVandermonde, DefaultPrimitivePoly, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := WriteShardFiles(dir, "object", header, shards); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := WriteProofFiles(dir, "object", header, shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadObjectManifest(filepath.Join(dir, ManifestFileName("object")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *read != *manifest || read.ObjectID != header.ObjectID {
+		t.Fatalf("read manifest %+v, want %+v", read, manifest)
+	}
+	root := manifest.MerkleRoot
+
+	_, got, rejected, err := ReadShardFiles(dir, WithProofs(root))
+	if err != nil || len(rejected) != 0 {
+		t.Fatalf("got rejected shards %v and error %v", rejected, err)
+	}
+	if i := firstDifferentShard(got, shards); i >= 0 {
+		t.Fatalf("shard %d differs", i)
+	}
+
+	// Shard 1 replaced by another payload with a valid checksum
+	header.Index = 1
+	if err := WriteShardFile(filepath.Join(dir, ShardFileName("object", 1)), header, shards[0]); err != nil {
+		t.Fatal(err)
+	}
+	// Proof 2 with a tampered sibling
+	proof, err := ReadProofFile(filepath.Join(dir, ProofFileName("object", 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof.Path[0][0] ^= 1
+	if err := WriteJSON(filepath.Join(dir, ProofFileName("object", 2)), proof); err != nil {
+		t.Fatal(err)
+	}
+	// Proof 3 replaced by the proof of shard 4
+	proof, err = ReadProofFile(filepath.Join(dir, ProofFileName("object", 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteJSON(filepath.Join(dir, ProofFileName("object", 3)), proof); err != nil {
+		t.Fatal(err)
+	}
+	// Shard 5 without a proof
+	if err := os.Remove(filepath.Join(dir, ProofFileName("object", 5))); err != nil {
+		t.Fatal(err)
+	}
+
+	_, got, rejected, err = ReadShardFiles(dir, WithProofs(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{1, 2, 3, 5} {
+		if !errors.Is(rejected[index], ErrProof) {
+			t.Errorf("shard %d: got error %v, want ErrProof", index, rejected[index])
+		}
+		if got[index] != nil {
+			t.Errorf("shard %d was read", index)
+		}
+	}
+	if len(rejected) != 4 || got[0] == nil || got[4] == nil {
+		t.Errorf("got rejected shards %v", rejected)
+	}
+
+	// Without proofs, only the checksums are checked
+	if _, _, rejected, err = ReadShardFiles(dir); err != nil || len(rejected) != 0 {
+		t.Errorf("without proofs: got rejected shards %v and error %v", rejected, err)
+	}
+
+	// Another root rejects every shard
+	other := root
+	other[0] ^= 1
+	if _, _, _, err = ReadShardFiles(dir, WithProofs(other)); err == nil {
+		t.Error("shards accepted with another root")
+	}
+}
+
+// firstDifferentShard returns the index of the first shard of got that differs from want, or -1
+func firstDifferentShard(got, want [][]byte) int {
+	for i := range want {
+		if string(got[i]) != string(want[i]) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+)
+
+// EncodeObject splits data into dataShards padded data shards and calculates parityShards
+// parity shards with the given codec. The returned header describes the object and carries
+// a new random object ID.
+func EncodeObject(data []byte, codec CodecID, primitivePoly byte, dataShards, parityShards int, opts ...rs.Option) (ShardHeader, [][]byte, error) {
+	return EncodeCompressedObject(data, CompressionNone, 0, codec, primitivePoly, dataShards, parityShards, opts...)
+}
+
+// EncodeCompressedObject compresses data before encoding it like EncodeObject, which saves
+// the compressed bytes (k+m)/k times. If the compressed size is above maxRatio times the
+// original size, compression is not worth it and the object is encoded as is; the header
+// records the compression actually used.
+func EncodeCompressedObject(data []byte, compression CompressionID, maxRatio float64, codec CodecID, primitivePoly byte, dataShards, parityShards int, opts ...rs.Option) (ShardHeader, [][]byte, error) {
+	header := ShardHeader{
+		Codec:         codec,
+		PrimitivePoly: primitivePoly,
+		DataShards:    dataShards,
+		ParityShards:  parityShards,
+		ObjectSize:    int64(len(data)),
+	}
+	if err := ValidateShardCounts(dataShards, parityShards); err != nil {
+		return header, nil, err
+	}
+	if !codec.MDS() {
+		return header, nil, fmt.Errorf("the %s codec is not MDS, some sets of %d shards cannot rebuild the object: encode files with vandermonde or lagrange", codec, dataShards)
+	}
+	if compression != CompressionNone {
+		compressed, err := Compress(data, compression)
+		if err != nil {
+			return header, nil, err
+		}
+		if float64(len(compressed)) <= maxRatio*float64(len(data)) {
+			header.Compression = compression
+			data = compressed
+		}
+	}
+	if _, err := rand.Read(header.ObjectID[:]); err != nil {
+		return header, nil, fmt.Errorf("failed to generate object ID: %v", err)
+	}
+
+	coder, err := rs.NewCodec(codec.String(), gf.NewGF(primitivePoly), dataShards, parityShards, opts...)
+	if err != nil {
+		return header, nil, err
+	}
+	shards := rs.SplitShards(data, dataShards, dataShards+parityShards)
+	if err := coder.Encode(shards); err != nil {
+		return header, nil, err
+	}
+	return header, shards, nil
+}
+
+// ReconstructObject recovers the missing (nil) shards of the object described by header
+func ReconstructObject(header ShardHeader, shards [][]byte, opts ...rs.Option) error {
+	codec, err := rs.NewCodec(header.Codec.String(), gf.NewGF(header.PrimitivePoly), header.DataShards, header.ParityShards, opts...)
+	if err != nil {
+		return err
+	}
+	return codec.Reconstruct(shards)
+}
+
+// DecodeObject recovers the missing data shards and returns the original object data,
+// decompressed if it was compressed before encoding
+func DecodeObject(header ShardHeader, shards [][]byte, opts ...rs.Option) ([]byte, error) {
+	codec, err := rs.NewCodec(header.Codec.String(), gf.NewGF(header.PrimitivePoly), header.DataShards, header.ParityShards, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := codec.Decode(shards); err != nil {
+		return nil, err
+	}
+
+	if header.Compression != CompressionNone {
+		var compressed []byte
+		for i := 0; i < header.DataShards; i++ {
+			compressed = append(compressed, shards[i]...)
+		}
+		return Decompress(compressed, header.Compression, header.ObjectSize)
+	}
+
+	data := make([]byte, 0, header.ObjectSize)
+	for i := 0; i < header.DataShards; i++ {
+		data = append(data, shards[i]...)
+	}
+	if int64(len(data)) < header.ObjectSize {
+		return nil, fmt.Errorf("shards hold %d bytes, object has %d", len(data), header.ObjectSize)
+	}
+	return data[:header.ObjectSize], nil
+}
+
+// VerifyObject checks that the available shards belong to one codeword. The shards other
+// than the first dataShards available ones are recalculated from them and compared; the
+// indices of the shards that differ are returned. A non-empty result means the object is
+// inconsistent, not necessarily that the returned shards are the damaged ones.
+func VerifyObject(header ShardHeader, shards [][]byte, opts ...rs.Option) ([]int, error) {
+	basis := make([][]byte, len(shards))
+	used := 0
+	for i, shard := range shards {
+		if shard != nil && used < header.DataShards {
+			basis[i] = shard
+			used++
+		}
+	}
+	if err := ReconstructObject(header, basis, opts...); err != nil {
+		return nil, err
+	}
+
+	var mismatched []int
+	for i, shard := range shards {
+		if shard != nil && !bytes.Equal(shard, basis[i]) {
+			mismatched = append(mismatched, i)
+		}
+	}
+	return mismatched, nil
+}
@@ -0,0 +1,186 @@
+package util
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// removeShardFiles removes the shard files of the given indices
+func removeShardFiles(t *testing.T, dir, name string, indices []int) {
+	t.Helper()
+	for _, index := range indices {
+		if err := os.Remove(filepath.Join(dir, ShardFileName(name, index))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestObjectFileRoundTrip(t *testing.T) {
+	tests := []struct {
+		name                     string
+		codec                    CodecID
+		compression              CompressionID
+		dataShards, parityShards int
+		size                     int
+		lost                     []int
+	}{
+		{"first shards lost", CodecVandermonde, CompressionNone, 4, 2, 1000, []int{0, 1}},
+		{"parity shards lost", CodecVandermonde, CompressionNone, 4, 2, 1000, []int{4, 5}},
+		{"spread", CodecLagrange, CompressionNone, 10, 4, 12345, []int{1, 5, 9, 12}},
+		{"padded", CodecLagrange, CompressionNone, 4, 2, 49, []int{3, 4}},
+		{"one byte", CodecVandermonde, CompressionNone, 4, 2, 1, []int{0, 2}},
+		{"empty", CodecVandermonde, CompressionNone, 4, 2, 0, []int{0, 5}},
+		{"no parity", CodecVandermonde, CompressionNone, 3, 0, 100, nil},
+		{"compressed", CodecVandermonde, CompressionGzip, 6, 3, 20000, []int{0, 2, 7}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := logLines(test.size)
+			header, shards, err := EncodeCompressedObject(data, test.compression, 1, test.codec, DefaultPrimitivePoly, test.dataShards, test.parityShards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header.Compression != test.compression {
+				t.Fatalf("object encoded with %s, want %s", header.Compression, test.compression)
+			}
+			dir := t.TempDir()
+			if err := WriteShardFiles(dir, "object", header, shards); err != nil {
+				t.Fatal(err)
+			}
+			removeShardFiles(t, dir, "object", test.lost)
+
+			read, got, rejected, err := ReadShardFiles(dir)
+			if err != nil || len(rejected) != 0 {
+				t.Fatalf("got rejected shards %v and error %v", rejected, err)
+			}
+			header.ShardLength = int64(len(shards[0]))
+			if !read.SameObject(header) || read.ObjectSize != int64(test.size) {
+				t.Errorf("read header %+v, want %+v", read, header)
+			}
+			for i, shard := range got {
+				lost := false
+				for _, index := range test.lost {
+					lost = lost || index == i
+				}
+				if lost != (shard == nil) {
+					t.Errorf("shard %d: read %v, lost %v", i, shard != nil, lost)
+				}
+			}
+
+			decoded, err := DecodeObject(read, got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded, data) {
+				t.Errorf("decoded %d bytes differing from the %d bytes encoded", len(decoded), len(data))
+			}
+		})
+	}
+}
+
+func TestDecodeObjectNeedsDataShards(t *testing.T) {
+	header, shards, err := EncodeObject(logLines(1000), CodecVandermonde, DefaultPrimitivePoly, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := WriteShardFiles(dir, "object", header, shards); err != nil {
+		t.Fatal(err)
+	}
+	removeShardFiles(t, dir, "object", []int{0, 3, 5})
+
+	read, got, _, err := ReadShardFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeObject(read, got); err == nil || !strings.Contains(err.Error(), "not enough shards: have 3, need 4") {
+		t.Errorf("got error %v, want not enough shards", err)
+	}
+}
+
+func TestReadShardFilesRejectsOtherObjects(t *testing.T) {
+	data := logLines(1000)
+	header, shards, err := EncodeObject(data, CodecVandermonde, DefaultPrimitivePoly, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same data and parameters under another object ID
+	otherHeader, otherShards, err := EncodeObject(data, CodecVandermonde, DefaultPrimitivePoly, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherHeader.ObjectID == header.ObjectID {
+		t.Fatal("two objects got the same ID")
+	}
+	dir, otherDir := t.TempDir(), t.TempDir()
+	if err := WriteShardFiles(dir, "object", header, shards); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteShardFiles(otherDir, "object", otherHeader, otherShards); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shards 2 and 5 replaced by those of the other object
+	for _, index := range []int{2, 5} {
+		name := ShardFileName("object", index)
+		if err := os.Rename(filepath.Join(otherDir, name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Shard 1 under the file name of shard 3
+	if err := os.Rename(filepath.Join(dir, ShardFileName("object", 1)), filepath.Join(dir, ShardFileName("object", 3))); err != nil {
+		t.Fatal(err)
+	}
+
+	read, got, rejected, err := ReadShardFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ObjectID != header.ObjectID {
+		t.Errorf("read object %s, want %s", read.ObjectID, header.ObjectID)
+	}
+	for _, index := range []int{2, 5} {
+		if err := rejected[index]; err == nil || !strings.Contains(err.Error(), "belongs to object "+otherHeader.ObjectID.String()) {
+			t.Errorf("shard %d: got error %v, want a shard of another object", index, err)
+		}
+	}
+	if err := rejected[3]; err == nil || !strings.Contains(err.Error(), "file name has index 3 but header has index 1") {
+		t.Errorf("shard 3: got error %v, want an index mismatch", err)
+	}
+	if len(rejected) != 3 {
+		t.Errorf("got rejected shards %v", rejected)
+	}
+	for i, shard := range got {
+		if (shard != nil) != (i == 0 || i == 4) {
+			t.Errorf("shard %d read: %v", i, shard != nil)
+		}
+	}
+	if _, err := DecodeObject(read, got); err == nil {
+		t.Error("decoded an object from 2 of 4 shards")
+	}
+
+	// The first valid shard describes the object: shard 0 of the other object rejects the others
+	if err := os.Rename(filepath.Join(otherDir, ShardFileName("object", 0)), filepath.Join(dir, ShardFileName("object", 0))); err != nil {
+		t.Fatal(err)
+	}
+	read, _, rejected, err = ReadShardFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ObjectID != otherHeader.ObjectID {
+		t.Errorf("read object %s, want %s", read.ObjectID, otherHeader.ObjectID)
+	}
+	if err := rejected[4]; err == nil || !strings.Contains(err.Error(), "belongs to object "+header.ObjectID.String()) {
+		t.Errorf("shard 4: got error %v, want a shard of another object", err)
+	}
+}
+
+func TestEncodeObjectRejectsHorner(t *testing.T) {
+	if _, _, err := EncodeObject(logLines(100), CodecHorner, DefaultPrimitivePoly, 4, 2); err == nil || !strings.Contains(err.Error(), "not MDS") {
+		t.Errorf("got error %v, want a codec that is not MDS", err)
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"fmt"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"strconv"
+	"strings"
+)
+
+// Default code parameters, used when neither a flag nor the input file sets them
+const (
+	DefaultDataShards    = 6
+	DefaultParityShards  = 12
+	DefaultPrimitivePoly = 0x1D // x^8 + x^4 + x^3 + x^2 + 1
+	DefaultCodec         = CodecVandermonde
+)
+
+// CodeParams are the code parameters written into the JSON files, so that
+// the decoder can be configured from its input
+type CodeParams struct {
+	Codec         string `json:"codec,omitempty"`
+	DataShards    int    `json:"data_shards,omitempty"`
+	ParityShards  int    `json:"parity_shards,omitempty"`
+	PrimitivePoly string `json:"primitive_poly,omitempty"`
+}
+
+// NewCodeParams returns the JSON representation of the given parameters
+func NewCodeParams(codec CodecID, dataShards, parityShards int, primitivePoly byte) CodeParams {
+	return CodeParams{
+		Codec:         codec.String(),
+		DataShards:    dataShards,
+		ParityShards:  parityShards,
+		PrimitivePoly: FormatPrimitivePoly(primitivePoly),
+	}
+}
+
+// ParsePrimitivePoly parses a primitive polynomial given in hexadecimal ("0x1D") or decimal ("29"),
+// without its x^8 term, and checks that it is primitive
+func ParsePrimitivePoly(s string) (byte, error) {
+	value, err := strconv.ParseUint(strings.TrimSpace(s), 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid primitive polynomial %q: must be a byte such as 0x1D", s)
+	}
+	if !gf.IsPrimitive(byte(value)) {
+		return 0, fmt.Errorf("polynomial x^8 + 0x%02x is not primitive", value)
+	}
+	return byte(value), nil
+}
+
+// FormatPrimitivePoly formats a primitive polynomial as written into the JSON files
+func FormatPrimitivePoly(poly byte) string {
+	return fmt.Sprintf("0x%02x", poly)
+}
+
+// ValidateShardCounts checks that a (dataShards, parityShards) layout can be encoded
+func ValidateShardCounts(dataShards, parityShards int) error {
+	if dataShards <= 0 {
+		return fmt.Errorf("number of data shards must be positive, got %d", dataShards)
+	}
+	if parityShards < 0 {
+		return fmt.Errorf("number of parity shards must not be negative, got %d", parityShards)
+	}
+	if dataShards+parityShards > rs.MaxTotalShards {
+		return fmt.Errorf("at most %d shards are supported, got %d+%d", rs.MaxTotalShards, dataShards, parityShards)
+	}
+	return nil
+}
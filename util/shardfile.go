@@ -0,0 +1,283 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ShardFileName returns the file name of a shard, e.g. "name.003" for index 3
+func ShardFileName(name string, index int) string {
+	return fmt.Sprintf("%s.%03d", name, index)
+}
+
+// FindShardFiles scans a shard directory for files named "<name>.NNN"
+// and returns the object name and the path of every shard found by index
+func FindShardFiles(dir string) (string, map[int]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read shard directory: %v", err)
+	}
+
+	name := ""
+	paths := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		// Split "<name>.NNN" into object name and shard index
+		dot := strings.LastIndex(entry.Name(), ".")
+		if dot <= 0 || len(entry.Name())-dot-1 != 3 {
+			continue
+		}
+		index, err := strconv.Atoi(entry.Name()[dot+1:])
+		if err != nil {
+			continue
+		}
+
+		if name == "" {
+			name = entry.Name()[:dot]
+		} else if name != entry.Name()[:dot] {
+			return "", nil, fmt.Errorf("shard directory contains shards of more than one object: %s and %s", name, entry.Name()[:dot])
+		}
+		paths[index] = filepath.Join(dir, entry.Name())
+	}
+
+	if len(paths) == 0 {
+		return "", nil, fmt.Errorf("no shard files found in %s", dir)
+	}
+
+	return name, paths, nil
+}
+
+// WriteShardFiles writes every shard to "<dir>/<name>.NNN", preceded by a copy of header
+// with the shard index, length and checksum filled in. The options are those of WriteShard.
+func WriteShardFiles(dir, name string, header ShardHeader, shards [][]byte, opts ...ShardOption) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create shard directory: %v", err)
+	}
+
+	for i, shard := range shards {
+		header.Index = i
+		if err := WriteShardFile(filepath.Join(dir, ShardFileName(name, i)), header, shard, opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteShardFile writes a single shard file, replacing any existing file
+func WriteShardFile(path string, header ShardHeader, shard []byte, opts ...ShardOption) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create shard %d: %v", header.Index, err)
+	}
+
+	err = WriteShard(file, header, shard, opts...)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write shard %d: %v", header.Index, err)
+	}
+	return nil
+}
+
+// ReadShardFiles reads the shards of the object in a shard directory.
+// The returned slice holds one entry per shard index, nil for missing shards. Shards that
+// cannot be read, fail their checksum or belong to another object are left out and
+// reported in rejected. The returned header describes the object, with the fields of the
+// first valid shard; an error is returned if no shard is valid. The options are those of
+// ReadShardFile: encrypted shards failing authentication and, with WithProofs, shards
+// without a valid inclusion proof are rejected.
+func ReadShardFiles(dir string, opts ...ShardOption) (ShardHeader, [][]byte, map[int]error, error) {
+	var object ShardHeader
+
+	_, paths, err := FindShardFiles(dir)
+	if err != nil {
+		return object, nil, nil, err
+	}
+
+	// Read shards in index order so the first valid one describes the object
+	indices := make([]int, 0, len(paths))
+	for index := range paths {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	var shards [][]byte
+	rejected := make(map[int]error)
+	for _, index := range indices {
+		header, payload, err := ReadShardFile(paths[index], opts...)
+		switch {
+		case err != nil:
+			rejected[index] = err
+		case header.Index != index:
+			rejected[index] = fmt.Errorf("file name has index %d but header has index %d", index, header.Index)
+		case shards != nil && !header.SameObject(object):
+			rejected[index] = fmt.Errorf("shard belongs to object %s, not %s", header.ObjectID, object.ObjectID)
+		default:
+			if shards == nil {
+				object = header
+				shards = make([][]byte, header.DataShards+header.ParityShards)
+			}
+			shards[index] = payload
+		}
+	}
+
+	if shards == nil {
+		return object, nil, rejected, fmt.Errorf("no valid shard files found in %s", dir)
+	}
+	return object, shards, rejected, nil
+}
+
+// ReadShardFile reads and verifies a single shard file. The options are those of ReadShard;
+// with WithProofs, the inclusion proof in "<path>.proof" must also verify.
+func ReadShardFile(path string, opts ...ShardOption) (ShardHeader, []byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ShardHeader{}, nil, err
+	}
+	defer file.Close()
+
+	header, payload, err := ReadShard(bufio.NewReader(file), opts...)
+	if err != nil {
+		return header, nil, err
+	}
+	if err := newShardOptions(opts).verifyProof(path+proofFileSuffix, header, payload); err != nil {
+		return header, nil, err
+	}
+	return header, payload, nil
+}
+
+// ReadShardHeader reads the header of a shard file without verifying its payload
+func ReadShardHeader(path string) (ShardHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ShardHeader{}, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, ShardHeaderSize)
+	if _, err := io.ReadFull(file, buf); err != nil {
+		return ShardHeader{}, fmt.Errorf("failed to read shard header: %v", err)
+	}
+	return ParseShardHeader(buf)
+}
+
+// ShardFile is an open shard file, reading from it reads the payload
+type ShardFile struct {
+	*io.SectionReader
+	Header ShardHeader
+	file   *os.File
+}
+
+// OpenShardFile opens a shard file for random access to its payload.
+// The payload checksum is verified before the file is returned. Encrypted shards can only
+// be read whole, with ReadShard.
+func OpenShardFile(path string) (*ShardFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, ShardHeaderSize)
+	if _, err := io.ReadFull(file, buf); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read shard header: %v", err)
+	}
+	header, err := ParseShardHeader(buf)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if header.Flags&FlagEncrypted != 0 {
+		file.Close()
+		return nil, fmt.Errorf("shard %d is encrypted and cannot be opened for random access", header.Index)
+	}
+
+	// Stream the payload through the checksum without loading it into memory
+	payload := io.NewSectionReader(file, ShardHeaderSize, header.ShardLength)
+	hash := crc32.New(castagnoli)
+	if n, err := io.Copy(hash, payload); err != nil || n != header.ShardLength {
+		file.Close()
+		return nil, fmt.Errorf("failed to read shard payload: %d of %d bytes, %v", n, header.ShardLength, err)
+	}
+	if hash.Sum32() != header.Checksum {
+		file.Close()
+		return nil, fmt.Errorf("shard %d payload: %w", header.Index, ErrChecksum)
+	}
+
+	return &ShardFile{
+		SectionReader: io.NewSectionReader(file, ShardHeaderSize, header.ShardLength),
+		Header:        header,
+		file:          file,
+	}, nil
+}
+
+// Close closes the shard file
+func (f *ShardFile) Close() error {
+	return f.file.Close()
+}
+
+// ShardFileWriter streams a shard payload to a file and writes the header,
+// with the final length and checksum, when it is closed
+type ShardFileWriter struct {
+	file   *os.File
+	header ShardHeader
+	length int64
+	hash   hash.Hash32
+}
+
+// CreateShardFile creates a shard file whose payload is written through the returned writer.
+// The payload is written as is, encrypted shards are written with WriteShard.
+func CreateShardFile(path string, header ShardHeader) (*ShardFileWriter, error) {
+	if header.Flags&FlagEncrypted != 0 {
+		return nil, fmt.Errorf("encrypted shards cannot be streamed")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reserve space for the header, it is rewritten by Close
+	if _, err := file.Write(make([]byte, ShardHeaderSize)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write shard header: %v", err)
+	}
+
+	return &ShardFileWriter{file: file, header: header, hash: crc32.New(castagnoli)}, nil
+}
+
+// Write appends data to the shard payload
+func (w *ShardFileWriter) Write(data []byte) (int, error) {
+	n, err := w.file.Write(data)
+	w.hash.Write(data[:n])
+	w.length += int64(n)
+	return n, err
+}
+
+// Close writes the header and closes the file
+func (w *ShardFileWriter) Close() error {
+	w.header.Version = w.header.formatVersion()
+	w.header.ShardLength = w.length
+	w.header.Checksum = w.hash.Sum32()
+
+	buf, err := w.header.MarshalBinary()
+	if err == nil {
+		_, err = w.file.WriteAt(buf, 0)
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"rs-encoder/rs"
+)
+
+// compressionProbeSize is the number of bytes at the start of a stream that are compressed
+// to decide whether compressing the whole stream is worth it
+const compressionProbeSize = 1 << 20
+
+// compressChunkSize is the number of bytes compressReader reads from its source at a time
+const compressChunkSize = 64 * 1024
+
+// EncodeCompressedStream compresses r before encoding it with enc like enc.EncodeContext,
+// without holding the stream in memory. The whole stream is not known in advance, so
+// compression is kept if the first compressionProbeSize bytes compress to at most maxRatio
+// times their size, and the stream is encoded as is otherwise. It returns the compression
+// actually used and the number of bytes encoded, which JoinCompressedStream needs; progress
+// reports the encoded bytes, not the bytes read from r.
+func EncodeCompressedStream(ctx context.Context, enc *rs.StreamEncoder, r io.Reader, data, parity []io.Writer, compression CompressionID, maxRatio float64, progress rs.ProgressFunc) (CompressionID, int64, error) {
+	if _, ok := compressionNames[compression]; !ok {
+		return CompressionNone, 0, fmt.Errorf("unsupported compression %s", compression)
+	}
+	probe := make([]byte, compressionProbeSize)
+	n, err := io.ReadFull(r, probe)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return CompressionNone, 0, fmt.Errorf("failed to read input: %v", err)
+	}
+	probe = probe[:n]
+
+	input := io.MultiReader(bytes.NewReader(probe), r)
+	if compression != CompressionNone {
+		compressed, err := Compress(probe, compression)
+		if err != nil {
+			return CompressionNone, 0, err
+		}
+		if n == 0 || float64(len(compressed)) > maxRatio*float64(n) {
+			compression = CompressionNone
+		} else if input, err = newCompressReader(input, compression); err != nil {
+			return CompressionNone, 0, err
+		}
+	}
+
+	counter := &countingReader{r: input}
+	err = enc.EncodeContext(ctx, counter, data, parity, progress)
+	return compression, counter.n, err
+}
+
+// JoinCompressedStream joins the size bytes of the data shard streams written by
+// EncodeCompressedStream like enc.JoinContext and writes them to dst, decompressed with
+// compression. progress reports the bytes joined before decompression.
+func JoinCompressedStream(ctx context.Context, enc *rs.StreamEncoder, dst io.Writer, data []io.Reader, size int64, compression CompressionID, progress rs.ProgressFunc) error {
+	if compression == CompressionNone {
+		return enc.JoinContext(ctx, dst, data, size, progress)
+	}
+	if _, ok := compressionNames[compression]; !ok {
+		return fmt.Errorf("unsupported compression %s", compression)
+	}
+
+	pr, pw := io.Pipe()
+	joined := make(chan error, 1)
+	go func() {
+		err := enc.JoinContext(ctx, pw, data, size, progress)
+		pw.CloseWithError(err)
+		joined <- err
+	}()
+
+	err := decompressStream(dst, pr, compression)
+	if err != nil {
+		// Stops the join at its next write
+		pr.CloseWithError(err)
+	} else {
+		_, err = io.Copy(io.Discard, pr)
+	}
+	joinErr := <-joined
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return ctx.Err()
+	case err != nil:
+		return err
+	}
+	return joinErr
+}
+
+// decompressStream writes the stream read from r, decompressed, to dst
+func decompressStream(dst io.Writer, r io.Reader, compression CompressionID) error {
+	dr, err := newDecompressor(r, compression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, dr); err != nil {
+		return fmt.Errorf("failed to decompress: %v", err)
+	}
+	return nil
+}
+
+// compressReader compresses the stream read from src, a chunk at a time
+type compressReader struct {
+	src   io.Reader
+	w     io.WriteCloser // Compressor writing to buf
+	buf   bytes.Buffer
+	chunk []byte
+	eof   bool // src is exhausted and w closed
+}
+
+// newCompressReader returns a reader of the stream read from src, compressed
+func newCompressReader(src io.Reader, compression CompressionID) (*compressReader, error) {
+	c := &compressReader{src: src, chunk: make([]byte, compressChunkSize)}
+	w, err := newCompressor(&c.buf, compression)
+	if err != nil {
+		return nil, err
+	}
+	c.w = w
+	return c, nil
+}
+
+func (c *compressReader) Read(p []byte) (int, error) {
+	for c.buf.Len() == 0 && !c.eof {
+		n, err := c.src.Read(c.chunk)
+		if n > 0 {
+			if _, err := c.w.Write(c.chunk[:n]); err != nil {
+				return 0, fmt.Errorf("failed to compress: %v", err)
+			}
+		}
+		if err == io.EOF {
+			c.eof = true
+			if err := c.w.Close(); err != nil {
+				return 0, fmt.Errorf("failed to compress: %v", err)
+			}
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if c.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.buf.Read(p)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,148 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"rs-encoder/gf"
+	"rs-encoder/rs"
+	"testing"
+)
+
+// testStreamEncoder returns a 4+2 stream encoder with small stripes
+func testStreamEncoder(t *testing.T) *rs.StreamEncoder {
+	t.Helper()
+	codec, err := rs.NewCodec(CodecVandermonde.String(), gf.NewGF(DefaultPrimitivePoly), 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs.NewStreamEncoder(codec, 1024)
+}
+
+// encodeStream encodes data with EncodeCompressedStream into 4+2 shard buffers
+func encodeStream(t *testing.T, enc *rs.StreamEncoder, data []byte, compression CompressionID, maxRatio float64) ([]*bytes.Buffer, CompressionID, int64) {
+	t.Helper()
+	shards := make([]*bytes.Buffer, 6)
+	writers := make([]io.Writer, 6)
+	for i := range shards {
+		shards[i] = new(bytes.Buffer)
+		writers[i] = shards[i]
+	}
+	used, size, err := EncodeCompressedStream(context.Background(), enc, bytes.NewReader(data), writers[:4], writers[4:], compression, maxRatio, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return shards, used, size
+}
+
+// dataReaders returns readers of the data shard streams
+func dataReaders(shards []*bytes.Buffer) []io.Reader {
+	readers := make([]io.Reader, 4)
+	for i := range readers {
+		readers[i] = bytes.NewReader(shards[i].Bytes())
+	}
+	return readers
+}
+
+func TestCompressedStreamRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		compression CompressionID
+		want        CompressionID
+	}{
+		{"logs", logLines(3 * compressionProbeSize), CompressionGzip, CompressionGzip},
+		{"logs with zlib", logLines(100000), CompressionZlib, CompressionZlib},
+		{"logs with flate", logLines(100000), CompressionFlate, CompressionFlate},
+		// Random data grows when compressed, so it is stored as is
+		{"random", randomData(4, 100000), CompressionGzip, CompressionNone},
+		{"not requested", logLines(100000), CompressionNone, CompressionNone},
+		{"empty", []byte{}, CompressionGzip, CompressionNone},
+		{"1 byte", []byte{42}, CompressionFlate, CompressionNone},
+	}
+
+	enc := testStreamEncoder(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shards, used, size := encodeStream(t, enc, test.data, test.compression, DefaultMaxCompressionRatio)
+			if used != test.want {
+				t.Fatalf("compression %s, want %s", used, test.want)
+			}
+			if used == CompressionNone && size != int64(len(test.data)) {
+				t.Errorf("encoded %d bytes of a %d byte stream", size, len(test.data))
+			}
+			if used != CompressionNone && size > int64(len(test.data))/10 {
+				t.Errorf("compressed %d bytes to %d", len(test.data), size)
+			}
+			if got := int64(shards[5].Len()); got != enc.StreamShardSize(size) {
+				t.Errorf("shard streams of %d bytes, want %d", got, enc.StreamShardSize(size))
+			}
+
+			var out bytes.Buffer
+			if err := JoinCompressedStream(context.Background(), enc, &out, dataReaders(shards), size, used, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), test.data) {
+				t.Error("joined stream differs")
+			}
+		})
+	}
+}
+
+// Compression is decided on the start of the stream only
+func TestCompressedStreamProbe(t *testing.T) {
+	enc := testStreamEncoder(t)
+	data := append(randomData(5, compressionProbeSize), logLines(compressionProbeSize)...)
+	if _, used, _ := encodeStream(t, enc, data, CompressionGzip, DefaultMaxCompressionRatio); used != CompressionNone {
+		t.Errorf("random start: compression %s, want none", used)
+	}
+	data = append(logLines(compressionProbeSize), randomData(5, 100000)...)
+	shards, used, size := encodeStream(t, enc, data, CompressionGzip, DefaultMaxCompressionRatio)
+	if used != CompressionGzip {
+		t.Fatalf("compressible start: compression %s, want gzip", used)
+	}
+	var out bytes.Buffer
+	if err := JoinCompressedStream(context.Background(), enc, &out, dataReaders(shards), size, used, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("joined stream differs")
+	}
+
+	if _, used, _ := encodeStream(t, enc, logLines(100000), CompressionGzip, 0.001); used != CompressionNone {
+		t.Errorf("ratio above the limit: compression %s, want none", used)
+	}
+}
+
+func TestJoinCompressedStreamErrors(t *testing.T) {
+	enc := testStreamEncoder(t)
+	data := logLines(200000)
+	shards, used, size := encodeStream(t, enc, data, CompressionZlib, DefaultMaxCompressionRatio)
+
+	// A corrupted data shard fails the zlib checksum instead of hanging the join
+	corrupted := dataReaders(shards)
+	damaged := append([]byte(nil), shards[1].Bytes()...)
+	damaged[len(damaged)/2] ^= 0xff
+	corrupted[1] = bytes.NewReader(damaged)
+	if err := JoinCompressedStream(context.Background(), enc, io.Discard, corrupted, size, used, nil); err == nil {
+		t.Error("joined a corrupted stream")
+	}
+
+	// A truncated data shard stream
+	truncated := dataReaders(shards)
+	truncated[2] = bytes.NewReader(shards[2].Bytes()[:shards[2].Len()/2])
+	if err := JoinCompressedStream(context.Background(), enc, io.Discard, truncated, size, used, nil); err == nil {
+		t.Error("joined a truncated stream")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := JoinCompressedStream(ctx, enc, io.Discard, dataReaders(shards), size, used, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled join: got error %v, want context.Canceled", err)
+	}
+
+	if _, _, err := EncodeCompressedStream(context.Background(), enc, bytes.NewReader(data), nil, nil, 9, 1, nil); err == nil {
+		t.Error("EncodeCompressedStream accepted an unknown compression")
+	}
+}
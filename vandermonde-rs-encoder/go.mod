@@ -1,3 +0,0 @@
-module rs-encoder
-
-go 1.19 
\ No newline at end of file